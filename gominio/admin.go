@@ -0,0 +1,421 @@
+package gominio
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/secure-io/sio-go"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// adminPrefix is the path prefix of the MinIO admin API endpoints used by madmin-go clients
+const adminPrefix = "/minio/admin/v3"
+
+// maxAdminBodySize bounds the body of admin requests
+const maxAdminBodySize = 1 << 20
+
+const (
+	accountEnabled  = "enabled"
+	accountDisabled = "disabled"
+
+	quotaTypeHard = "hard"
+)
+
+// adminOperations maps the admin routes to their operation
+var adminOperations = map[string]string{
+	adminPrefix + "/add-user":                 OpAddUser,
+	adminPrefix + "/remove-user":              OpRemoveUser,
+	adminPrefix + "/list-users":               OpListUsers,
+	adminPrefix + "/user-info":                OpGetUserInfo,
+	adminPrefix + "/set-user-status":          OpSetUserStatus,
+	adminPrefix + "/add-canned-policy":        OpAddCannedPolicy,
+	adminPrefix + "/remove-canned-policy":     OpRemoveCannedPolicy,
+	adminPrefix + "/list-canned-policies":     OpListCannedPolicies,
+	adminPrefix + "/info-canned-policy":       OpInfoCannedPolicy,
+	adminPrefix + "/set-user-or-group-policy": OpSetPolicyForUserOrGroup,
+	adminPrefix + "/set-bucket-quota":         OpPutBucketQuotaConfig,
+	adminPrefix + "/get-bucket-quota":         OpGetBucketQuotaConfig,
+	adminPrefix + "/info":                     OpServerInfo,
+}
+
+// adminUserInfo is the madmin-go UserInfo, add-user requests give the secret key and the status
+type adminUserInfo struct {
+	SecretKey  string `json:"secretKey,omitempty"`
+	PolicyName string `json:"policyName,omitempty"`
+	Status     string `json:"status"`
+}
+
+// adminPolicyInfo is the madmin-go PolicyInfo
+type adminPolicyInfo struct {
+	PolicyName string
+	Policy     json.RawMessage
+}
+
+// adminBucketQuota is the madmin-go BucketQuota
+type adminBucketQuota struct {
+	Quota uint64 `json:"quota"`
+	Type  string `json:"quotatype,omitempty"`
+}
+
+// adminCount is the count of the madmin-go server info buckets and objects
+type adminCount struct {
+	Count uint64 `json:"count"`
+}
+
+// adminServerInfo is the subset of the madmin-go InfoMessage describing an embedded server
+type adminServerInfo struct {
+	Mode         string     `json:"mode"`
+	Region       string     `json:"region"`
+	DeploymentID string     `json:"deploymentID"`
+	Buckets      adminCount `json:"buckets"`
+	Objects      adminCount `json:"objects"`
+	Usage        struct {
+		Size uint64 `json:"size"`
+	} `json:"usage"`
+	Servers []adminServerProperties `json:"servers"`
+}
+
+type adminServerProperties struct {
+	State          string `json:"state"`
+	Endpoint       string `json:"endpoint"`
+	Uptime         int64  `json:"uptime"`
+	Version        string `json:"version"`
+	NumCPU         int    `json:"num_cpu"`
+	RuntimeVersion string `json:"runtime_version"`
+}
+
+// Identifiers of the AEAD and key derivation of madmin-go encrypted data
+const (
+	adminArgon2idAESGCM           = 0x00
+	adminArgon2idChaCha20Poly1305 = 0x01
+	adminPBKDF2AESGCM             = 0x02
+
+	adminSaltSize  = 32
+	adminNonceSize = 8
+)
+
+// adminKey derives the encryption key of madmin-go encrypted data from password
+func adminKey(id byte, password string, salt []byte) ([]byte, error) {
+	switch id {
+	case adminArgon2idAESGCM, adminArgon2idChaCha20Poly1305:
+		return argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32), nil
+	case adminPBKDF2AESGCM:
+		return pbkdf2.Key([]byte(password), salt, 8192, 32, sha256.New), nil
+	}
+	return nil, fmt.Errorf("unknown encryption scheme %#x", id)
+}
+
+// encryptAdminData encrypts data the way madmin-go EncryptData does, with a key derived from
+// password. The ciphertext is salt | AEAD id | nonce | sio stream.
+func encryptAdminData(password string, data []byte) ([]byte, error) {
+	header := make([]byte, adminSaltSize+1+adminNonceSize)
+	if _, err := rand.Read(header); err != nil {
+		return nil, err
+	}
+	header[adminSaltSize] = adminArgon2idAESGCM
+	key, err := adminKey(adminArgon2idAESGCM, password, header[:adminSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	stream, err := sio.AES_256_GCM.Stream(key)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(header)
+	w := stream.EncryptWriter(buf, header[adminSaltSize+1:], nil)
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decryptAdminData decrypts data encrypted by madmin-go EncryptData with password
+func decryptAdminData(password string, data []byte) ([]byte, error) {
+	if len(data) < adminSaltSize+1+adminNonceSize {
+		return nil, errors.New("encrypted data is too short")
+	}
+	salt, id, nonce := data[:adminSaltSize], data[adminSaltSize], data[adminSaltSize+1:adminSaltSize+1+adminNonceSize]
+	key, err := adminKey(id, password, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead := sio.AES_256_GCM
+	if id == adminArgon2idChaCha20Poly1305 {
+		aead = sio.ChaCha20Poly1305
+	}
+	stream, err := aead.Stream(key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(stream.DecryptReader(bytes.NewReader(data[len(salt)+1+len(nonce):]), nonce, nil))
+}
+
+// readAdminBody decodes the JSON body of an admin request into v, encrypted bodies are keyed by
+// the secret key of the access key that signed the request
+func (api *ApiServer) readAdminBody(ctx *gin.Context, encrypted bool, v any) error {
+	data, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxAdminBodySize))
+	if err != nil {
+		return ErrIncompleteBody
+	}
+	if encrypted {
+		secret, _, err := api.GetMS().GetIAM().lookupCredentials(ctx.GetString(accessKeyKey))
+		if err != nil {
+			return err
+		}
+		if data, err = decryptAdminData(secret, data); err != nil {
+			apiErr := ErrAdminConfigBadJSON
+			apiErr.Description = fmt.Sprintf("cannot decrypt the request body: %v", err)
+			return apiErr
+		}
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		apiErr := ErrAdminConfigBadJSON
+		apiErr.Description = err.Error()
+		return apiErr
+	}
+	return nil
+}
+
+// adminResponse writes v as the JSON body of an admin response, encrypted like readAdminBody
+// expects encrypted request bodies
+func (api *ApiServer) adminResponse(ctx *gin.Context, encrypted bool, v any) {
+	data, err := json.Marshal(v)
+	if err == nil && encrypted {
+		var secret string
+		if secret, _, err = api.GetMS().GetIAM().lookupCredentials(ctx.GetString(accessKeyKey)); err == nil {
+			data, err = encryptAdminData(secret, data)
+		}
+	}
+	if err != nil {
+		ErrResponse(ctx, "", "", ToAPIError(err))
+		return
+	}
+	ctx.Writer.Header().Set("Content-Type", "application/json")
+	SuccessResponse(ctx, http.StatusOK, data)
+}
+
+// adminResult responds to an admin request that has no response body
+func adminResult(ctx *gin.Context, bucket string, err error) {
+	if err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+	SuccessResponse(ctx, http.StatusOK, nil)
+}
+
+// accountEnabledStatus parses the status of an account
+func accountEnabledStatus(status string) (bool, error) {
+	switch status {
+	case accountEnabled, "":
+		return true, nil
+	case accountDisabled:
+		return false, nil
+	}
+	apiErr := ErrInvalidArgument
+	apiErr.Description = fmt.Sprintf("invalid account status %q", status)
+	return false, apiErr
+}
+
+// AddUser adds a user or updates its secret key and status, the body is encrypted
+func (api *ApiServer) AddUser(ctx *gin.Context) {
+	access := ctx.Query("accessKey")
+	var req adminUserInfo
+	err := api.readAdminBody(ctx, true, &req)
+	var enabled bool
+	if err == nil {
+		enabled, err = accountEnabledStatus(req.Status)
+	}
+	iam := api.GetMS().GetIAM()
+	if err == nil && req.SecretKey != "" {
+		err = iam.AddUser(access, req.SecretKey)
+	}
+	if err == nil {
+		err = iam.SetUserEnabled(access, enabled)
+	}
+	adminResult(ctx, "", err)
+}
+
+// RemoveUser removes a user
+func (api *ApiServer) RemoveUser(ctx *gin.Context) {
+	adminResult(ctx, "", api.GetMS().GetIAM().RemoveUser(ctx.Query("accessKey")))
+}
+
+// ListUsers lists the users by access key, the response is encrypted
+func (api *ApiServer) ListUsers(ctx *gin.Context) {
+	users := make(map[string]adminUserInfo)
+	for _, user := range api.GetMS().GetIAM().ListUsers() {
+		users[user.AccessKey] = newAdminUserInfo(user)
+	}
+	api.adminResponse(ctx, true, users)
+}
+
+// GetUserInfo returns the policies and the status of a user
+func (api *ApiServer) GetUserInfo(ctx *gin.Context) {
+	user, ok := api.GetMS().GetIAM().GetUser(ctx.Query("accessKey"))
+	if !ok {
+		ErrResponse(ctx, "", "", ErrAdminNoSuchUser)
+		return
+	}
+	api.adminResponse(ctx, false, newAdminUserInfo(user))
+}
+
+func newAdminUserInfo(user User) adminUserInfo {
+	info := adminUserInfo{PolicyName: strings.Join(user.Policies, ","), Status: accountEnabled}
+	if !user.Enabled {
+		info.Status = accountDisabled
+	}
+	return info
+}
+
+// SetUserStatus enables or disables a user
+func (api *ApiServer) SetUserStatus(ctx *gin.Context) {
+	enabled, err := accountEnabledStatus(ctx.Query("status"))
+	if err == nil {
+		err = api.GetMS().GetIAM().SetUserEnabled(ctx.Query("accessKey"), enabled)
+	}
+	adminResult(ctx, "", err)
+}
+
+// AddCannedPolicy stores the policy document of the body under a name
+func (api *ApiServer) AddCannedPolicy(ctx *gin.Context) {
+	document, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxAdminBodySize))
+	if err != nil {
+		ErrResponse(ctx, "", "", ErrIncompleteBody)
+		return
+	}
+	adminResult(ctx, "", api.GetMS().GetIAM().SetPolicy(ctx.Query("name"), document))
+}
+
+// RemoveCannedPolicy removes a policy and detaches it from the users
+func (api *ApiServer) RemoveCannedPolicy(ctx *gin.Context) {
+	adminResult(ctx, "", api.GetMS().GetIAM().RemovePolicy(ctx.Query("name")))
+}
+
+// ListCannedPolicies returns the policy documents by name
+func (api *ApiServer) ListCannedPolicies(ctx *gin.Context) {
+	iam := api.GetMS().GetIAM()
+	policies := make(map[string]*Policy)
+	for _, name := range iam.ListPolicies() {
+		if policy, ok := iam.GetPolicy(name); ok {
+			policies[name] = policy
+		}
+	}
+	api.adminResponse(ctx, false, policies)
+}
+
+// InfoCannedPolicy returns a policy document, wrapped in a madmin-go PolicyInfo when v=2
+func (api *ApiServer) InfoCannedPolicy(ctx *gin.Context) {
+	name := ctx.Query("name")
+	policy, ok := api.GetMS().GetIAM().GetPolicy(name)
+	if !ok {
+		ErrResponse(ctx, "", "", ErrAdminNoSuchPolicy)
+		return
+	}
+	if ctx.Query("v") != "2" {
+		api.adminResponse(ctx, false, policy)
+		return
+	}
+	document, err := json.Marshal(policy)
+	if err != nil {
+		ErrResponse(ctx, "", "", ToAPIError(err))
+		return
+	}
+	api.adminResponse(ctx, false, adminPolicyInfo{PolicyName: name, Policy: document})
+}
+
+// SetUserOrGroupPolicy replaces the policies of a user by the comma separated policyName,
+// groups are not supported
+func (api *ApiServer) SetUserOrGroupPolicy(ctx *gin.Context) {
+	if ctx.Query("isGroup") == "true" {
+		apiErr := ErrNotImplemented
+		apiErr.Description = "groups are not supported"
+		ErrResponse(ctx, "", "", apiErr)
+		return
+	}
+	var policies []string
+	for _, name := range strings.Split(ctx.Query("policyName"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			policies = append(policies, name)
+		}
+	}
+	adminResult(ctx, "", api.GetMS().GetIAM().SetUserPolicies(ctx.Query("userOrGroup"), policies...))
+}
+
+// SetBucketQuota sets the hard quota of a bucket, a zero quota removes it
+func (api *ApiServer) SetBucketQuota(ctx *gin.Context) {
+	bucket := ctx.Query("bucket")
+	var quota adminBucketQuota
+	if err := api.readAdminBody(ctx, false, &quota); err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+	if quota.Quota > 0 && quota.Type != quotaTypeHard {
+		apiErr := ErrAdminConfigBadJSON
+		apiErr.Description = fmt.Sprintf("unsupported quota type %q", quota.Type)
+		ErrResponse(ctx, "", bucket, apiErr)
+		return
+	}
+	adminResult(ctx, bucket, api.GetMS().SetBucketQuota(bucket, quota.Quota))
+}
+
+// GetBucketQuota returns the hard quota of a bucket
+func (api *ApiServer) GetBucketQuota(ctx *gin.Context) {
+	bucket := ctx.Query("bucket")
+	quota, err := api.GetMS().GetBucketQuota(bucket)
+	if err == nil && quota == 0 {
+		err = ErrAdminNoSuchQuotaConfiguration
+	}
+	if err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+	api.adminResponse(ctx, false, adminBucketQuota{Quota: quota, Type: quotaTypeHard})
+}
+
+// ServerInfo describes the server, its buckets and their usage
+func (api *ApiServer) ServerInfo(ctx *gin.Context) {
+	info := adminServerInfo{
+		Mode:         "online",
+		Region:       api.GetMS().GetRegion(),
+		DeploymentID: api.deploymentID,
+		Servers: []adminServerProperties{{
+			State:          "online",
+			Endpoint:       ctx.Request.Host,
+			Uptime:         int64(time.Since(api.started).Seconds()),
+			Version:        "gominio",
+			NumCPU:         runtime.NumCPU(),
+			RuntimeVersion: runtime.Version(),
+		}},
+	}
+	info.Buckets.Count, info.Objects.Count, info.Usage.Size = api.GetMS().usage()
+	api.adminResponse(ctx, false, info)
+}
+
+// usage returns the number of buckets and objects of the server and the size of the objects
+func (ms *MinioServer) usage() (buckets, objects, size uint64) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	for _, bd := range ms.Buckets {
+		buckets++
+		for _, oi := range bd.Objects {
+			objects++
+			size += oi.Size
+		}
+	}
+	return buckets, objects, size
+}
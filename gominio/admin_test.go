@@ -0,0 +1,243 @@
+package gominio
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/signer"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// adminDo sends an admin request signed like madmin-go signs them and returns the status and
+// the body of the response
+func adminDo(t *testing.T, server *TestServer, access, secret, method, path string, query url.Values, body []byte) (int, []byte) {
+	t.Helper()
+	u := "http://" + server.Endpoint + adminPrefix + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	require.NoError(t, err)
+	sum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	req = signer.SignV4(*req, access, secret, "", "")
+
+	rsp, err := server.HTTPClient.Do(req)
+	require.NoError(t, err)
+	data, err := io.ReadAll(rsp.Body)
+	_ = rsp.Body.Close()
+	require.NoError(t, err)
+	return rsp.StatusCode, data
+}
+
+// adminErrorCode returns the error code of an admin error response
+func adminErrorCode(t *testing.T, data []byte) string {
+	var rsp APIErrorResponse
+	require.NoError(t, xml.Unmarshal(data, &rsp), string(data))
+	return rsp.Code
+}
+
+func TestAdminData(t *testing.T) {
+	data := []byte(`{"secretKey":"user-secret","status":"enabled"}`)
+	encrypted, err := encryptAdminData("password", data)
+	require.NoError(t, err)
+	require.NotContains(t, string(encrypted), "user-secret")
+
+	decrypted, err := decryptAdminData("password", encrypted)
+	require.NoError(t, err)
+	require.Equal(t, data, decrypted)
+
+	_, err = decryptAdminData("wrong password", encrypted)
+	require.Error(t, err)
+	_, err = decryptAdminData("password", encrypted[:20])
+	require.Error(t, err)
+}
+
+func TestAdminUsers(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	ctx := context.Background()
+	do := func(method, path string, query url.Values, body []byte) (int, []byte) {
+		return adminDo(t, server, server.Access, server.Secret, method, path, query, body)
+	}
+	encrypt := func(v any) []byte {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		data, err = encryptAdminData(server.Secret, data)
+		require.NoError(t, err)
+		return data
+	}
+
+	// admin requests are always authenticated
+	rsp, err := server.HTTPClient.Get("http://" + server.Endpoint + adminPrefix + "/list-users")
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusForbidden, rsp.StatusCode)
+	status, data := adminDo(t, server, server.Access, "wrong-secret", http.MethodGet, "/list-users", nil, nil)
+	require.Equal(t, http.StatusForbidden, status)
+	require.Equal(t, "SignatureDoesNotMatch", adminErrorCode(t, data))
+
+	status, data = do(http.MethodPut, "/add-canned-policy", url.Values{"name": {"readwrite"}}, []byte(`{
+		"Version": "2012-10-17",
+		"Statement": [{"Effect": "Allow", "Action": ["s3:GetObject", "s3:PutObject"], "Resource": "arn:aws:s3:::test/*"}]
+	}`))
+	require.Equal(t, http.StatusOK, status, string(data))
+	status, data = do(http.MethodPut, "/add-canned-policy", url.Values{"name": {"admin"}}, []byte(`{
+		"Statement": [{"Effect": "Allow", "Action": ["admin:ListUsers", "admin:GetUser"]}]
+	}`))
+	require.Equal(t, http.StatusOK, status, string(data))
+	status, data = do(http.MethodPut, "/add-canned-policy", url.Values{"name": {"bad"}}, []byte(`not a policy`))
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, "MalformedPolicy", adminErrorCode(t, data))
+
+	// add-user bodies are encrypted with the secret key of the caller
+	status, data = do(http.MethodPut, "/add-user", url.Values{"accessKey": {"provisioned"}},
+		encrypt(adminUserInfo{SecretKey: "provisioned-secret", Status: accountEnabled}))
+	require.Equal(t, http.StatusOK, status, string(data))
+	status, data = do(http.MethodPut, "/add-user", url.Values{"accessKey": {"plain"}},
+		[]byte(`{"secretKey":"plain-secret","status":"enabled"}`))
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, "XMinioAdminConfigBadJSON", adminErrorCode(t, data))
+
+	status, data = do(http.MethodPut, "/set-user-or-group-policy",
+		url.Values{"policyName": {"readwrite,admin"}, "userOrGroup": {"provisioned"}, "isGroup": {"false"}}, nil)
+	require.Equal(t, http.StatusOK, status, string(data))
+	status, data = do(http.MethodPut, "/set-user-or-group-policy",
+		url.Values{"policyName": {"missing"}, "userOrGroup": {"provisioned"}, "isGroup": {"false"}}, nil)
+	require.Equal(t, http.StatusNotFound, status)
+	require.Equal(t, "XMinioAdminNoSuchPolicy", adminErrorCode(t, data))
+	status, _ = do(http.MethodPut, "/set-user-or-group-policy",
+		url.Values{"policyName": {"readwrite"}, "userOrGroup": {"developers"}, "isGroup": {"true"}}, nil)
+	require.Equal(t, http.StatusNotImplemented, status)
+
+	// list-users responses are encrypted with the secret key of the caller
+	status, data = do(http.MethodGet, "/list-users", nil, nil)
+	require.Equal(t, http.StatusOK, status, string(data))
+	data, err = decryptAdminData(server.Secret, data)
+	require.NoError(t, err)
+	var users map[string]adminUserInfo
+	require.NoError(t, json.Unmarshal(data, &users))
+	require.Equal(t, map[string]adminUserInfo{
+		"provisioned": {PolicyName: "readwrite,admin", Status: accountEnabled},
+	}, users)
+
+	// provisioned users are allowed what their policies allow, admin actions included
+	client, err := minio.New(server.Endpoint, &minio.Options{
+		Creds: credentials.NewStaticV4("provisioned", "provisioned-secret", ""),
+	})
+	require.NoError(t, err)
+	content := `hello world`
+	_, err = client.PutObject(ctx, "test", "hello.txt", strings.NewReader(content),
+		int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+	status, data = adminDo(t, server, "provisioned", "provisioned-secret", http.MethodGet, "/user-info",
+		url.Values{"accessKey": {"provisioned"}}, nil)
+	require.Equal(t, http.StatusOK, status, string(data))
+	var user adminUserInfo
+	require.NoError(t, json.Unmarshal(data, &user))
+	require.Equal(t, "readwrite,admin", user.PolicyName)
+	status, data = adminDo(t, server, "provisioned", "provisioned-secret", http.MethodGet, "/list-users", nil, nil)
+	require.Equal(t, http.StatusOK, status, string(data))
+	_, err = decryptAdminData("provisioned-secret", data)
+	require.NoError(t, err)
+	status, data = adminDo(t, server, "provisioned", "provisioned-secret", http.MethodDelete, "/remove-user",
+		url.Values{"accessKey": {"provisioned"}}, nil)
+	require.Equal(t, http.StatusForbidden, status)
+	require.Equal(t, "AccessDenied", adminErrorCode(t, data))
+
+	status, data = do(http.MethodPut, "/set-user-status",
+		url.Values{"accessKey": {"provisioned"}, "status": {accountDisabled}}, nil)
+	require.Equal(t, http.StatusOK, status, string(data))
+	_, err = client.StatObject(ctx, "test", "hello.txt", minio.StatObjectOptions{})
+	require.Equal(t, "AccessDenied", minio.ToErrorResponse(err).Code)
+
+	// policies can be read back and removed
+	status, data = do(http.MethodGet, "/info-canned-policy", url.Values{"name": {"readwrite"}, "v": {"2"}}, nil)
+	require.Equal(t, http.StatusOK, status, string(data))
+	var info adminPolicyInfo
+	require.NoError(t, json.Unmarshal(data, &info))
+	require.Equal(t, "readwrite", info.PolicyName)
+	_, err = ParsePolicy(info.Policy)
+	require.NoError(t, err)
+	status, data = do(http.MethodGet, "/list-canned-policies", nil, nil)
+	require.Equal(t, http.StatusOK, status, string(data))
+	var policies map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &policies))
+	require.Len(t, policies, 2)
+	status, data = do(http.MethodDelete, "/remove-canned-policy", url.Values{"name": {"admin"}}, nil)
+	require.Equal(t, http.StatusOK, status, string(data))
+	status, data = do(http.MethodGet, "/info-canned-policy", url.Values{"name": {"admin"}}, nil)
+	require.Equal(t, http.StatusNotFound, status)
+	require.Equal(t, "XMinioAdminNoSuchPolicy", adminErrorCode(t, data))
+
+	status, data = do(http.MethodDelete, "/remove-user", url.Values{"accessKey": {"provisioned"}}, nil)
+	require.Equal(t, http.StatusOK, status, string(data))
+	status, data = do(http.MethodDelete, "/remove-user", url.Values{"accessKey": {"provisioned"}}, nil)
+	require.Equal(t, http.StatusNotFound, status)
+	require.Equal(t, "XMinioAdminNoSuchUser", adminErrorCode(t, data))
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpRemoveUser, Status: http.StatusNotFound}, 1)
+}
+
+func TestAdminBucketQuota(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	ctx := context.Background()
+	do := func(method, path string, query url.Values, body []byte) (int, []byte) {
+		return adminDo(t, server, server.Access, server.Secret, method, path, query, body)
+	}
+	put := func(object string) error {
+		content := `hello world`
+		_, err := server.Client.PutObject(ctx, "test", object, strings.NewReader(content),
+			int64(len(content)), minio.PutObjectOptions{})
+		return err
+	}
+
+	status, data := do(http.MethodGet, "/get-bucket-quota", url.Values{"bucket": {"test"}}, nil)
+	require.Equal(t, http.StatusNotFound, status)
+	require.Equal(t, "XMinioAdminNoSuchQuotaConfiguration", adminErrorCode(t, data))
+	status, data = do(http.MethodPut, "/set-bucket-quota", url.Values{"bucket": {"missing"}},
+		[]byte(`{"quota":16,"quotatype":"hard"}`))
+	require.Equal(t, http.StatusNotFound, status)
+	require.Equal(t, "NoSuchBucket", adminErrorCode(t, data))
+	status, _ = do(http.MethodPut, "/set-bucket-quota", url.Values{"bucket": {"test"}},
+		[]byte(`{"quota":16,"quotatype":"fifo"}`))
+	require.Equal(t, http.StatusBadRequest, status)
+
+	status, data = do(http.MethodPut, "/set-bucket-quota", url.Values{"bucket": {"test"}},
+		[]byte(`{"quota":16,"quotatype":"hard"}`))
+	require.Equal(t, http.StatusOK, status, string(data))
+	status, data = do(http.MethodGet, "/get-bucket-quota", url.Values{"bucket": {"test"}}, nil)
+	require.Equal(t, http.StatusOK, status, string(data))
+	require.JSONEq(t, `{"quota":16,"quotatype":"hard"}`, string(data))
+
+	// writes beyond the quota fail, replacing an object only counts the new version
+	server.NoError(put("hello.txt"))
+	server.NoError(put("hello.txt"))
+	require.Equal(t, "XMinioAdminBucketQuotaExceeded", minio.ToErrorResponse(put("other.txt")).Code)
+	_, err := server.Client.CopyObject(ctx, minio.CopyDestOptions{Bucket: "test", Object: "copy.txt"},
+		minio.CopySrcOptions{Bucket: "test", Object: "hello.txt"})
+	require.Equal(t, "XMinioAdminBucketQuotaExceeded", minio.ToErrorResponse(err).Code)
+
+	status, data = do(http.MethodGet, "/info", nil, nil)
+	require.Equal(t, http.StatusOK, status, string(data))
+	var info adminServerInfo
+	require.NoError(t, json.Unmarshal(data, &info))
+	require.Equal(t, uint64(1), info.Buckets.Count)
+	require.Equal(t, uint64(1), info.Objects.Count)
+	require.Equal(t, uint64(11), info.Usage.Size)
+	require.Len(t, info.Servers, 1)
+	require.Equal(t, "online", info.Servers[0].State)
+
+	status, data = do(http.MethodPut, "/set-bucket-quota", url.Values{"bucket": {"test"}}, []byte(`{"quota":0}`))
+	require.Equal(t, http.StatusOK, status, string(data))
+	server.NoError(put("other.txt"))
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpPutBucketQuotaConfig}, 4)
+}
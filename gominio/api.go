@@ -1,62 +1,231 @@
 package gominio
 
 import (
-	"encoding/xml"
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7/pkg/notification"
 	"github.com/minio/minio-go/v7/pkg/tags"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 type ApiServer struct {
-	ms *MinioServer
+	ms      *MinioServer
+	router  *gin.Engine
+	domain  string
+	journal *Journal
+	faults  *FaultInjector
+	shaper  *Shaper
+
+	hostID       string
+	deploymentID string
+	started      time.Time
+	requests     uint64
+
+	// websiteDomain is the base domain of the website endpoints of the buckets
+	websiteDomain string
 }
 
 func (api *ApiServer) GetMS() *MinioServer {
 	return api.ms
 }
 
+// GetJournal returns the journal of requests served by api
+func (api *ApiServer) GetJournal() *Journal {
+	return api.journal
+}
+
+// GetFaults returns the fault rules applied to requests served by api
+func (api *ApiServer) GetFaults() *FaultInjector {
+	return api.faults
+}
+
+// GetShaper returns the latency and throughput rules applied to requests served by api
+func (api *ApiServer) GetShaper() *Shaper {
+	return api.shaper
+}
+
+// SetDomain sets the base domain of virtual-hosted-style requests, requests to bucket.<domain>
+// are served as if they were addressed path-style to /bucket. An empty domain disables it.
+func (api *ApiServer) SetDomain(domain string) {
+	api.domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+}
+
+// SetWebsiteDomain sets the base domain of the website endpoints, requests to bucket.<domain>
+// are served by the website of the bucket. An empty domain disables it.
+func (api *ApiServer) SetWebsiteDomain(domain string) {
+	api.websiteDomain = strings.ToLower(strings.TrimSuffix(domain, "."))
+}
+
+// ServeHTTP serves requests addressed both path-style and virtual-hosted-style, and the
+// requests addressed to the website endpoints of the buckets
+func (api *ApiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if bucket := bucketFromHost(r.Host, api.websiteDomain); bucket != "" {
+		api.serveWebsite(w, r, bucket)
+		return
+	}
+	if bucket := bucketFromHost(r.Host, api.domain); bucket != "" {
+		r = r.WithContext(context.WithValue(r.Context(), originalPathKey{}, r.URL.Path))
+		r.URL.Path = "/" + bucket + r.URL.Path
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = "/" + bucket + r.URL.RawPath
+		}
+	}
+	api.router.ServeHTTP(w, r)
+}
+
+// bucketFromHost returns the bucket addressed by a virtual-hosted-style host of domain
+func bucketFromHost(host, domain string) string {
+	if domain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	bucket := strings.TrimSuffix(host, "."+domain)
+	if bucket == host || bucket == "" {
+		return ""
+	}
+	return bucket
+}
+
 // RegisterApiRouter register S3 requests routers
 func RegisterApiRouter(router *gin.Engine, minioServer *MinioServer) *ApiServer {
 	api := &ApiServer{
-		ms: minioServer,
-	}
+		ms:      minioServer,
+		router:  router,
+		journal: NewJournal(),
+		faults:  NewFaultInjector(),
+		shaper:  NewShaper(),
+		hostID:  fmt.Sprintf("%x", sha256.Sum256([]byte(GetUid()))),
+
+		deploymentID: GetUid(),
+		started:      time.Now(),
+	}
+
+	// Middlewares must be registered before the routes they apply to
+	router.Use(api.requestIDMiddleware, objectParamMiddleware, api.corsMiddleware, api.journalMiddleware, api.shapingMiddleware,
+		api.faultMiddleware, api.regionMiddleware, api.authMiddleware)
+
+	// Control routers
+	router.GET(controlPrefix+"/faults", api.ListFaults)
+	router.POST(controlPrefix+"/faults", api.AddFault)
+	router.DELETE(controlPrefix+"/faults", api.DeleteFault)
+
+	// Admin routers, served for madmin-go clients
+	router.PUT(adminPrefix+"/add-user", api.AddUser)
+	router.DELETE(adminPrefix+"/remove-user", api.RemoveUser)
+	router.GET(adminPrefix+"/list-users", api.ListUsers)
+	router.GET(adminPrefix+"/user-info", api.GetUserInfo)
+	router.PUT(adminPrefix+"/set-user-status", api.SetUserStatus)
+	router.PUT(adminPrefix+"/add-canned-policy", api.AddCannedPolicy)
+	router.DELETE(adminPrefix+"/remove-canned-policy", api.RemoveCannedPolicy)
+	router.GET(adminPrefix+"/list-canned-policies", api.ListCannedPolicies)
+	router.GET(adminPrefix+"/info-canned-policy", api.InfoCannedPolicy)
+	router.PUT(adminPrefix+"/set-user-or-group-policy", api.SetUserOrGroupPolicy)
+	router.PUT(adminPrefix+"/set-bucket-quota", api.SetBucketQuota)
+	router.GET(adminPrefix+"/get-bucket-quota", api.GetBucketQuota)
+	router.GET(adminPrefix+"/info", api.ServerInfo)
 
-	// Bucket routers
 	router.GET("/", api.ListBucket)
-	router.HEAD("/:bucket/", api.HeadBucket)
-	router.GET("/:bucket/", api.GetBucket)
-	router.PUT("/:bucket/", api.PutBucket)
-	router.DELETE("/:bucket/", api.DeleteBucket)
-
-	// Object routers
-	router.HEAD("/:bucket/:object", api.HeadObject)
-	router.PUT("/:bucket/:object", api.PutObject)
-	router.POST("/:bucket/:object", api.MultipartObject)
-	router.DELETE("/:bucket/:object", api.DeleteObject)
-	router.GET("/:bucket/:object", api.GetObject)
+	router.POST("/", api.STS)
+	router.HandleMethodNotAllowed = true
+	router.NoMethod(func(ctx *gin.Context) {
+		ErrResponse(ctx, ctx.Param("object"), ctx.Param("bucket"), ErrMethodNotAllowed)
+	})
+
+	// Bucket and object routers
+	handle(router, http.MethodHead, api.HeadBucket, api.HeadObject)
+	handle(router, http.MethodGet, api.GetBucket, api.GetObject)
+	handle(router, http.MethodPut, api.PutBucket, api.PutObject)
+	handle(router, http.MethodPost, nil, api.MultipartObject)
+	handle(router, http.MethodDelete, api.DeleteBucket, api.DeleteObject)
+	handle(router, http.MethodOptions, api.Options, api.Options)
 
 	return api
 }
 
+// handle routes method requests to the bucket handler when they have no object key and to the
+// object handler otherwise. Object keys may contain slashes, so they are matched by a catch-all.
+func handle(router *gin.Engine, method string, bucketHandler, objectHandler gin.HandlerFunc) {
+	dispatch := func(ctx *gin.Context) {
+		if ctx.Param("object") != "" {
+			objectHandler(ctx)
+			return
+		}
+		if bucketHandler == nil {
+			ErrResponse(ctx, "", ctx.Param("bucket"), ErrMethodNotAllowed)
+			return
+		}
+		bucketHandler(ctx)
+	}
+	router.Handle(method, "/:bucket", dispatch)
+	router.Handle(method, "/:bucket/*object", dispatch)
+}
+
+const (
+	amzRequestID        = "x-amz-request-id"
+	amzID2              = "x-amz-id-2"
+	amzTagging          = "x-amz-tagging"
+	amzTaggingCount     = "x-amz-tagging-count"
+	amzTaggingDirective = "x-amz-tagging-directive"
+	amzCopySource       = "x-amz-copy-source"
+)
+
+// requestIDMiddleware sets the request id and host id headers of every response
+func (api *ApiServer) requestIDMiddleware(ctx *gin.Context) {
+	api.setRequestID(ctx.Writer.Header())
+}
+
+func (api *ApiServer) setRequestID(header http.Header) {
+	seq := atomic.AddUint64(&api.requests, 1)
+	header.Set(amzRequestID, fmt.Sprintf("%X%04X", time.Now().UnixNano(), seq&0xffff))
+	header.Set(amzID2, api.hostID)
+}
+
+// objectParamMiddleware strips the leading slash the catch-all route leaves on object keys
+func objectParamMiddleware(ctx *gin.Context) {
+	for i, param := range ctx.Params {
+		if param.Key == "object" {
+			ctx.Params[i].Value = strings.TrimPrefix(param.Value, "/")
+		}
+	}
+}
+
 // HeadBucket head bucket
 func (api *ApiServer) HeadBucket(ctx *gin.Context) {
 	bucket := ctx.Param("bucket")
-	if !api.GetMS().BucketExists(bucket) {
+	region, ok := api.GetMS().GetBucketLocation(bucket)
+	if !ok {
 		// Bucket not exists
 		ctx.Writer.WriteHeader(ErrNoSuchBucket.HTTPStatusCode)
 		return
 	}
 
+	ctx.Writer.Header().Set("x-amz-bucket-region", region)
+
 	SuccessResponse(ctx, http.StatusOK, nil)
 }
 
 // ListBucket list bucket
 func (api *ApiServer) ListBucket(ctx *gin.Context) {
+	if isListenRequest(ctx) {
+		api.ListenBucketNotification(ctx)
+		return
+	}
+
 	// list bucket
 	lr := api.GetMS().ListBucket()
 	SuccessResponse(ctx, http.StatusOK, lr.Encode())
@@ -70,25 +239,57 @@ func (api *ApiServer) GetBucket(ctx *gin.Context) {
 		lifecycle  bool
 		encryption bool
 		versioning bool
+		tagging    bool
+		notify     bool
 		bucket     string
 	)
 
+	if isListenRequest(ctx) {
+		api.ListenBucketNotification(ctx)
+		return
+	}
+	if _, ok := ctx.GetQuery("cors"); ok {
+		api.getBucketCors(ctx)
+		return
+	}
+	if _, ok := ctx.GetQuery("replication"); ok {
+		api.getBucketReplication(ctx)
+		return
+	}
+	if _, ok := ctx.GetQuery("website"); ok {
+		api.getBucketWebsite(ctx)
+		return
+	}
+
 	_, location = ctx.GetQuery("location")
 	_, policy = ctx.GetQuery("policy")
 	_, lifecycle = ctx.GetQuery("lifecycle")
 	_, encryption = ctx.GetQuery("encryption")
 	_, versioning = ctx.GetQuery("versioning")
-	if location || policy || lifecycle || encryption || versioning {
-		bucket = ctx.Param("bucket")
-		if !api.GetMS().BucketExists(bucket) {
-			// Bucket not exists
-			ErrResponse(ctx, "", bucket, ErrNoSuchBucket)
-			return
-		}
+	_, tagging = ctx.GetQuery("tagging")
+	_, notify = ctx.GetQuery("notification")
+	if !(location || policy || lifecycle || encryption || versioning || tagging || notify) {
+		api.listObjects(ctx)
+		return
+	}
+	bucket = ctx.Param("bucket")
+	if !api.GetMS().BucketExists(bucket) {
+		// Bucket not exists
+		ErrResponse(ctx, "", bucket, ErrNoSuchBucket)
+		return
 	}
 
 	if location {
-		SuccessResponse(ctx, http.StatusOK, LocationResponse{}.Encode())
+		region, ok := api.GetMS().GetBucketLocation(bucket)
+		if !ok {
+			ErrResponse(ctx, "", bucket, ErrNoSuchBucket)
+			return
+		}
+		// Buckets of us-east-1 have an empty location constraint
+		if region == defaultRegion {
+			region = ""
+		}
+		SuccessResponse(ctx, http.StatusOK, LocationResponse{Location: region}.Encode())
 		return
 	}
 
@@ -99,9 +300,36 @@ func (api *ApiServer) GetBucket(ctx *gin.Context) {
 			return
 		}
 		SuccessResponse(ctx, http.StatusOK, []byte(content))
+		return
+	}
+
+	if tagging {
+		tag, err := api.GetMS().GetBucketTagging(bucket)
+		if err != nil {
+			ErrResponse(ctx, "", bucket, ToAPIError(err))
+			return
+		}
+		SuccessResponse(ctx, http.StatusOK, encodeAny(tag))
+		return
+	}
+
+	if notify {
+		cfg, err := api.GetMS().GetBucketNotification(bucket)
+		if err != nil {
+			ErrResponse(ctx, "", bucket, ToAPIError(err))
+			return
+		}
+		SuccessResponse(ctx, http.StatusOK, encodeAny(cfg))
 	}
 }
 
+// isListenRequest reports whether the request is a MinIO ListenBucketNotification request
+func isListenRequest(ctx *gin.Context) bool {
+	_, events := ctx.GetQuery("events")
+	_, ping := ctx.GetQuery("ping")
+	return events || ping
+}
+
 // PutBucket create bucket
 func (api *ApiServer) PutBucket(ctx *gin.Context) {
 	var (
@@ -118,6 +346,26 @@ func (api *ApiServer) PutBucket(ctx *gin.Context) {
 	_, lifecycle = ctx.GetQuery("lifecycle")
 	_, encryption = ctx.GetQuery("encryption")
 	_, versioning = ctx.GetQuery("versioning")
+	if _, ok := ctx.GetQuery("tagging"); ok {
+		api.putBucketTagging(ctx)
+		return
+	}
+	if _, ok := ctx.GetQuery("notification"); ok {
+		api.putBucketNotification(ctx)
+		return
+	}
+	if _, ok := ctx.GetQuery("cors"); ok {
+		api.putBucketCors(ctx)
+		return
+	}
+	if _, ok := ctx.GetQuery("replication"); ok {
+		api.putBucketReplication(ctx)
+		return
+	}
+	if _, ok := ctx.GetQuery("website"); ok {
+		api.putBucketWebsite(ctx)
+		return
+	}
 	if policy || lifecycle || encryption || versioning {
 		bucket = ctx.Param("bucket")
 		if !api.GetMS().BucketExists(bucket) {
@@ -144,9 +392,46 @@ func (api *ApiServer) PutBucket(ctx *gin.Context) {
 	}
 
 	bucket = ctx.Param("bucket")
-	if !api.GetMS().MakeBucket(bucket) {
-		// bucket exists
-		ErrResponse(ctx, "", bucket, ErrBucketAlreadyOwnedByYou)
+	var cfg = new(CreateBucketConfiguration)
+	if err = cfg.Decode(ctx.Request.Body); err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+	if err = api.GetMS().CreateBucket(bucket, cfg.LocationConstraint); err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, nil)
+}
+
+func (api *ApiServer) putBucketTagging(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	tag, err := tags.ParseBucketXML(ctx.Request.Body)
+	if err != nil {
+		ErrResponse(ctx, "", bucket, tagsAPIError(err))
+		return
+	}
+
+	err = api.GetMS().SetBucketTagging(bucket, tag)
+	if err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusNoContent, nil)
+}
+
+func (api *ApiServer) putBucketNotification(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	var cfg = new(notification.Configuration)
+	if err := decodeAny(ctx.Request.Body, cfg); err != nil {
+		ErrResponse(ctx, "", bucket, ErrMalformedXML)
+		return
+	}
+
+	if err := api.GetMS().SetBucketNotification(bucket, cfg); err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
 		return
 	}
 
@@ -162,6 +447,44 @@ func (api *ApiServer) DeleteBucket(ctx *gin.Context) {
 	)
 
 	bucket = ctx.Param("bucket")
+	if _, ok := ctx.GetQuery("tagging"); ok {
+		err = api.GetMS().RemoveBucketTagging(bucket)
+		if err != nil {
+			ErrResponse(ctx, "", bucket, ToAPIError(err))
+			return
+		}
+		SuccessResponse(ctx, http.StatusNoContent, nil)
+		return
+	}
+	if _, ok := ctx.GetQuery("cors"); ok {
+		err = api.GetMS().RemoveBucketCors(bucket)
+		if err != nil {
+			ErrResponse(ctx, "", bucket, ToAPIError(err))
+			return
+		}
+		SuccessResponse(ctx, http.StatusNoContent, nil)
+		return
+	}
+	if _, ok := ctx.GetQuery("replication"); ok {
+		// MinIO answers with 200 OK, minio-go rejects other statuses
+		err = api.GetMS().RemoveBucketReplication(bucket)
+		if err != nil {
+			ErrResponse(ctx, "", bucket, ToAPIError(err))
+			return
+		}
+		SuccessResponse(ctx, http.StatusOK, nil)
+		return
+	}
+	if _, ok := ctx.GetQuery("website"); ok {
+		err = api.GetMS().RemoveBucketWebsite(bucket)
+		if err != nil {
+			ErrResponse(ctx, "", bucket, ToAPIError(err))
+			return
+		}
+		SuccessResponse(ctx, http.StatusNoContent, nil)
+		return
+	}
+
 	forceArg := ctx.Request.Header.Get("x-minio-force-delete")
 	if forceArg != "" {
 		force, err = strconv.ParseBool(forceArg)
@@ -174,7 +497,7 @@ func (api *ApiServer) DeleteBucket(ctx *gin.Context) {
 	}
 	err = api.GetMS().DelBucket(bucket, force)
 	if err != nil {
-		ErrResponse(ctx, "", bucket, ErrBucketNotEmpty)
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
 		return
 	}
 
@@ -196,14 +519,16 @@ func (api *ApiServer) HeadObject(ctx *gin.Context) {
 
 	oi, err = api.GetMS().GetObject(bucket, object)
 	if err != nil {
-		apiErr := ErrInvalidRequest
-		apiErr.Description = err.Error()
-		ErrResponse(ctx, object, bucket, apiErr)
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
 		return
 	}
 	ctx.Writer.Header().Set("Last-Modified", oi.LastModified.Format(http.TimeFormat))
 	ctx.Writer.Header()["ETag"] = []string{"\"" + oi.Etag + "\""}
 	ctx.Writer.Header().Set("Content-Length", fmt.Sprintf("%d", oi.Size))
+	setTaggingCount(ctx, oi)
+	setChecksumHeader(ctx.Request, ctx.Writer.Header(), oi.Checksum)
+	setStorageHeaders(ctx.Writer.Header(), oi, api.GetMS().now())
+	setReplicationStatus(ctx.Writer.Header(), oi)
 	SuccessResponse(ctx, http.StatusOK, nil)
 }
 
@@ -215,21 +540,15 @@ func (api *ApiServer) putObjectTagging(ctx *gin.Context) {
 	bucket = ctx.Param("bucket")
 	object = ctx.Param("object")
 
-	tag, err := tags.MapToObjectTags(map[string]string{})
+	tag, err := tags.ParseObjectXML(ctx.Request.Body)
 	if err != nil {
-		ErrResponse(ctx, object, bucket, ErrInvalidRequest)
-		return
-	}
-
-	err = xml.NewDecoder(ctx.Request.Body).Decode(tag)
-	if err != nil {
-		ErrResponse(ctx, object, bucket, ErrInvalidRequest)
+		ErrResponse(ctx, object, bucket, tagsAPIError(err))
 		return
 	}
 
 	err = api.GetMS().PutObjectTagging(bucket, object, tag)
 	if err != nil {
-		ErrResponse(ctx, object, bucket, ErrNoSuchKey)
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
 		return
 	}
 
@@ -249,27 +568,29 @@ func (api *ApiServer) putObjectOrPart(ctx *gin.Context) {
 	etag = GetUid()
 	bucket = ctx.Param("bucket")
 	object = ctx.Param("object")
-	data, err := io.ReadAll(ctx.Request.Body)
+	tag, err := parseTaggingHeader(ctx)
 	if err != nil {
-		ErrResponse(ctx, object, bucket, ErrInvalidRequest)
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
 		return
 	}
-
-	// get upload data
-	content := ""
-	datas := strings.Split(string(data), "\n")
-	for i := 1; i < len(datas)-3; i++ {
-		content += datas[i] + "\n"
+	content, trailer, err := readObjectBody(ctx.Request)
+	if err != nil {
+		ErrResponse(ctx, object, bucket, ErrIncompleteBody)
+		return
 	}
-	if len(content) > 1 {
-		content = content[:len(content)-2]
+	checksum, err := requestChecksum(ctx.Request, trailer)
+	if err != nil {
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
+		return
 	}
 
 	// upload part processing
 	if part, ok := ctx.GetQuery("partNumber"); ok {
 		partNumber, err = strconv.Atoi(part)
 		if err != nil {
-			ErrResponse(ctx, object, bucket, ErrInvalidRequest)
+			apiErr := ErrInvalidArgument
+			apiErr.Description = "Part number must be an integer between 1 and 10000, inclusive"
+			ErrResponse(ctx, object, bucket, apiErr)
 			return
 		}
 		uploadId, ok = ctx.GetQuery("uploadId")
@@ -277,18 +598,25 @@ func (api *ApiServer) putObjectOrPart(ctx *gin.Context) {
 			ErrResponse(ctx, object, bucket, ErrInvalidRequest)
 			return
 		}
-		err = api.GetMS().PutObjectPart(bucket, object, uploadId, etag, partNumber, []byte(content))
+		err = api.GetMS().PutObjectPartWithChecksum(bucket, object, uploadId, etag, partNumber, content, checksum)
 	} else {
-		err = api.GetMS().PutObject(bucket, object, etag, []byte(content))
+		err = api.GetMS().PutObjectWithOptions(bucket, object, etag, content, ObjectOptions{
+			Tags:         tag,
+			Checksum:     checksum,
+			StorageClass: ctx.GetHeader(amzStorageClass),
+		})
 	}
 
 	if err == nil {
+		if checksum != nil {
+			ctx.Writer.Header().Set(checksum.Header(), checksum.Value)
+		}
 		ctx.Writer.Header().Set("ETag", etag)
 		SuccessResponse(ctx, http.StatusOK, nil)
 		return
 	}
 
-	ErrResponse(ctx, object, bucket, ErrNoSuchBucket)
+	ErrResponse(ctx, object, bucket, ToAPIError(err))
 }
 
 // PutObject Upload objects, including direct upload and sharded upload
@@ -316,9 +644,102 @@ func (api *ApiServer) PutObject(ctx *gin.Context) {
 		return
 	}
 
+	if ctx.GetHeader(amzCopySource) != "" {
+		if _, ok := ctx.GetQuery("partNumber"); ok {
+			ErrResponse(ctx, ctx.Param("object"), ctx.Param("bucket"), ErrNotImplemented)
+			return
+		}
+		api.copyObject(ctx)
+		return
+	}
+
 	api.putObjectOrPart(ctx)
 }
 
+func (api *ApiServer) copyObject(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	object := ctx.Param("object")
+
+	srcBucket, srcObject, ok := parseCopySource(ctx.GetHeader(amzCopySource))
+	if !ok {
+		apiErr := ErrInvalidArgument
+		apiErr.Description = "Copy Source must mention the source bucket and key: sourcebucket/sourcekey"
+		ErrResponse(ctx, object, bucket, apiErr)
+		return
+	}
+
+	// tags are copied from the source object unless the directive replaces them
+	var tag *tags.Tags
+	var err error
+	switch directive := ctx.GetHeader(amzTaggingDirective); {
+	case strings.EqualFold(directive, "REPLACE"):
+		tag, err = parseTaggingHeader(ctx)
+		if err == nil && tag == nil {
+			tag, err = tags.MapToObjectTags(map[string]string{})
+		}
+		if err != nil {
+			ErrResponse(ctx, object, bucket, ToAPIError(err))
+			return
+		}
+	case directive != "" && !strings.EqualFold(directive, "COPY"):
+		apiErr := ErrInvalidArgument
+		apiErr.Description = "Unknown tagging directive."
+		ErrResponse(ctx, object, bucket, apiErr)
+		return
+	}
+
+	oi, err := api.GetMS().CopyObject(srcBucket, srcObject, bucket, object, GetUid(), ObjectOptions{
+		Tags:         tag,
+		StorageClass: ctx.GetHeader(amzStorageClass),
+	})
+	if err != nil {
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
+		return
+	}
+	ctx.Writer.Header()["ETag"] = []string{"\"" + oi.Etag + "\""}
+	SuccessResponse(ctx, http.StatusOK, CopyObjectResult{
+		ETag:         "\"" + oi.Etag + "\"",
+		LastModified: oi.LastModified.UTC(),
+	}.Encode())
+}
+
+// parseCopySource returns the bucket and key of a x-amz-copy-source header, the copy source is
+// "/bucket/key" optionally followed by "?versionId="
+func parseCopySource(header string) (bucket, object string, ok bool) {
+	source, err := url.PathUnescape(header)
+	if i := strings.Index(source, "?"); i >= 0 {
+		source = source[:i]
+	}
+	bucket, object, ok = strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	return bucket, object, err == nil && ok && bucket != "" && object != ""
+}
+
+// parseTaggingHeader returns the tags of the url encoded x-amz-tagging header, nil when it is not set
+func parseTaggingHeader(ctx *gin.Context) (*tags.Tags, error) {
+	header := ctx.GetHeader(amzTagging)
+	if header == "" {
+		return nil, nil
+	}
+
+	tag, err := tags.ParseObjectTags(header)
+	if err != nil {
+		apiErr := ErrInvalidTag
+		apiErr.Description = err.Error()
+		return nil, apiErr
+	}
+	return tag, nil
+}
+
+// setTaggingCount reports the number of tags of the object, the header is omitted without tags
+func setTaggingCount(ctx *gin.Context, oi *ObjectInfo) {
+	if oi.Tags == nil {
+		return
+	}
+	if count := len(oi.Tags.ToMap()); count > 0 {
+		ctx.Writer.Header().Set(amzTaggingCount, strconv.Itoa(count))
+	}
+}
+
 // MultipartObject Including creating a shard upload ID and completing shard upload
 func (api *ApiServer) MultipartObject(ctx *gin.Context) {
 	var (
@@ -333,15 +754,37 @@ func (api *ApiServer) MultipartObject(ctx *gin.Context) {
 	bucket = ctx.Param("bucket")
 	object = ctx.Param("object")
 
+	if _, ok := ctx.GetQuery("select"); ok {
+		api.SelectObjectContent(ctx)
+		return
+	}
+	if _, ok := ctx.GetQuery("restore"); ok {
+		api.restoreObject(ctx)
+		return
+	}
+
 	// Processing of creating sharded upload ID
 	_, uploads = ctx.GetQuery("uploads")
 	if uploads {
+		tag, err := parseTaggingHeader(ctx)
+		if err != nil {
+			ErrResponse(ctx, object, bucket, ToAPIError(err))
+			return
+		}
 		uploadId = GetUid()
-		err = api.GetMS().PutObjectPart(bucket, object, uploadId, "", 0, nil)
+		algorithm := ctx.GetHeader(amzChecksumAlgorithm)
+		err = api.GetMS().InitiateMultipartUpload(bucket, object, uploadId, ObjectOptions{
+			Tags:         tag,
+			Checksum:     &Checksum{Algorithm: algorithm},
+			StorageClass: ctx.GetHeader(amzStorageClass),
+		})
 		if err != nil {
-			ErrResponse(ctx, object, bucket, ErrInvalidRequest)
+			ErrResponse(ctx, object, bucket, ToAPIError(err))
 			return
 		}
+		if algorithm != "" {
+			ctx.Writer.Header().Set(amzChecksumAlgorithm, strings.ToUpper(algorithm))
+		}
 		SuccessResponse(ctx, http.StatusOK, InitiateMultipartUploadResult{
 			Bucket:   bucket,
 			Key:      object,
@@ -357,17 +800,25 @@ func (api *ApiServer) MultipartObject(ctx *gin.Context) {
 		return
 	}
 	var parts = new(CompleteMultiPart)
-	parts.Decode(ctx.Request.Body)
+	if err = parts.Decode(ctx.Request.Body); err != nil {
+		ErrResponse(ctx, object, bucket, ErrMalformedXML)
+		return
+	}
+	if parts.Checksum, err = requestChecksum(ctx.Request, nil); err != nil {
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
+		return
+	}
 	etag, err = api.GetMS().CompleteObjectPart(bucket, object, uploadId, parts)
 	if err != nil {
-		ErrResponse(ctx, object, bucket, ErrInvalidRequest)
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
 		return
 	}
 	ctx.Writer.Header().Set("ETag", etag)
 	SuccessResponse(ctx, http.StatusOK, CompleteMultipartUploadResponse{
-		Bucket: bucket,
-		Key:    object,
-		ETag:   etag,
+		Bucket:         bucket,
+		Key:            object,
+		ETag:           etag,
+		checksumFields: newChecksumFields(parts.Checksum),
 	}.Encode())
 }
 
@@ -391,7 +842,7 @@ func (api *ApiServer) DeleteObject(ctx *gin.Context) {
 	if tagging {
 		err = api.GetMS().RemoveObjectTagging(bucket, object)
 		if err != nil {
-			ErrResponse(ctx, object, bucket, ErrNoSuchKey)
+			ErrResponse(ctx, object, bucket, ToAPIError(err))
 			return
 		}
 		SuccessResponse(ctx, http.StatusNoContent, nil)
@@ -406,11 +857,20 @@ func (api *ApiServer) DeleteObject(ctx *gin.Context) {
 		return
 	}
 
+	if uploadId, ok := ctx.GetQuery("uploadId"); ok {
+		err = api.GetMS().AbortMultipartUpload(bucket, object, uploadId)
+		if err != nil {
+			ErrResponse(ctx, object, bucket, ToAPIError(err))
+			return
+		}
+		SuccessResponse(ctx, http.StatusNoContent, nil)
+		return
+	}
+
+	// Deleting a missing key succeeds like S3 does
 	err = api.GetMS().DeleteObject(bucket, object)
-	if err != nil {
-		apiErr := ErrInvalidRequest
-		apiErr.Description = err.Error()
-		ErrResponse(ctx, object, bucket, apiErr)
+	if err != nil && !errors.Is(err, ErrObjectNotExists) {
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
 		return
 	}
 	SuccessResponse(ctx, http.StatusNoContent, nil)
@@ -431,7 +891,7 @@ func (api *ApiServer) GetObject(ctx *gin.Context) {
 	object = ctx.Param("object")
 	oi, err = api.GetMS().GetObject(bucket, object)
 	if err != nil {
-		ErrResponse(ctx, object, bucket, ErrNoSuchKey)
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
 		return
 	}
 
@@ -452,8 +912,22 @@ func (api *ApiServer) GetObject(ctx *gin.Context) {
 		return
 	}
 
+	if _, ok := ctx.GetQuery("attributes"); ok {
+		api.getObjectAttributes(ctx, oi)
+		return
+	}
+
+	now := api.GetMS().now()
+	if err = oi.checkReadable(now); err != nil {
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
+		return
+	}
 	ctx.Writer.Header().Set("Content-Length", fmt.Sprintf("%d", oi.Size))
 	ctx.Writer.Header().Set("Last-Modified", oi.LastModified.Format(http.TimeFormat))
+	setTaggingCount(ctx, oi)
+	setChecksumHeader(ctx.Request, ctx.Writer.Header(), oi.Checksum)
+	setStorageHeaders(ctx.Writer.Header(), oi, now)
+	setReplicationStatus(ctx.Writer.Header(), oi)
 	SuccessResponse(ctx, http.StatusOK, oi.Data)
 }
 
@@ -468,11 +942,20 @@ func SuccessResponse(ctx *gin.Context, status int, data []byte) {
 
 // ErrResponse error response
 func ErrResponse(ctx *gin.Context, key, bucket string, apiErr APIError) {
+	errResponseWithRegion(ctx, key, bucket, "", apiErr)
+}
+
+// errResponseWithRegion error response telling the client the region it should have used
+func errResponseWithRegion(ctx *gin.Context, key, bucket, region string, apiErr APIError) {
 	apiRsp := APIErrorResponse{
 		Code:       apiErr.Code,
 		Message:    apiErr.Description,
 		Key:        key,
 		BucketName: bucket,
+		Resource:   ctx.Request.URL.Path,
+		Region:     region,
+		RequestID:  ctx.Writer.Header().Get(amzRequestID),
+		HostID:     ctx.Writer.Header().Get(amzID2),
 	}
 	ctx.Writer.WriteHeader(apiErr.HTTPStatusCode)
 	_, err := ctx.Writer.Write(apiRsp.Encode())
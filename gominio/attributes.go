@@ -0,0 +1,144 @@
+package gominio
+
+import (
+	"encoding/xml"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+const (
+	amzObjectAttributes = "x-amz-object-attributes"
+	amzMaxParts         = "x-amz-max-parts"
+	amzPartNumberMarker = "x-amz-part-number-marker"
+	// defaultMaxParts is the number of parts listed by GetObjectAttributes when not limited
+	defaultMaxParts = 1000
+)
+
+// objectAttributes are the attributes x-amz-object-attributes may select, in lowercase
+var objectAttributes = map[string]bool{
+	"etag":         true,
+	"checksum":     true,
+	"objectparts":  true,
+	"storageclass": true,
+	"objectsize":   true,
+}
+
+type ObjectAttributesPart struct {
+	PartNumber int
+	Size       int64
+	checksumFields
+}
+
+type ObjectAttributesParts struct {
+	PartsCount           int
+	PartNumberMarker     int
+	NextPartNumberMarker int
+	MaxParts             int
+	IsTruncated          bool
+	Parts                []ObjectAttributesPart `xml:"Part"`
+}
+
+type GetObjectAttributesResponse struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ GetObjectAttributesResponse" json:"-"`
+
+	ETag         string                 `xml:",omitempty"`
+	Checksum     *checksumFields        `xml:",omitempty"`
+	ObjectParts  *ObjectAttributesParts `xml:",omitempty"`
+	StorageClass string                 `xml:",omitempty"`
+	ObjectSize   *uint64                `xml:",omitempty"`
+}
+
+func (r GetObjectAttributesResponse) Encode() []byte {
+	return encodeAny(r)
+}
+
+// listParts returns the page of the object parts following marker
+func (oi *ObjectInfo) listParts(marker, maxParts int) *ObjectAttributesParts {
+	parts := &ObjectAttributesParts{
+		PartsCount:       len(oi.ObjectParts),
+		PartNumberMarker: marker,
+		MaxParts:         maxParts,
+	}
+	for _, part := range oi.ObjectParts {
+		if part.PartNumber <= marker {
+			continue
+		}
+		if len(parts.Parts) == maxParts {
+			parts.IsTruncated = true
+			break
+		}
+		parts.Parts = append(parts.Parts, ObjectAttributesPart{
+			PartNumber:     part.PartNumber,
+			Size:           part.Size,
+			checksumFields: newChecksumFields(part.Checksum),
+		})
+		parts.NextPartNumberMarker = part.PartNumber
+	}
+	return parts
+}
+
+// getObjectAttributes returns the attributes of an object selected by x-amz-object-attributes,
+// the parts of multipart objects are paged with x-amz-max-parts and x-amz-part-number-marker
+func (api *ApiServer) getObjectAttributes(ctx *gin.Context, oi *ObjectInfo) {
+	bucket, object := ctx.Param("bucket"), ctx.Param("object")
+	invalid := func(description string) {
+		apiErr := ErrInvalidArgument
+		apiErr.Description = description
+		ErrResponse(ctx, object, bucket, apiErr)
+	}
+
+	attributes := splitHeaderList(ctx.GetHeader(amzObjectAttributes))
+	if len(attributes) == 0 {
+		invalid("The x-amz-object-attributes header specifying the attributes to be retrieved is either missing or empty")
+		return
+	}
+	selected := make(map[string]bool)
+	for _, attribute := range attributes {
+		if !objectAttributes[attribute] {
+			invalid("Invalid attribute name specified.")
+			return
+		}
+		selected[attribute] = true
+	}
+	maxParts, marker := defaultMaxParts, 0
+	if value := ctx.GetHeader(amzMaxParts); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			invalid("Argument max-parts must be an integer between 0 and 2147483647")
+			return
+		}
+		if n < maxParts {
+			maxParts = n
+		}
+	}
+	if value := ctx.GetHeader(amzPartNumberMarker); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			invalid("Argument part-number-marker must be an integer between 0 and 2147483647")
+			return
+		}
+		marker = n
+	}
+
+	var rsp GetObjectAttributesResponse
+	if selected["etag"] {
+		rsp.ETag = oi.Etag
+	}
+	if selected["checksum"] && oi.Checksum != nil {
+		fields := newChecksumFields(oi.Checksum)
+		rsp.Checksum = &fields
+	}
+	if selected["objectparts"] && len(oi.ObjectParts) > 0 {
+		rsp.ObjectParts = oi.listParts(marker, maxParts)
+	}
+	if selected["storageclass"] {
+		rsp.StorageClass = oi.GetStorageClass()
+	}
+	if selected["objectsize"] {
+		size := oi.Size
+		rsp.ObjectSize = &size
+	}
+	ctx.Writer.Header().Set("Last-Modified", oi.LastModified.Format(http.TimeFormat))
+	SuccessResponse(ctx, http.StatusOK, rsp.Encode())
+}
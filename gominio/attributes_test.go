@@ -0,0 +1,125 @@
+package gominio
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetObjectAttributes(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"), WithServerConfig(func(cfg *ServerConfig) {
+		cfg.MinPartSize = 1
+	}))
+	core := minio.Core{Client: server.Client}
+	ctx := context.Background()
+	encode := base64.StdEncoding.EncodeToString
+
+	id, err := core.NewMultipartUpload(ctx, "test", "multi.txt", minio.PutObjectOptions{
+		UserMetadata: map[string]string{"X-Amz-Checksum-Algorithm": "SHA256"},
+	})
+	require.NoError(t, err)
+	var parts []minio.CompletePart
+	var sums []string
+	for i, data := range []string{"hello", " ", "world!"} {
+		part, err := core.PutObjectPart(ctx, "test", "multi.txt", id, 2*i+1, strings.NewReader(data),
+			int64(len(data)), minio.PutObjectPartOptions{})
+		require.NoError(t, err)
+		parts = append(parts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+		sum := sha256.Sum256([]byte(data))
+		sums = append(sums, encode(sum[:]))
+	}
+	info, err := core.CompleteMultipartUpload(ctx, "test", "multi.txt", id, parts, minio.PutObjectOptions{})
+	require.NoError(t, err)
+	_, err = server.Client.PutObject(ctx, "test", "single.txt", strings.NewReader("single"), 6,
+		minio.PutObjectOptions{})
+	require.NoError(t, err)
+
+	attributes := func(object string, header map[string]string) (*http.Response, *GetObjectAttributesResponse) {
+		req, err := http.NewRequest(http.MethodGet, "/test/"+object+"?attributes", nil)
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rsp, err := server.Do(req)
+		require.NoError(t, err)
+		defer rsp.Body.Close()
+		data, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		if rsp.StatusCode != http.StatusOK {
+			return rsp, nil
+		}
+		attrs := new(GetObjectAttributesResponse)
+		require.NoError(t, xml.Unmarshal(data, attrs), string(data))
+		return rsp, attrs
+	}
+
+	rsp, attrs := attributes("multi.txt", map[string]string{
+		amzObjectAttributes: "ETag, Checksum, ObjectParts, StorageClass, ObjectSize",
+		amzMaxParts:         "2",
+	})
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.NotEmpty(t, rsp.Header.Get("Last-Modified"))
+	require.Equal(t, strings.Trim(info.ETag, `"`), attrs.ETag)
+	require.Equal(t, info.ChecksumSHA256, attrs.Checksum.ChecksumSHA256)
+	require.True(t, strings.HasSuffix(attrs.Checksum.ChecksumSHA256, "-3"))
+	require.Equal(t, "STANDARD", attrs.StorageClass)
+	require.Equal(t, uint64(12), *attrs.ObjectSize)
+	require.Equal(t, &ObjectAttributesParts{
+		PartsCount:           3,
+		NextPartNumberMarker: 3,
+		MaxParts:             2,
+		IsTruncated:          true,
+		Parts: []ObjectAttributesPart{
+			{PartNumber: 1, Size: 5, checksumFields: checksumFields{ChecksumSHA256: sums[0]}},
+			{PartNumber: 3, Size: 1, checksumFields: checksumFields{ChecksumSHA256: sums[1]}},
+		},
+	}, attrs.ObjectParts)
+
+	_, attrs = attributes("multi.txt", map[string]string{
+		amzObjectAttributes: "ObjectParts",
+		amzPartNumberMarker: "3",
+	})
+	require.Empty(t, attrs.ETag)
+	require.Nil(t, attrs.ObjectSize)
+	require.Equal(t, &ObjectAttributesParts{
+		PartsCount:           3,
+		PartNumberMarker:     3,
+		NextPartNumberMarker: 5,
+		MaxParts:             defaultMaxParts,
+		Parts: []ObjectAttributesPart{
+			{PartNumber: 5, Size: 6, checksumFields: checksumFields{ChecksumSHA256: sums[2]}},
+		},
+	}, attrs.ObjectParts)
+
+	// objects uploaded at once have no parts
+	_, attrs = attributes("single.txt", map[string]string{amzObjectAttributes: "ObjectParts,ObjectSize,Checksum"})
+	require.Nil(t, attrs.ObjectParts)
+	require.Nil(t, attrs.Checksum)
+	require.Equal(t, uint64(6), *attrs.ObjectSize)
+
+	for _, header := range []map[string]string{
+		{},
+		{amzObjectAttributes: "ETag,Owner"},
+		{amzObjectAttributes: "ETag", amzMaxParts: "many"},
+	} {
+		rsp, _ = attributes("multi.txt", header)
+		require.Equal(t, http.StatusBadRequest, rsp.StatusCode, header)
+	}
+	rsp, _ = attributes("missing.txt", map[string]string{amzObjectAttributes: "ETag"})
+	require.Equal(t, http.StatusNotFound, rsp.StatusCode)
+
+	// the object data is still served whole
+	obj, err := server.Client.GetObject(ctx, "test", "multi.txt", minio.GetObjectOptions{})
+	require.NoError(t, err)
+	data, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	require.Equal(t, "hello world!", string(data))
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpGetObjectAttributes}, 7)
+}
@@ -0,0 +1,393 @@
+package gominio
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const signV4Algorithm = "AWS4-HMAC-SHA256"
+
+// credentialScope is the credential of a signature version 4 request
+type credentialScope struct {
+	AccessKey string
+	Date      string
+	Region    string
+	Service   string
+}
+
+// authorizationFields returns the fields of a signature version 4 Authorization header, ok is
+// false for other Authorization headers
+func authorizationFields(r *http.Request) (map[string]string, bool) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, signV4Algorithm) {
+		return nil, false
+	}
+	fields := make(map[string]string)
+	for _, field := range strings.Split(strings.TrimPrefix(auth, signV4Algorithm), ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(field), "="); ok {
+			fields[key] = value
+		}
+	}
+	return fields, true
+}
+
+// parseCredential extracts the credential of a signature version 4 request from its
+// Authorization header or its presigned query, ok is false for other requests.
+func parseCredential(r *http.Request) (credentialScope, bool) {
+	credential := r.URL.Query().Get("X-Amz-Credential")
+	if fields, ok := authorizationFields(r); ok {
+		credential = fields["Credential"]
+	}
+
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != "aws4_request" {
+		return credentialScope{}, false
+	}
+	return credentialScope{
+		AccessKey: parts[0],
+		Date:      parts[1],
+		Region:    parts[2],
+		Service:   parts[3],
+	}, true
+}
+
+// regionMiddleware rejects requests signed for another region than the one of the bucket they
+// address. Like S3, location lookups, bucket listings and bucket creations may use any region.
+func (api *ApiServer) regionMiddleware(ctx *gin.Context) {
+	if api.GetMS().Region == "" {
+		return
+	}
+	switch GetOperation(ctx) {
+	case OpGetBucketLocation, OpListBuckets, OpCreateBucket, OpUnknown:
+		return
+	}
+	// madmin-go signs admin requests for an empty region
+	if strings.HasPrefix(ctx.FullPath(), adminPrefix) {
+		return
+	}
+
+	scope, ok := parseCredential(ctx.Request)
+	if !ok || scope.Service != "s3" {
+		return
+	}
+
+	bucket := ctx.Param("bucket")
+	expected, ok := api.GetMS().GetBucketLocation(bucket)
+	if !ok {
+		expected = api.GetMS().GetRegion()
+	}
+	if scope.Region == expected {
+		return
+	}
+
+	apiErr := ErrAuthorizationHeaderMalformed
+	apiErr.Description = fmt.Sprintf("The authorization header is malformed; the region '%s' is wrong; expecting '%s'",
+		scope.Region, expected)
+	ctx.Writer.Header().Set("x-amz-bucket-region", expected)
+	errResponseWithRegion(ctx, ctx.Param("object"), bucket, expected, apiErr)
+	ctx.Abort()
+}
+
+const (
+	amzSecurityToken = "X-Amz-Security-Token"
+
+	iso8601Format   = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// maxRequestTimeSkew is the largest difference allowed between the date of a request and the
+	// time of the server
+	maxRequestTimeSkew = 15 * time.Minute
+)
+
+// originalPathKey is the request context key of the path of a request before ServeHTTP rewrites
+// it, signatures are computed over the path sent by the client
+type originalPathKey struct{}
+
+// requestPath returns the path r was sent to by the client
+func requestPath(r *http.Request) string {
+	if path, ok := r.Context().Value(originalPathKey{}).(string); ok {
+		return path
+	}
+	return r.URL.Path
+}
+
+// verifySignature checks the signature version 4 of a request signed with its Authorization
+// header or presigned, and returns the credential it is signed with
+func (iam *IAM) verifySignature(r *http.Request) (credentialScope, error) {
+	scope, ok := parseCredential(r)
+	if !ok {
+		return scope, ErrAccessDenied
+	}
+	secret, token, err := iam.lookupCredentials(scope.AccessKey)
+	if err != nil {
+		return scope, err
+	}
+
+	query := r.URL.Query()
+	var signedHeaders, signature, date, requestToken string
+	fields, ok := authorizationFields(r)
+	if ok {
+		signedHeaders, signature = fields["SignedHeaders"], fields["Signature"]
+		date = r.Header.Get("X-Amz-Date")
+		requestToken = r.Header.Get(amzSecurityToken)
+	} else {
+		signedHeaders, signature = query.Get("X-Amz-SignedHeaders"), query.Get("X-Amz-Signature")
+		date = query.Get("X-Amz-Date")
+		requestToken = query.Get(amzSecurityToken)
+	}
+	if requestToken != token {
+		return scope, ErrInvalidToken
+	}
+	t, err := time.Parse(iso8601Format, date)
+	if err != nil {
+		apiErr := ErrAccessDenied
+		apiErr.Description = "AWS authentication requires a valid Date or x-amz-date header"
+		return scope, apiErr
+	}
+	if ok {
+		if skew := time.Since(t); skew > maxRequestTimeSkew || skew < -maxRequestTimeSkew {
+			return scope, ErrRequestTimeTooSkewed
+		}
+	} else {
+		expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err != nil || expires < 0 {
+			apiErr := ErrAccessDenied
+			apiErr.Description = "X-Amz-Expires must be a non-negative integer"
+			return scope, apiErr
+		}
+		if time.Since(t) > time.Duration(expires)*time.Second {
+			apiErr := ErrAccessDenied
+			apiErr.Description = "Request has expired"
+			return scope, apiErr
+		}
+	}
+
+	hashedPayload, err := payloadHash(r, scope)
+	if err != nil {
+		return scope, err
+	}
+	query.Del("X-Amz-Signature")
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		s3utils.EncodePath(requestPath(r)),
+		strings.ReplaceAll(query.Encode(), "+", "%20"),
+		canonicalHeaders(r, strings.Split(signedHeaders, ";")),
+		signedHeaders,
+		hashedPayload,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		signV4Algorithm,
+		date,
+		strings.Join([]string{scope.Date, scope.Region, scope.Service, "aws4_request"}, "/"),
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + secret)
+	for _, data := range []string{scope.Date, scope.Region, scope.Service, "aws4_request", stringToSign} {
+		key = sumHMAC(key, data)
+	}
+	if !hmac.Equal([]byte(hex.EncodeToString(key)), []byte(signature)) {
+		return scope, ErrSignatureDoesNotMatch
+	}
+	return scope, nil
+}
+
+// payloadHash returns the payload hash a request is signed with. S3 requests carry it in the
+// x-amz-content-sha256 header or are unsigned, STS requests sign the hash of their body.
+func payloadHash(r *http.Request, scope credentialScope) (string, error) {
+	if hash := r.Header.Get("X-Amz-Content-Sha256"); hash != "" {
+		return hash, nil
+	}
+	if scope.Service != "sts" || r.Body == nil {
+		return unsignedPayload, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", ErrIncompleteBody
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// canonicalHeaders returns the signed headers of r in the canonical form of signature version 4
+func canonicalHeaders(r *http.Request, signedHeaders []string) string {
+	var buf strings.Builder
+	for _, name := range signedHeaders {
+		values := r.Header.Values(name)
+		switch {
+		case name == "host":
+			values = []string{r.Host}
+		case name == "content-length" && len(values) == 0:
+			values = []string{strconv.FormatInt(r.ContentLength, 10)}
+		}
+		for i, value := range values {
+			values[i] = strings.Join(strings.Fields(value), " ")
+		}
+		buf.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+	return buf.String()
+}
+
+func sumHMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// operationActions are the policy actions required by the operations
+var operationActions = map[string]string{
+	OpListBuckets:         "s3:ListAllMyBuckets",
+	OpHeadBucket:          "s3:ListBucket",
+	OpCreateBucket:        "s3:CreateBucket",
+	OpDeleteBucket:        "s3:DeleteBucket",
+	OpListObjects:         "s3:ListBucket",
+	OpGetBucketLocation:   "s3:GetBucketLocation",
+	OpGetBucketPolicy:     "s3:GetBucketPolicy",
+	OpPutBucketPolicy:     "s3:PutBucketPolicy",
+	OpGetBucketLifecycle:  "s3:GetLifecycleConfiguration",
+	OpPutBucketLifecycle:  "s3:PutLifecycleConfiguration",
+	OpGetBucketEncryption: "s3:GetEncryptionConfiguration",
+	OpPutBucketEncryption: "s3:PutEncryptionConfiguration",
+	OpGetBucketVersioning: "s3:GetBucketVersioning",
+	OpPutBucketVersioning: "s3:PutBucketVersioning",
+	OpGetBucketTagging:    "s3:GetBucketTagging",
+	OpPutBucketTagging:    "s3:PutBucketTagging",
+	OpDeleteBucketTagging: "s3:PutBucketTagging",
+	OpGetBucketCors:       "s3:GetBucketCORS",
+	OpPutBucketCors:       "s3:PutBucketCORS",
+	OpDeleteBucketCors:    "s3:PutBucketCORS",
+
+	OpGetBucketReplication:    "s3:GetReplicationConfiguration",
+	OpPutBucketReplication:    "s3:PutReplicationConfiguration",
+	OpDeleteBucketReplication: "s3:PutReplicationConfiguration",
+	OpGetBucketWebsite:        "s3:GetBucketWebsite",
+	OpPutBucketWebsite:        "s3:PutBucketWebsite",
+	OpDeleteBucketWebsite:     "s3:DeleteBucketWebsite",
+
+	OpGetBucketNotification:    "s3:GetBucketNotification",
+	OpPutBucketNotification:    "s3:PutBucketNotification",
+	OpListenNotification:       "s3:ListenNotification",
+	OpListenBucketNotification: "s3:ListenBucketNotification",
+
+	OpHeadObject:              "s3:GetObject",
+	OpGetObject:               "s3:GetObject",
+	OpPutObject:               "s3:PutObject",
+	OpCopyObject:              "s3:PutObject",
+	OpDeleteObject:            "s3:DeleteObject",
+	OpGetObjectTagging:        "s3:GetObjectTagging",
+	OpPutObjectTagging:        "s3:PutObjectTagging",
+	OpDeleteObjectTagging:     "s3:DeleteObjectTagging",
+	OpGetObjectRetention:      "s3:GetObjectRetention",
+	OpPutObjectRetention:      "s3:PutObjectRetention",
+	OpGetObjectLegalHold:      "s3:GetObjectLegalHold",
+	OpPutObjectLegalHold:      "s3:PutObjectLegalHold",
+	OpCreateMultipartUpload:   "s3:PutObject",
+	OpUploadPart:              "s3:PutObject",
+	OpUploadPartCopy:          "s3:PutObject",
+	OpCompleteMultipartUpload: "s3:PutObject",
+	OpAbortMultipartUpload:    "s3:AbortMultipartUpload",
+	OpSelectObjectContent:     "s3:GetObject",
+	OpGetObjectAttributes:     "s3:GetObjectAttributes",
+	OpRestoreObject:           "s3:RestoreObject",
+
+	OpAddUser:                 "admin:CreateUser",
+	OpRemoveUser:              "admin:DeleteUser",
+	OpListUsers:               "admin:ListUsers",
+	OpGetUserInfo:             "admin:GetUser",
+	OpSetUserStatus:           "admin:EnableUser",
+	OpAddCannedPolicy:         "admin:CreatePolicy",
+	OpRemoveCannedPolicy:      "admin:DeletePolicy",
+	OpListCannedPolicies:      "admin:ListUserPolicies",
+	OpInfoCannedPolicy:        "admin:GetPolicy",
+	OpSetPolicyForUserOrGroup: "admin:AttachUserOrGroupPolicy",
+	OpPutBucketQuotaConfig:    "admin:SetBucketQuota",
+	OpGetBucketQuotaConfig:    "admin:GetBucketQuota",
+	OpServerInfo:              "admin:ServerInfo",
+}
+
+// s3Resource returns the policy resource name of a bucket or an object, of every bucket when
+// bucket is empty
+func s3Resource(bucket, object string) string {
+	switch {
+	case bucket == "":
+		return s3ResourcePrefix + "*"
+	case object == "":
+		return s3ResourcePrefix + bucket
+	}
+	return s3ResourcePrefix + bucket + "/" + object
+}
+
+// authorize checks access is allowed the action of the operation of ctx on the resource it
+// addresses, copies also require reading the source object
+func (iam *IAM) authorize(ctx *gin.Context, access string) error {
+	op := GetOperation(ctx)
+	action, ok := operationActions[op]
+	if !ok {
+		return nil
+	}
+	if op == OpSetUserStatus && ctx.Query("status") == accountDisabled {
+		action = "admin:DisableUser"
+	}
+	if !iam.IsAllowed(access, action, s3Resource(ctx.Param("bucket"), ctx.Param("object"))) {
+		return ErrAccessDenied
+	}
+	if op == OpCopyObject {
+		srcBucket, srcObject, ok := parseCopySource(ctx.GetHeader(amzCopySource))
+		if ok && !iam.IsAllowed(access, "s3:GetObject", s3Resource(srcBucket, srcObject)) {
+			return ErrAccessDenied
+		}
+	}
+	return nil
+}
+
+// accessKeyKey is the gin context key of the access key that signed an authenticated request
+const accessKeyKey = "gominio.accessKey"
+
+// authMiddleware authenticates and authorizes the requests once the identity store holds users,
+// requests must then be signed by a known access key allowed the action of their operation.
+// Admin requests are always authenticated, control requests must then be signed by the root
+// credentials.
+func (api *ApiServer) authMiddleware(ctx *gin.Context) {
+	iam := api.GetMS().GetIAM()
+	admin := strings.HasPrefix(ctx.FullPath(), adminPrefix)
+	control := strings.HasPrefix(ctx.FullPath(), controlPrefix)
+	if !admin && !iam.Enabled() {
+		return
+	}
+	// the STS handlers authenticate their requests themselves and preflight requests are not
+	// authenticated, browsers send them without credentials
+	switch GetOperation(ctx) {
+	case OpAssumeRole, OpAssumeRoleWithWebIdentity, OpOptionsObject:
+		return
+	}
+
+	scope, err := iam.verifySignature(ctx.Request)
+	switch {
+	case err != nil:
+	case scope.Service != "s3":
+		err = ErrSignatureDoesNotMatch
+	case control:
+		if !iam.isRoot(scope.AccessKey) {
+			err = ErrAccessDenied
+		}
+	default:
+		ctx.Set(accessKeyKey, scope.AccessKey)
+		err = iam.authorize(ctx, scope.AccessKey)
+	}
+	if err != nil {
+		ErrResponse(ctx, ctx.Param("object"), ctx.Param("bucket"), ToAPIError(err))
+		ctx.Abort()
+	}
+}
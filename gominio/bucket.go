@@ -3,6 +3,8 @@ package gominio
 import (
 	"encoding/xml"
 	"errors"
+	"github.com/minio/minio-go/v7/pkg/tags"
+	"io"
 	"time"
 )
 
@@ -62,33 +64,184 @@ func (ms *MinioServer) GetBucketPolicy(bucket string) (string, bool) {
 	return policy, true
 }
 
-// MakeBucket create bucket
+// SetBucketTagging set bucket tagging
+func (ms *MinioServer) SetBucketTagging(bucket string, tags *tags.Tags) error {
+	info := &HookInfo{Operation: OpPutBucketTagging, Bucket: bucket, Tags: tags}
+	return ms.hooks.run(info, func() error {
+		return ms.setBucketTagging(bucket, tags)
+	})
+}
+
+func (ms *MinioServer) setBucketTagging(bucket string, tags *tags.Tags) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return ErrBucketNotExists
+	}
+
+	bd.Info.Tags = tags
+	return nil
+}
+
+// GetBucketTagging get bucket tagging, ErrNoSuchTagSet is returned when the bucket has no tags
+func (ms *MinioServer) GetBucketTagging(bucket string) (*tags.Tags, error) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return nil, ErrBucketNotExists
+	}
+
+	if bd.Info.Tags == nil || len(bd.Info.Tags.ToMap()) == 0 {
+		return nil, ErrNoSuchTagSet
+	}
+	return bd.Info.Tags, nil
+}
+
+// RemoveBucketTagging remove bucket tagging
+func (ms *MinioServer) RemoveBucketTagging(bucket string) error {
+	info := &HookInfo{Operation: OpDeleteBucketTagging, Bucket: bucket}
+	return ms.hooks.run(info, func() error {
+		return ms.removeBucketTagging(bucket)
+	})
+}
+
+func (ms *MinioServer) removeBucketTagging(bucket string) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return ErrBucketNotExists
+	}
+
+	bd.Info.Tags = nil
+	return nil
+}
+
+// MakeBucket create bucket in the server region, false when it cannot be created
 func (ms *MinioServer) MakeBucket(bucket string) bool {
+	return ms.CreateBucket(bucket, "") == nil
+}
+
+// MakeBucketWithLocation create bucket in the given region, the server region when it is empty,
+// false when it cannot be created
+func (ms *MinioServer) MakeBucketWithLocation(bucket, location string) bool {
+	return ms.CreateBucket(bucket, location) == nil
+}
+
+// CreateBucket create bucket in the given region, the server region when it is empty. Unlike
+// MakeBucket it reports why the bucket cannot be created.
+func (ms *MinioServer) CreateBucket(bucket, location string) error {
+	info := &HookInfo{Operation: OpCreateBucket, Bucket: bucket}
+	return ms.hooks.run(info, func() error {
+		return ms.makeBucket(bucket, location)
+	})
+}
+
+func (ms *MinioServer) makeBucket(bucket, location string) error {
+	if err := CheckBucketName(bucket, !ms.LenientNames); err != nil {
+		return err
+	}
+	if location == "" {
+		location = ms.GetRegion()
+	}
+
 	ms.Lock()
 	defer ms.Unlock()
 	if _, ok := ms.Buckets[bucket]; ok {
-		return false
+		return ErrBucketAlreadyOwnedByYou
 	}
 	ms.Buckets[bucket] = &BucketData{
 		Info: BucketInfo{
-			Created: time.Now(),
+			Location: location,
+			Created:  time.Now(),
 		},
 		Objects: make(map[string]*ObjectInfo),
+		Uploads: make(map[string]*ObjectInfo),
 	}
-	return true
+	return nil
+}
+
+// GetBucketLocation get the region of bucket
+func (ms *MinioServer) GetBucketLocation(bucket string) (string, bool) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return "", false
+	}
+	return bd.Info.Location, true
+}
+
+// SetBucketQuota sets the hard quota of bucket in bytes, writes that would grow the objects of
+// the bucket beyond it fail with ErrBucketQuotaExceeded. A zero quota removes it.
+func (ms *MinioServer) SetBucketQuota(bucket string, quota uint64) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return ErrBucketNotExists
+	}
+
+	bd.Info.Quota = quota
+	return nil
+}
+
+// GetBucketQuota returns the hard quota of bucket in bytes, zero when it has none
+func (ms *MinioServer) GetBucketQuota(bucket string) (uint64, error) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return 0, ErrBucketNotExists
+	}
+	return bd.Info.Quota, nil
+}
+
+// checkQuota returns ErrBucketQuotaExceeded when writing size bytes to object, replacing any
+// previous version of it, would exceed the quota of the bucket
+func (bd *BucketData) checkQuota(object string, size uint64) error {
+	if bd.Info.Quota == 0 {
+		return nil
+	}
+
+	var used uint64
+	for name, oi := range bd.Objects {
+		if name != object {
+			used += oi.Size
+		}
+	}
+	if used+size > bd.Info.Quota {
+		return ErrBucketQuotaExceeded
+	}
+	return nil
 }
 
 // DelBucket delete bucket
 func (ms *MinioServer) DelBucket(bucket string, force bool) error {
+	info := &HookInfo{Operation: OpDeleteBucket, Bucket: bucket}
+	return ms.hooks.run(info, func() error {
+		return ms.delBucket(bucket, force)
+	})
+}
+
+func (ms *MinioServer) delBucket(bucket string, force bool) error {
 	ms.Lock()
 	defer ms.Unlock()
 	if _, ok := ms.Buckets[bucket]; !ok {
-		return nil
+		return ErrBucketNotExists
 	}
 
 	// If the deletion is not mandatory, then if there are objects in the bucket, the deletion will fail
 	if bd, ok := ms.Buckets[bucket]; ok && len(bd.Objects) > 0 && !force {
-		return errors.New("bucket not empty")
+		return ErrBucketHasObjects
 	}
 	delete(ms.Buckets, bucket)
 	return nil
@@ -103,6 +256,20 @@ func (lr LocationResponse) Encode() []byte {
 	return encodeAny(lr)
 }
 
+type CreateBucketConfiguration struct {
+	XMLName            xml.Name `xml:"CreateBucketConfiguration" json:"-"`
+	LocationConstraint string
+}
+
+// Decode decodes the optional configuration, an empty body leaves it blank. Malformed
+// configurations are MalformedXML errors.
+func (cbc *CreateBucketConfiguration) Decode(r io.Reader) error {
+	if err := decodeAny(r, cbc); err != nil && !errors.Is(err, io.EOF) {
+		return ErrMalformedXML
+	}
+	return nil
+}
+
 type ListBucketsResponse struct {
 	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListAllMyBucketsResult" json:"-"`
 	Owner   Owner
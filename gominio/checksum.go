@@ -0,0 +1,202 @@
+package gominio
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Checksum algorithms of the x-amz-checksum-* headers
+const (
+	ChecksumCRC32  = "CRC32"
+	ChecksumCRC32C = "CRC32C"
+	ChecksumSHA1   = "SHA1"
+	ChecksumSHA256 = "SHA256"
+)
+
+const (
+	amzChecksumPrefix       = "x-amz-checksum-"
+	amzChecksumAlgorithm    = "x-amz-checksum-algorithm"
+	amzSDKChecksumAlgorithm = "x-amz-sdk-checksum-algorithm"
+	amzChecksumMode         = "x-amz-checksum-mode"
+	amzTrailer              = "x-amz-trailer"
+)
+
+// checksumAlgorithms are the supported checksum algorithms in the order their headers are checked
+var checksumAlgorithms = []string{ChecksumCRC32, ChecksumCRC32C, ChecksumSHA1, ChecksumSHA256}
+
+// Checksum is the base64 encoded checksum of an object or a part, the checksum of a multipart
+// object is the checksum of the checksums of its parts followed by -<number of parts>
+type Checksum struct {
+	Algorithm string
+	Value     string
+}
+
+// newChecksumHash returns the hash of a checksum algorithm, nil when it is not supported
+func newChecksumHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case ChecksumCRC32:
+		return crc32.NewIEEE()
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case ChecksumSHA1:
+		return sha1.New()
+	case ChecksumSHA256:
+		return sha256.New()
+	}
+	return nil
+}
+
+// ParseChecksumAlgorithm returns the checksum algorithm named by s, case insensitive
+func ParseChecksumAlgorithm(s string) (string, error) {
+	algorithm := strings.ToUpper(s)
+	if newChecksumHash(algorithm) == nil {
+		apiErr := ErrInvalidRequest
+		apiErr.Description = fmt.Sprintf("Checksum algorithm %q is not supported.", s)
+		return "", apiErr
+	}
+	return algorithm, nil
+}
+
+// Header returns the name of the x-amz-checksum-* header of the checksum
+func (c *Checksum) Header() string {
+	return amzChecksumPrefix + strings.ToLower(c.Algorithm)
+}
+
+// sum returns the raw checksum of data
+func (c *Checksum) sum(data []byte) []byte {
+	h := newChecksumHash(c.Algorithm)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// Verify checks the checksum matches data, the value is computed when it is empty
+func (c *Checksum) Verify(data []byte) error {
+	h := newChecksumHash(c.Algorithm)
+	if h == nil {
+		_, err := ParseChecksumAlgorithm(c.Algorithm)
+		return err
+	}
+	computed := base64.StdEncoding.EncodeToString(c.sum(data))
+	if c.Value == "" {
+		c.Value = computed
+		return nil
+	}
+
+	if raw, err := base64.StdEncoding.DecodeString(c.Value); err != nil || len(raw) != h.Size() {
+		apiErr := ErrInvalidRequest
+		apiErr.Description = fmt.Sprintf("Value for %s header is invalid.", c.Header())
+		return apiErr
+	}
+	if c.Value != computed {
+		apiErr := ErrBadDigest
+		apiErr.Description = fmt.Sprintf("The %s you specified did not match the calculated checksum.", c.Header())
+		return apiErr
+	}
+	return nil
+}
+
+// compositeChecksum returns the checksum of the part checksums of a multipart object
+func compositeChecksum(algorithm string, parts []*Checksum) (*Checksum, error) {
+	h := newChecksumHash(algorithm)
+	for _, part := range parts {
+		raw, err := base64.StdEncoding.DecodeString(part.Value)
+		if err != nil {
+			return nil, err
+		}
+		h.Write(raw)
+	}
+	return &Checksum{
+		Algorithm: algorithm,
+		Value:     base64.StdEncoding.EncodeToString(h.Sum(nil)) + "-" + strconv.Itoa(len(parts)),
+	}, nil
+}
+
+// requestChecksum returns the checksum sent with the headers or the trailer of an upload request,
+// it has no value when the request only names its algorithm and nil when it has no checksum
+func requestChecksum(r *http.Request, trailer http.Header) (*Checksum, error) {
+	var checksum *Checksum
+	for _, algorithm := range checksumAlgorithms {
+		c := &Checksum{Algorithm: algorithm}
+		value := r.Header.Get(c.Header())
+		for _, name := range strings.Split(r.Header.Get(amzTrailer), ",") {
+			if strings.EqualFold(strings.TrimSpace(name), c.Header()) {
+				if value = trailer.Get(c.Header()); value == "" {
+					apiErr := ErrInvalidRequest
+					apiErr.Description = fmt.Sprintf("The request trailer is missing the %s checksum.", c.Header())
+					return nil, apiErr
+				}
+			}
+		}
+		if value == "" {
+			continue
+		}
+		if checksum != nil {
+			apiErr := ErrInvalidRequest
+			apiErr.Description = "Expecting a single x-amz-checksum- header. Multiple checksum Types are not allowed."
+			return nil, apiErr
+		}
+		c.Value = value
+		checksum = c
+	}
+	if checksum != nil {
+		return checksum, nil
+	}
+
+	if name := r.Header.Get(amzSDKChecksumAlgorithm); name != "" {
+		algorithm, err := ParseChecksumAlgorithm(name)
+		if err != nil {
+			return nil, err
+		}
+		return &Checksum{Algorithm: algorithm}, nil
+	}
+	return nil, nil
+}
+
+// setChecksumHeader sets the checksum header of an object when the request asks for it with
+// x-amz-checksum-mode
+func setChecksumHeader(r *http.Request, header http.Header, checksum *Checksum) {
+	if checksum != nil && strings.EqualFold(r.Header.Get(amzChecksumMode), "ENABLED") {
+		header.Set(checksum.Header(), checksum.Value)
+	}
+}
+
+// checksumFields are the checksum elements of the multipart XML documents
+type checksumFields struct {
+	ChecksumCRC32  string `xml:"ChecksumCRC32,omitempty"`
+	ChecksumCRC32C string `xml:"ChecksumCRC32C,omitempty"`
+	ChecksumSHA1   string `xml:"ChecksumSHA1,omitempty"`
+	ChecksumSHA256 string `xml:"ChecksumSHA256,omitempty"`
+}
+
+// field returns the element of an algorithm
+func (f *checksumFields) field(algorithm string) *string {
+	switch algorithm {
+	case ChecksumCRC32:
+		return &f.ChecksumCRC32
+	case ChecksumCRC32C:
+		return &f.ChecksumCRC32C
+	case ChecksumSHA1:
+		return &f.ChecksumSHA1
+	case ChecksumSHA256:
+		return &f.ChecksumSHA256
+	}
+	return nil
+}
+
+// newChecksumFields returns the elements of a checksum, they are empty when it is nil
+func newChecksumFields(checksum *Checksum) checksumFields {
+	var f checksumFields
+	if checksum != nil {
+		if field := f.field(checksum.Algorithm); field != nil {
+			*field = checksum.Value
+		}
+	}
+	return f
+}
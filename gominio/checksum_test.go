@@ -0,0 +1,130 @@
+package gominio
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/require"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestChecksums(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"), WithServerConfig(func(cfg *ServerConfig) {
+		cfg.MinPartSize = 8
+	}))
+	ctx := context.Background()
+	client, err := minio.New(server.Endpoint, &minio.Options{
+		Creds:           credentials.NewStaticV4(server.Access, server.Secret, ""),
+		TrailingHeaders: true,
+	})
+	require.NoError(t, err)
+	crc32c := func(data []byte) []byte {
+		h := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+		h.Write(data)
+		return h.Sum(nil)
+	}
+	encode := base64.StdEncoding.EncodeToString
+
+	// unsigned payloads carry their checksum in the trailer of the aws-chunked body
+	content := []byte("hello world")
+	info, err := client.PutObject(ctx, "test", "hello.txt", bytes.NewReader(content), int64(len(content)),
+		minio.PutObjectOptions{DisableContentSha256: true})
+	require.NoError(t, err)
+	require.Equal(t, encode(crc32c(content)), info.ChecksumCRC32C)
+
+	oi, err := client.StatObject(ctx, "test", "hello.txt", minio.StatObjectOptions{})
+	require.NoError(t, err)
+	require.Empty(t, oi.ChecksumCRC32C)
+	oi, err = client.StatObject(ctx, "test", "hello.txt", minio.StatObjectOptions{Checksum: true})
+	require.NoError(t, err)
+	require.Equal(t, encode(crc32c(content)), oi.ChecksumCRC32C)
+	obj, err := client.GetObject(ctx, "test", "hello.txt", minio.GetObjectOptions{Checksum: true})
+	require.NoError(t, err)
+	oi, err = obj.Stat()
+	require.NoError(t, err)
+	require.Equal(t, encode(crc32c(content)), oi.ChecksumCRC32C)
+	require.NoError(t, obj.Close())
+
+	// checksum headers are verified
+	sum := sha1.Sum(content)
+	_, err = server.Client.PutObject(ctx, "test", "sha1.txt", bytes.NewReader(content), int64(len(content)),
+		minio.PutObjectOptions{UserMetadata: map[string]string{"x-amz-checksum-sha1": encode(sum[:])}})
+	require.NoError(t, err)
+	ms, err := server.GetMS().GetObject("test", "sha1.txt")
+	require.NoError(t, err)
+	require.Equal(t, &Checksum{Algorithm: ChecksumSHA1, Value: encode(sum[:])}, ms.Checksum)
+	for value, code := range map[string]string{
+		encode(crc32c(content)):  "InvalidRequest",
+		encode(make([]byte, 20)): "BadDigest",
+	} {
+		_, err = server.Client.PutObject(ctx, "test", "sha1.txt", strings.NewReader("other"), 5,
+			minio.PutObjectOptions{UserMetadata: map[string]string{"x-amz-checksum-sha1": value}})
+		require.Equal(t, code, minio.ToErrorResponse(err).Code, value)
+	}
+	_, err = server.Client.PutObject(ctx, "test", "sha1.txt", bytes.NewReader(content), int64(len(content)),
+		minio.PutObjectOptions{UserMetadata: map[string]string{
+			"x-amz-checksum-sha1":  encode(sum[:]),
+			"x-amz-checksum-crc32": encode(make([]byte, 4)),
+		}})
+	require.Equal(t, "InvalidRequest", minio.ToErrorResponse(err).Code)
+
+	// the streaming multipart upload sends the checksum of every part and the composite checksum
+	large := bytes.Repeat([]byte("0123456789abcdef"), 6<<16)
+	info, err = client.PutObject(ctx, "test", "large.bin", bytes.NewReader(large), int64(len(large)),
+		minio.PutObjectOptions{PartSize: 5 << 20})
+	require.NoError(t, err)
+	composite := encode(crc32c(append(crc32c(large[:5<<20]), crc32c(large[5<<20:])...))) + "-2"
+	require.Equal(t, composite, info.ChecksumCRC32C)
+	oi, err = client.StatObject(ctx, "test", "large.bin", minio.StatObjectOptions{Checksum: true})
+	require.NoError(t, err)
+	require.Equal(t, composite, oi.ChecksumCRC32C)
+
+	// the parts of an upload with an algorithm get checksums computed by the server
+	core := minio.Core{Client: server.Client}
+	id, err := core.NewMultipartUpload(ctx, "test", "multi.txt", minio.PutObjectOptions{
+		UserMetadata: map[string]string{"X-Amz-Checksum-Algorithm": "sha1"},
+	})
+	require.NoError(t, err)
+	var parts []minio.CompletePart
+	var sums []byte
+	for i, data := range []string{"hello wo", "rld"} {
+		part, err := core.PutObjectPart(ctx, "test", "multi.txt", id, i+1, strings.NewReader(data),
+			int64(len(data)), minio.PutObjectPartOptions{})
+		require.NoError(t, err)
+		parts = append(parts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+		sum := sha1.Sum([]byte(data))
+		sums = append(sums, sum[:]...)
+	}
+	_, err = core.PutObjectPart(ctx, "test", "multi.txt", id, 3, strings.NewReader("!"), 1,
+		minio.PutObjectPartOptions{CustomHeader: map[string][]string{"x-amz-checksum-crc32": {encode(make([]byte, 4))}}})
+	require.Equal(t, "InvalidRequest", minio.ToErrorResponse(err).Code)
+
+	wrong := parts[0]
+	wrong.ChecksumSHA1 = encode(make([]byte, 20))
+	_, err = core.CompleteMultipartUpload(ctx, "test", "multi.txt", id, []minio.CompletePart{wrong, parts[1]},
+		minio.PutObjectOptions{})
+	require.Equal(t, "InvalidPart", minio.ToErrorResponse(err).Code)
+	compositeSum := sha1.Sum(sums)
+	info, err = core.CompleteMultipartUpload(ctx, "test", "multi.txt", id, parts, minio.PutObjectOptions{})
+	require.NoError(t, err)
+	require.Equal(t, encode(compositeSum[:])+"-2", info.ChecksumSHA1)
+
+	// copies keep the checksum of their source
+	_, err = server.Client.CopyObject(ctx, minio.CopyDestOptions{Bucket: "test", Object: "copy.txt"},
+		minio.CopySrcOptions{Bucket: "test", Object: "multi.txt"})
+	require.NoError(t, err)
+	oi, err = server.Client.StatObject(ctx, "test", "copy.txt", minio.StatObjectOptions{Checksum: true})
+	require.NoError(t, err)
+	require.Equal(t, encode(compositeSum[:])+"-2", oi.ChecksumSHA1)
+	obj, err = server.Client.GetObject(ctx, "test", "copy.txt", minio.GetObjectOptions{})
+	require.NoError(t, err)
+	data, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(data))
+}
@@ -0,0 +1,119 @@
+package gominio
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errMalformedChunk = errors.New("malformed aws-chunked body")
+
+// chunkedReader decodes an aws-chunked request body and its trailing headers. The chunk
+// signatures are not verified, the chunk extensions carrying them are skipped.
+type chunkedReader struct {
+	r       *bufio.Reader
+	remain  int64
+	done    bool
+	trailer http.Header
+}
+
+func newChunkedReader(r io.Reader) *chunkedReader {
+	return &chunkedReader{
+		r:       bufio.NewReader(r),
+		trailer: make(http.Header),
+	}
+}
+
+func (cr *chunkedReader) Read(p []byte) (int, error) {
+	if cr.done {
+		return 0, io.EOF
+	}
+
+	if cr.remain == 0 {
+		line, err := cr.readLine()
+		if err == io.EOF {
+			// the body ends with the last chunk of size 0
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(line), 16, 64)
+		if err != nil || size < 0 {
+			return 0, errMalformedChunk
+		}
+		if size == 0 {
+			cr.done = true
+			return 0, cr.readTrailer()
+		}
+		cr.remain = size
+	}
+
+	if int64(len(p)) > cr.remain {
+		p = p[:cr.remain]
+	}
+	n, err := cr.r.Read(p)
+	cr.remain -= int64(n)
+	if cr.remain == 0 && err == nil {
+		// every data chunk is terminated by a CRLF
+		var line string
+		if line, err = cr.readLine(); err == nil && line != "" {
+			err = errMalformedChunk
+		}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// readTrailer reads the trailing headers following the last chunk until the end of the body
+func (cr *chunkedReader) readTrailer() error {
+	for {
+		line, err := cr.readLine()
+		if err == io.EOF {
+			return io.EOF
+		}
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return errMalformedChunk
+		}
+		cr.trailer.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+}
+
+func (cr *chunkedReader) readLine() (string, error) {
+	line, err := cr.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readObjectBody reads the payload of an upload request and its trailing headers, decoding the
+// aws-chunked bodies of streaming uploads. The chunk signatures of signed streaming uploads are
+// not verified.
+func readObjectBody(r *http.Request) ([]byte, http.Header, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		data, err := io.ReadAll(r.Body)
+		return data, nil, err
+	}
+	cr := newChunkedReader(r.Body)
+	data, err := io.ReadAll(cr)
+	return data, cr.trailer, err
+}
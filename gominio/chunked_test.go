@@ -0,0 +1,67 @@
+package gominio
+
+import (
+	"bytes"
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestChunkedBody(t *testing.T) {
+	var trailer http.Header
+	read := func(sha256, body string) (string, error) {
+		req, err := http.NewRequest(http.MethodPut, "/test/object", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Amz-Content-Sha256", sha256)
+		var data []byte
+		data, trailer, err = readObjectBody(req)
+		return string(data), err
+	}
+
+	data, err := read("UNSIGNED-PAYLOAD", "5\r\nhello\r\n0\r\n\r\n")
+	require.NoError(t, err)
+	require.Equal(t, "5\r\nhello\r\n0\r\n\r\n", data, "bodies of unsigned payloads are not decoded")
+
+	// the chunk signatures are skipped, they are not verified
+	data, err = read("STREAMING-AWS4-HMAC-SHA256-PAYLOAD",
+		"5;chunk-signature=aaaa\r\nhello\r\n7;chunk-signature=bbbb\r\n\nworld\n\r\n0;chunk-signature=cccc\r\n\r\n")
+	require.NoError(t, err)
+	require.Equal(t, "hello\nworld\n", data)
+	require.Empty(t, trailer)
+
+	// the trailing headers follow the last chunk
+	data, err = read("STREAMING-UNSIGNED-PAYLOAD-TRAILER",
+		"5\r\nhello\r\n0\r\nx-amz-checksum-crc32:NhCmhg==\r\nx-amz-trailer-signature:dddd\r\n\r\n")
+	require.NoError(t, err)
+	require.Equal(t, "hello", data)
+	require.Equal(t, "NhCmhg==", trailer.Get("x-amz-checksum-crc32"))
+	require.Equal(t, "dddd", trailer.Get("x-amz-trailer-signature"))
+
+	for body, expected := range map[string]error{
+		"zz\r\nhello\r\n0\r\n\r\n":    errMalformedChunk,
+		"-1\r\nhello\r\n0\r\n\r\n":    errMalformedChunk,
+		"5\r\nhelloXX\r\n0\r\n\r\n":   errMalformedChunk,
+		"a\r\nhello":                  io.ErrUnexpectedEOF,
+		"5\r\nhello\r\n":              io.ErrUnexpectedEOF,
+		"5;sig\r\nhello\r\n0;sig\r\n": nil,
+		"0\r\nx-amz-checksum\r\n":     errMalformedChunk,
+	} {
+		_, err = read("STREAMING-AWS4-HMAC-SHA256-PAYLOAD", body)
+		require.ErrorIs(t, err, expected, body)
+	}
+
+	// streaming uploads are split in 64 KiB chunks
+	server := NewTestServer(t, WithBuckets("test"))
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("line\r\n"), 50000)
+	_, err = server.Client.PutObject(ctx, "test", "big.txt", bytes.NewReader(content), int64(len(content)),
+		minio.PutObjectOptions{})
+	require.NoError(t, err)
+	oi, err := server.GetMS().GetObject("test", "big.txt")
+	require.NoError(t, err)
+	require.Equal(t, content, oi.Data)
+}
@@ -0,0 +1,268 @@
+package gominio
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxCORSRules is the maximum number of rules of a CORS configuration
+const maxCORSRules = 100
+
+// corsMethods are the methods CORS rules may allow
+var corsMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPut:    true,
+	http.MethodHead:   true,
+	http.MethodPost:   true,
+	http.MethodDelete: true,
+}
+
+// CORSRule allows cross-origin requests from origins with methods and headers, origins and
+// headers may contain a single * wildcard
+type CORSRule struct {
+	ID            string   `xml:"ID,omitempty"`
+	AllowedOrigin []string `xml:"AllowedOrigin"`
+	AllowedMethod []string `xml:"AllowedMethod"`
+	AllowedHeader []string `xml:"AllowedHeader,omitempty"`
+	ExposeHeader  []string `xml:"ExposeHeader,omitempty"`
+	MaxAgeSeconds int      `xml:"MaxAgeSeconds,omitempty"`
+}
+
+// CORSConfiguration is the cross-origin resource sharing configuration of a bucket, requests
+// are allowed by the first matching rule
+type CORSConfiguration struct {
+	XMLName   xml.Name   `xml:"CORSConfiguration"`
+	CORSRules []CORSRule `xml:"CORSRule"`
+}
+
+// Validate checks the configuration follows the S3 CORS rules limits
+func (cfg *CORSConfiguration) Validate() error {
+	invalid := func(format string, args ...any) error {
+		apiErr := ErrInvalidRequest
+		apiErr.Description = fmt.Sprintf(format, args...)
+		return apiErr
+	}
+
+	if len(cfg.CORSRules) == 0 || len(cfg.CORSRules) > maxCORSRules {
+		return ErrMalformedXML
+	}
+	for _, rule := range cfg.CORSRules {
+		if len(rule.AllowedOrigin) == 0 || len(rule.AllowedMethod) == 0 || rule.MaxAgeSeconds < 0 {
+			return ErrMalformedXML
+		}
+		for _, method := range rule.AllowedMethod {
+			if !corsMethods[method] {
+				return invalid("Found unsupported HTTP method in CORS config. Unsupported method is %s", method)
+			}
+		}
+		for _, origin := range rule.AllowedOrigin {
+			if strings.Count(origin, "*") > 1 {
+				return invalid("AllowedOrigin %q can not have more than one wildcard.", origin)
+			}
+		}
+		for _, header := range rule.AllowedHeader {
+			if strings.Count(header, "*") > 1 {
+				return invalid("AllowedHeader %q can not have more than one wildcard.", header)
+			}
+		}
+	}
+	return nil
+}
+
+// matchOrigin returns the allowed origin of the rule matching origin
+func (rule *CORSRule) matchOrigin(origin string) (string, bool) {
+	for _, allowed := range rule.AllowedOrigin {
+		if matchWildcard(allowed, origin) {
+			return allowed, true
+		}
+	}
+	return "", false
+}
+
+// allowsHeaders reports whether every header is allowed by the rule, header names are case
+// insensitive
+func (rule *CORSRule) allowsHeaders(headers []string) bool {
+	for _, header := range headers {
+		if !matchAnyWildcard(rule.AllowedHeader, header, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns the first rule allowing a request from origin with method and headers
+func (cfg *CORSConfiguration) match(origin, method string, headers []string) (*CORSRule, string, bool) {
+	for i := range cfg.CORSRules {
+		rule := &cfg.CORSRules[i]
+		allowed, ok := rule.matchOrigin(origin)
+		if ok && containsName(rule.AllowedMethod, method) && rule.allowsHeaders(headers) {
+			return rule, allowed, true
+		}
+	}
+	return nil, "", false
+}
+
+// SetBucketCors sets the CORS configuration of bucket
+func (ms *MinioServer) SetBucketCors(bucket string, cfg *CORSConfiguration) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	ms.Lock()
+	defer ms.Unlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return ErrBucketNotExists
+	}
+
+	bd.Info.CORS = cfg
+	return nil
+}
+
+// GetBucketCors returns the CORS configuration of bucket, ErrNoSuchCORSConfiguration is returned
+// when the bucket has none
+func (ms *MinioServer) GetBucketCors(bucket string) (*CORSConfiguration, error) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return nil, ErrBucketNotExists
+	}
+	if bd.Info.CORS == nil {
+		return nil, ErrNoSuchCORSConfiguration
+	}
+	return bd.Info.CORS, nil
+}
+
+// RemoveBucketCors removes the CORS configuration of bucket
+func (ms *MinioServer) RemoveBucketCors(bucket string) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return ErrBucketNotExists
+	}
+
+	bd.Info.CORS = nil
+	return nil
+}
+
+func (api *ApiServer) getBucketCors(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	cfg, err := api.GetMS().GetBucketCors(bucket)
+	if err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+	SuccessResponse(ctx, http.StatusOK, encodeAny(cfg))
+}
+
+func (api *ApiServer) putBucketCors(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	var cfg = new(CORSConfiguration)
+	if err := decodeAny(ctx.Request.Body, cfg); err != nil {
+		ErrResponse(ctx, "", bucket, ErrMalformedXML)
+		return
+	}
+
+	if err := api.GetMS().SetBucketCors(bucket, cfg); err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, nil)
+}
+
+// splitHeaderList splits a comma separated header value into its trimmed lowercase elements
+func splitHeaderList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// setCORSHeaders sets the Access-Control headers of a response allowed by rule
+func setCORSHeaders(ctx *gin.Context, rule *CORSRule, allowedOrigin string) {
+	header := ctx.Writer.Header()
+	if allowedOrigin == "*" {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", ctx.GetHeader("Origin"))
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	header.Set("Access-Control-Allow-Methods", strings.Join(rule.AllowedMethod, ", "))
+	if len(rule.ExposeHeader) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(rule.ExposeHeader, ", "))
+	}
+}
+
+// Options answers the CORS preflight requests of a bucket or an object with the rule of the
+// bucket CORS configuration allowing the origin, the method and the headers of the request
+func (api *ApiServer) Options(ctx *gin.Context) {
+	bucket, object := ctx.Param("bucket"), ctx.Param("object")
+	origin := ctx.GetHeader("Origin")
+	method := ctx.GetHeader("Access-Control-Request-Method")
+	if origin == "" || method == "" {
+		apiErr := ErrInvalidRequest
+		apiErr.Description = "Insufficient information. Origin and Access-Control-Request-Method request headers needed."
+		ErrResponse(ctx, object, bucket, apiErr)
+		return
+	}
+
+	header := ctx.Writer.Header()
+	header.Add("Vary", "Origin, Access-Control-Request-Headers, Access-Control-Request-Method")
+	cfg, err := api.GetMS().GetBucketCors(bucket)
+	if err != nil {
+		apiErr := ToAPIError(err)
+		if errors.Is(err, ErrNoSuchCORSConfiguration) {
+			apiErr = ErrCORSForbidden
+		}
+		ErrResponse(ctx, object, bucket, apiErr)
+		return
+	}
+	headers := splitHeaderList(ctx.GetHeader("Access-Control-Request-Headers"))
+	rule, allowedOrigin, ok := cfg.match(origin, method, headers)
+	if !ok {
+		ErrResponse(ctx, object, bucket, ErrCORSForbidden)
+		return
+	}
+
+	setCORSHeaders(ctx, rule, allowedOrigin)
+	if len(headers) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if rule.MaxAgeSeconds > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(rule.MaxAgeSeconds))
+	}
+	SuccessResponse(ctx, http.StatusOK, nil)
+}
+
+// corsMiddleware sets the Access-Control headers of the cross-origin requests to a bucket
+// allowed by its CORS configuration, preflight requests are answered by Options
+func (api *ApiServer) corsMiddleware(ctx *gin.Context) {
+	origin := ctx.GetHeader("Origin")
+	bucket := ctx.Param("bucket")
+	if origin == "" || bucket == "" || ctx.Request.Method == http.MethodOptions {
+		return
+	}
+
+	cfg, err := api.GetMS().GetBucketCors(bucket)
+	if err != nil {
+		return
+	}
+	ctx.Writer.Header().Add("Vary", "Origin")
+	if rule, allowedOrigin, ok := cfg.match(origin, ctx.Request.Method, nil); ok {
+		setCORSHeaders(ctx, rule, allowedOrigin)
+	}
+}
@@ -0,0 +1,141 @@
+package gominio
+
+import (
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const corsConfig = `<CORSConfiguration>
+	<CORSRule>
+		<AllowedOrigin>https://*.example.com</AllowedOrigin>
+		<AllowedMethod>PUT</AllowedMethod>
+		<AllowedMethod>GET</AllowedMethod>
+		<AllowedHeader>Content-Type</AllowedHeader>
+		<AllowedHeader>x-amz-*</AllowedHeader>
+		<ExposeHeader>ETag</ExposeHeader>
+		<MaxAgeSeconds>3000</MaxAgeSeconds>
+	</CORSRule>
+	<CORSRule>
+		<AllowedOrigin>*</AllowedOrigin>
+		<AllowedMethod>GET</AllowedMethod>
+	</CORSRule>
+</CORSConfiguration>`
+
+func TestBucketCors(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	ctx := context.Background()
+	endpoint := "http://" + server.Endpoint
+	content := `hello world`
+	_, err := server.Client.PutObject(ctx, "test", "hello.txt", strings.NewReader(content),
+		int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+
+	do := func(method, path, body string) (*http.Response, string) {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		require.NoError(t, err)
+		rsp, err := server.Do(req)
+		require.NoError(t, err)
+		data, err := io.ReadAll(rsp.Body)
+		_ = rsp.Body.Close()
+		require.NoError(t, err)
+		return rsp, string(data)
+	}
+	preflight := func(path, origin, method, headers string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, endpoint+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		rsp, err := server.HTTPClient.Do(req)
+		require.NoError(t, err)
+		_ = rsp.Body.Close()
+		return rsp
+	}
+
+	rsp, data := do(http.MethodGet, "/test?cors", "")
+	require.Equal(t, http.StatusNotFound, rsp.StatusCode)
+	require.Contains(t, data, "NoSuchCORSConfiguration")
+	rsp = preflight("/test/hello.txt", "https://app.example.com", http.MethodGet, "")
+	require.Equal(t, http.StatusForbidden, rsp.StatusCode)
+
+	rsp, data = do(http.MethodPut, "/test?cors", strings.Replace(corsConfig, "PUT", "PATCH", 1))
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	require.Contains(t, data, "InvalidRequest")
+	rsp, data = do(http.MethodPut, "/test?cors", corsConfig)
+	require.Equal(t, http.StatusOK, rsp.StatusCode, data)
+	rsp, data = do(http.MethodGet, "/test?cors", "")
+	require.Equal(t, http.StatusOK, rsp.StatusCode, data)
+	cfg, err := server.GetMS().GetBucketCors("test")
+	require.NoError(t, err)
+	require.Len(t, cfg.CORSRules, 2)
+	require.Equal(t, string(encodeAny(cfg)), data)
+
+	// preflight requests are answered by the first rule allowing the origin, method and headers
+	rsp = preflight("/test/upload.txt", "https://app.example.com", http.MethodPut, "Content-Type, X-Amz-Date")
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, "https://app.example.com", rsp.Header.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "PUT, GET", rsp.Header.Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "content-type, x-amz-date", rsp.Header.Get("Access-Control-Allow-Headers"))
+	require.Equal(t, "ETag", rsp.Header.Get("Access-Control-Expose-Headers"))
+	require.Equal(t, "3000", rsp.Header.Get("Access-Control-Max-Age"))
+	require.Equal(t, "true", rsp.Header.Get("Access-Control-Allow-Credentials"))
+
+	rsp = preflight("/test/hello.txt", "https://other.org", http.MethodGet, "")
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, "*", rsp.Header.Get("Access-Control-Allow-Origin"))
+	require.Empty(t, rsp.Header.Get("Access-Control-Allow-Credentials"))
+
+	for _, rsp = range []*http.Response{
+		preflight("/test/upload.txt", "https://other.org", http.MethodPut, ""),
+		preflight("/test/upload.txt", "https://app.example.com", http.MethodPut, "X-Custom"),
+		preflight("/test/upload.txt", "https://app.example.com", http.MethodDelete, ""),
+		preflight("/missing/upload.txt", "https://app.example.com", http.MethodPut, ""),
+	} {
+		require.Contains(t, []int{http.StatusForbidden, http.StatusNotFound}, rsp.StatusCode)
+		require.Empty(t, rsp.Header.Get("Access-Control-Allow-Origin"))
+	}
+	req, err := http.NewRequest(http.MethodOptions, endpoint+"/test/hello.txt", nil)
+	require.NoError(t, err)
+	rsp, err = server.HTTPClient.Do(req)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+
+	// actual cross-origin responses carry the headers of the matching rule
+	req, err = http.NewRequest(http.MethodGet, "/test/hello.txt", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://app.example.com")
+	rsp, err = server.Do(req)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, "https://app.example.com", rsp.Header.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "ETag", rsp.Header.Get("Access-Control-Expose-Headers"))
+
+	req, err = http.NewRequest(http.MethodHead, "/test/hello.txt", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://app.example.com")
+	rsp, err = server.Do(req)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Empty(t, rsp.Header.Get("Access-Control-Allow-Origin"))
+
+	// preflight requests are not authenticated
+	require.NoError(t, server.GetMS().GetIAM().AddUser("reader", "reader-secret"))
+	rsp = preflight("/test/upload.txt", "https://app.example.com", http.MethodPut, "")
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.NoError(t, server.GetMS().GetIAM().RemoveUser("reader"))
+
+	rsp, data = do(http.MethodDelete, "/test?cors", "")
+	require.Equal(t, http.StatusNoContent, rsp.StatusCode, data)
+	rsp = preflight("/test/upload.txt", "https://app.example.com", http.MethodPut, "")
+	require.Equal(t, http.StatusForbidden, rsp.StatusCode)
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpOptionsObject, Status: http.StatusOK}, 3)
+}
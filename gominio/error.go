@@ -2,6 +2,8 @@ package gominio
 
 import (
 	"encoding/xml"
+	"errors"
+	"github.com/minio/minio-go/v7/pkg/tags"
 	"net/http"
 )
 
@@ -23,6 +25,63 @@ type APIErrorResponse struct {
 	HostID     string `xml:"HostId" json:"HostId"`
 }
 
+// Error returns the S3 error code and description, APIError values may be returned as errors
+func (ae APIError) Error() string {
+	return ae.Code + ": " + ae.Description
+}
+
+// Errors returned by the MinioServer methods, ToAPIError maps them to S3 errors
+var (
+	ErrBucketNotExists  = errors.New("bucket not exists")
+	ErrBucketHasObjects = errors.New("bucket not empty")
+	ErrObjectNotExists  = errors.New("object not exists")
+	ErrUploadNotExists  = errors.New("upload not exists")
+	ErrPartNotExists    = errors.New("object parts not exists")
+	ErrPartEtagMismatch = errors.New("object parts etag not same")
+)
+
+var sentinelErrors = map[error]APIError{
+	ErrBucketNotExists:  ErrNoSuchBucket,
+	ErrBucketHasObjects: ErrBucketNotEmpty,
+	ErrObjectNotExists:  ErrNoSuchKey,
+	ErrUploadNotExists:  ErrNoSuchUpload,
+	ErrPartNotExists:    ErrInvalidPart,
+	ErrPartEtagMismatch: ErrInvalidPart,
+	ErrUserNotExists:    ErrAdminNoSuchUser,
+	ErrPolicyNotExists:  ErrAdminNoSuchPolicy,
+}
+
+// ToAPIError returns the S3 error err stands for, errors other than APIError values and
+// the MinioServer errors are internal errors
+func ToAPIError(err error) APIError {
+	var apiErr APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	for sentinel, apiErr := range sentinelErrors {
+		if errors.Is(err, sentinel) {
+			return apiErr
+		}
+	}
+
+	apiErr = ErrInternalError
+	apiErr.Description = err.Error()
+	return apiErr
+}
+
+// tagsAPIError returns the S3 error of a tag set that failed to parse, tags breaking the
+// tagging limits are invalid tags while anything else is malformed XML
+func tagsAPIError(err error) APIError {
+	var tagErr tags.Error
+	if !errors.As(err, &tagErr) {
+		return ErrMalformedXML
+	}
+
+	apiErr := ErrInvalidTag
+	apiErr.Description = tagErr.Error()
+	return apiErr
+}
+
 func (ar APIErrorResponse) Encode() []byte {
 	return encodeAny(ar)
 }
@@ -53,4 +112,353 @@ var (
 		Description:    "The bucket you tried to delete is not empty",
 		HTTPStatusCode: http.StatusConflict,
 	}
+	ErrBucketAlreadyExists = APIError{
+		Code:           "BucketAlreadyExists",
+		Description:    "The requested bucket name is not available. The bucket namespace is shared by all users of the system. Please select a different name and try again.",
+		HTTPStatusCode: http.StatusConflict,
+	}
+	ErrInvalidBucketName = APIError{
+		Code:           "InvalidBucketName",
+		Description:    "The specified bucket is not valid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrKeyTooLongError = APIError{
+		Code:           "KeyTooLongError",
+		Description:    "Your key is too long",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidArgument = APIError{
+		Code:           "InvalidArgument",
+		Description:    "Invalid Argument",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrNoSuchUpload = APIError{
+		Code:           "NoSuchUpload",
+		Description:    "The specified multipart upload does not exist. The upload ID may be invalid, or the upload may have been aborted or completed.",
+		HTTPStatusCode: http.StatusNotFound,
+	}
+	ErrInvalidPart = APIError{
+		Code:           "InvalidPart",
+		Description:    "One or more of the specified parts could not be found.  The part may not have been uploaded, or the specified entity tag may not match the part's entity tag.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidPartOrder = APIError{
+		Code:           "InvalidPartOrder",
+		Description:    "The list of parts was not in ascending order. The parts list must be specified in order by part number.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrEntityTooSmall = APIError{
+		Code:           "EntityTooSmall",
+		Description:    "Your proposed upload is smaller than the minimum allowed object size.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrEntityTooLarge = APIError{
+		Code:           "EntityTooLarge",
+		Description:    "Your proposed upload exceeds the maximum allowed object size.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrIncompleteBody = APIError{
+		Code:           "IncompleteBody",
+		Description:    "You did not provide the number of bytes specified by the Content-Length HTTP header.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrMalformedXML = APIError{
+		Code:           "MalformedXML",
+		Description:    "The XML you provided was not well-formed or did not validate against our published schema.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrMalformedPolicy = APIError{
+		Code:           "MalformedPolicy",
+		Description:    "Policy has invalid resource.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidDigest = APIError{
+		Code:           "InvalidDigest",
+		Description:    "The Content-Md5 you specified is not valid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrBadDigest = APIError{
+		Code:           "BadDigest",
+		Description:    "The Content-Md5 you specified did not match what we received.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidRange = APIError{
+		Code:           "InvalidRange",
+		Description:    "The requested range is not satisfiable",
+		HTTPStatusCode: http.StatusRequestedRangeNotSatisfiable,
+	}
+	ErrInvalidTag = APIError{
+		Code:           "InvalidTag",
+		Description:    "The Tag value you have provided is invalid",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidStorageClass = APIError{
+		Code:           "InvalidStorageClass",
+		Description:    "Invalid storage class.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidObjectState = APIError{
+		Code:           "InvalidObjectState",
+		Description:    "The operation is not valid for the current state of the object.",
+		HTTPStatusCode: http.StatusForbidden,
+	}
+	ErrRestoreAlreadyInProgress = APIError{
+		Code:           "RestoreAlreadyInProgress",
+		Description:    "Object restore is already in progress.",
+		HTTPStatusCode: http.StatusConflict,
+	}
+	ErrNoSuchBucketPolicy = APIError{
+		Code:           "NoSuchBucketPolicy",
+		Description:    "The bucket policy does not exist",
+		HTTPStatusCode: http.StatusNotFound,
+	}
+	ErrNoSuchTagSet = APIError{
+		Code:           "NoSuchTagSet",
+		Description:    "The TagSet does not exist",
+		HTTPStatusCode: http.StatusNotFound,
+	}
+	ErrMissingContentLength = APIError{
+		Code:           "MissingContentLength",
+		Description:    "You must provide the Content-Length HTTP header.",
+		HTTPStatusCode: http.StatusLengthRequired,
+	}
+	ErrPreconditionFailed = APIError{
+		Code:           "PreconditionFailed",
+		Description:    "At least one of the pre-conditions you specified did not hold",
+		HTTPStatusCode: http.StatusPreconditionFailed,
+	}
+	ErrMethodNotAllowed = APIError{
+		Code:           "MethodNotAllowed",
+		Description:    "The specified method is not allowed against this resource.",
+		HTTPStatusCode: http.StatusMethodNotAllowed,
+	}
+	ErrNotImplemented = APIError{
+		Code:           "NotImplemented",
+		Description:    "A header you provided implies functionality that is not implemented",
+		HTTPStatusCode: http.StatusNotImplemented,
+	}
+	ErrAccessDenied = APIError{
+		Code:           "AccessDenied",
+		Description:    "Access Denied.",
+		HTTPStatusCode: http.StatusForbidden,
+	}
+	ErrInvalidAccessKeyId = APIError{
+		Code:           "InvalidAccessKeyId",
+		Description:    "The Access Key Id you provided does not exist in our records.",
+		HTTPStatusCode: http.StatusForbidden,
+	}
+	ErrSignatureDoesNotMatch = APIError{
+		Code:           "SignatureDoesNotMatch",
+		Description:    "The request signature we calculated does not match the signature you provided. Check your key and signing method.",
+		HTTPStatusCode: http.StatusForbidden,
+	}
+	ErrRequestTimeTooSkewed = APIError{
+		Code:           "RequestTimeTooSkewed",
+		Description:    "The difference between the request time and the server's time is too large.",
+		HTTPStatusCode: http.StatusForbidden,
+	}
+	ErrAuthorizationHeaderMalformed = APIError{
+		Code:           "AuthorizationHeaderMalformed",
+		Description:    "The authorization header is malformed; the region is wrong.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidToken = APIError{
+		Code:           "InvalidToken",
+		Description:    "The provided token is malformed or otherwise invalid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrExpiredToken = APIError{
+		Code:           "ExpiredToken",
+		Description:    "The provided token has expired.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidParameterValue = APIError{
+		Code:           "InvalidParameterValue",
+		Description:    "An invalid or out-of-range value was supplied for the input parameter.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidIdentityToken = APIError{
+		Code:           "InvalidIdentityToken",
+		Description:    "The web identity token that was passed could not be validated.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrMalformedPolicyDocument = APIError{
+		Code:           "MalformedPolicyDocument",
+		Description:    "The request was rejected because the policy document was malformed.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrNoSuchCORSConfiguration = APIError{
+		Code:           "NoSuchCORSConfiguration",
+		Description:    "The CORS configuration does not exist",
+		HTTPStatusCode: http.StatusNotFound,
+	}
+	ErrReplicationConfigurationNotFound = APIError{
+		Code:           "ReplicationConfigurationNotFoundError",
+		Description:    "The replication configuration was not found",
+		HTTPStatusCode: http.StatusNotFound,
+	}
+	ErrNoSuchWebsiteConfiguration = APIError{
+		Code:           "NoSuchWebsiteConfiguration",
+		Description:    "The specified bucket does not have a website configuration",
+		HTTPStatusCode: http.StatusNotFound,
+	}
+	ErrCORSForbidden = APIError{
+		Code: "AccessForbidden",
+		Description: "CORSResponse: This CORS request is not allowed. This is usually because the evalution of Origin, " +
+			"request method / Access-Control-Request-Method or Access-Control-Request-Headers are not whitelisted by the resource's CORS spec.",
+		HTTPStatusCode: http.StatusForbidden,
+	}
+	ErrBucketQuotaExceeded = APIError{
+		Code:           "XMinioAdminBucketQuotaExceeded",
+		Description:    "Bucket quota exceeded",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrAdminNoSuchUser = APIError{
+		Code:           "XMinioAdminNoSuchUser",
+		Description:    "The specified user does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	}
+	ErrAdminNoSuchPolicy = APIError{
+		Code:           "XMinioAdminNoSuchPolicy",
+		Description:    "The canned policy does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	}
+	ErrAdminNoSuchQuotaConfiguration = APIError{
+		Code:           "XMinioAdminNoSuchQuotaConfiguration",
+		Description:    "The quota configuration does not exist",
+		HTTPStatusCode: http.StatusNotFound,
+	}
+	ErrAdminConfigBadJSON = APIError{
+		Code:           "XMinioAdminConfigBadJSON",
+		Description:    "JSON configuration provided is of incorrect format",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidExpressionType = APIError{
+		Code:           "InvalidExpressionType",
+		Description:    "The ExpressionType is invalid. Only SQL expressions are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrParseUnexpectedToken = APIError{
+		Code:           "ParseUnexpectedToken",
+		Description:    "The SQL expression contains an unexpected token.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrUnsupportedSQLOperation = APIError{
+		Code:           "UnsupportedSqlOperation",
+		Description:    "Encountered an unsupported SQL operation.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidDataType = APIError{
+		Code:           "InvalidDataType",
+		Description:    "The SQL expression contains an invalid data type.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrCastFailed = APIError{
+		Code:           "CastFailed",
+		Description:    "Attempt to convert from one data type to another using CAST failed in the SQL expression.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrCSVParsingError = APIError{
+		Code:           "CSVParsingError",
+		Description:    "Encountered an error parsing the CSV file.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrJSONParsingError = APIError{
+		Code:           "JSONParsingError",
+		Description:    "Encountered an error parsing the JSON file.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidCompressionFormat = APIError{
+		Code:           "InvalidCompressionFormat",
+		Description:    "The file is not in a supported compression format. Only GZIP and BZIP2 are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	ErrInternalError = APIError{
+		Code:           "InternalError",
+		Description:    "We encountered an internal error, please try again.",
+		HTTPStatusCode: http.StatusInternalServerError,
+	}
+	ErrSlowDown = APIError{
+		Code:           "SlowDown",
+		Description:    "Please reduce your request rate.",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	}
+	ErrServiceUnavailable = APIError{
+		Code:           "ServiceUnavailable",
+		Description:    "The server is currently unable to handle the request.",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	}
 )
+
+var apiErrors = []APIError{
+	ErrInvalidRequest,
+	ErrNoSuchBucket,
+	ErrNoSuchKey,
+	ErrBucketAlreadyOwnedByYou,
+	ErrBucketNotEmpty,
+	ErrBucketAlreadyExists,
+	ErrInvalidBucketName,
+	ErrKeyTooLongError,
+	ErrInvalidArgument,
+	ErrNoSuchUpload,
+	ErrInvalidPart,
+	ErrInvalidPartOrder,
+	ErrEntityTooSmall,
+	ErrEntityTooLarge,
+	ErrIncompleteBody,
+	ErrMalformedXML,
+	ErrMalformedPolicy,
+	ErrInvalidDigest,
+	ErrBadDigest,
+	ErrInvalidRange,
+	ErrInvalidTag,
+	ErrInvalidStorageClass,
+	ErrInvalidObjectState,
+	ErrRestoreAlreadyInProgress,
+	ErrNoSuchBucketPolicy,
+	ErrNoSuchTagSet,
+	ErrMissingContentLength,
+	ErrPreconditionFailed,
+	ErrMethodNotAllowed,
+	ErrNotImplemented,
+	ErrAccessDenied,
+	ErrInvalidAccessKeyId,
+	ErrSignatureDoesNotMatch,
+	ErrRequestTimeTooSkewed,
+	ErrAuthorizationHeaderMalformed,
+	ErrInvalidToken,
+	ErrExpiredToken,
+	ErrInvalidParameterValue,
+	ErrInvalidIdentityToken,
+	ErrMalformedPolicyDocument,
+	ErrNoSuchCORSConfiguration,
+	ErrReplicationConfigurationNotFound,
+	ErrNoSuchWebsiteConfiguration,
+	ErrCORSForbidden,
+	ErrBucketQuotaExceeded,
+	ErrAdminNoSuchUser,
+	ErrAdminNoSuchPolicy,
+	ErrAdminNoSuchQuotaConfiguration,
+	ErrAdminConfigBadJSON,
+	ErrInvalidExpressionType,
+	ErrParseUnexpectedToken,
+	ErrUnsupportedSQLOperation,
+	ErrInvalidDataType,
+	ErrCastFailed,
+	ErrCSVParsingError,
+	ErrJSONParsingError,
+	ErrInvalidCompressionFormat,
+	ErrInternalError,
+	ErrSlowDown,
+	ErrServiceUnavailable,
+}
+
+// LookupAPIError returns the APIError with the given S3 error code
+func LookupAPIError(code string) (APIError, bool) {
+	for _, apiErr := range apiErrors {
+		if apiErr.Code == code {
+			return apiErr, true
+		}
+	}
+	return APIError{}, false
+}
@@ -0,0 +1,80 @@
+package gominio
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestToAPIError(t *testing.T) {
+	require.Equal(t, ErrNoSuchBucket, ToAPIError(ErrBucketNotExists))
+	require.Equal(t, ErrNoSuchKey, ToAPIError(fmt.Errorf("get: %w", ErrObjectNotExists)))
+	require.Equal(t, ErrNoSuchUpload, ToAPIError(ErrUploadNotExists))
+	require.Equal(t, ErrEntityTooSmall, ToAPIError(ErrEntityTooSmall))
+	require.Equal(t, ErrInternalError.Code, ToAPIError(io.ErrUnexpectedEOF).Code)
+
+	for _, apiErr := range apiErrors {
+		found, ok := LookupAPIError(apiErr.Code)
+		require.True(t, ok)
+		require.Equal(t, apiErr, found)
+		require.NotZero(t, apiErr.HTTPStatusCode)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+
+	// missing keys are 404 on HEAD and silently deleted
+	_, err := server.Client.StatObject(context.Background(), "test", "missing.txt", minio.StatObjectOptions{})
+	errRsp := minio.ToErrorResponse(err)
+	require.Equal(t, http.StatusNotFound, errRsp.StatusCode)
+	require.Equal(t, ErrNoSuchKey.Code, errRsp.Code)
+	require.NotEmpty(t, errRsp.RequestID)
+	require.NotEmpty(t, errRsp.HostID)
+
+	err = server.Client.RemoveObject(context.Background(), "test", "missing.txt", minio.RemoveObjectOptions{})
+	require.NoError(t, err)
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpDeleteObject, Status: http.StatusNoContent}, 1)
+
+	content := `hello world`
+	_, err = server.Client.PutObject(context.Background(), "test", "hello.txt",
+		strings.NewReader(content), int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+	err = server.Client.RemoveBucket(context.Background(), "test")
+	require.Equal(t, ErrBucketNotEmpty.Code, minio.ToErrorResponse(err).Code)
+
+	// the error body names the resource and the request
+	req, err := http.NewRequest(http.MethodGet, "/test/missing.txt", nil)
+	require.NoError(t, err)
+	rsp, err := server.Do(req)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	var apiRsp APIErrorResponse
+	require.NoError(t, xml.NewDecoder(rsp.Body).Decode(&apiRsp))
+	require.Equal(t, ErrNoSuchKey.Code, apiRsp.Code)
+	require.Equal(t, "/test/missing.txt", apiRsp.Resource)
+	require.Equal(t, rsp.Header.Get("x-amz-request-id"), apiRsp.RequestID)
+	require.Equal(t, rsp.Header.Get("x-amz-id-2"), apiRsp.HostID)
+
+	req, err = http.NewRequest(http.MethodPatch, "/test/hello.txt", nil)
+	require.NoError(t, err)
+	rsp, err = server.Do(req)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, rsp.StatusCode)
+
+	// every request has its own id
+	entries := server.GetJournal().Entries()
+	ids := make(map[string]bool)
+	for _, e := range entries {
+		require.NotEmpty(t, e.RequestID)
+		ids[e.RequestID] = true
+	}
+	require.Len(t, ids, len(entries))
+}
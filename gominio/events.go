@@ -0,0 +1,554 @@
+package gominio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event names of the tagging changes, the other events use the notification.EventType values
+const (
+	EventObjectTaggingAll    notification.EventType = "s3:ObjectTagging:*"
+	EventObjectTaggingPut    notification.EventType = "s3:ObjectTagging:Put"
+	EventObjectTaggingDelete notification.EventType = "s3:ObjectTagging:Delete"
+)
+
+// supportedEvents are the event names a notification configuration may subscribe to
+var supportedEvents = map[notification.EventType]bool{
+	notification.ObjectCreatedAll:                     true,
+	notification.ObjectCreatedPut:                     true,
+	notification.ObjectCreatedCopy:                    true,
+	notification.ObjectCreatedCompleteMultipartUpload: true,
+	notification.ObjectRemovedAll:                     true,
+	notification.ObjectRemovedDelete:                  true,
+	EventObjectTaggingAll:                             true,
+	EventObjectTaggingPut:                             true,
+	EventObjectTaggingDelete:                          true,
+}
+
+const eventTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// EventFilter selects the events delivered to a subscriber, empty fields match everything
+type EventFilter struct {
+	Bucket string
+	Events []notification.EventType
+	Prefix string
+	Suffix string
+}
+
+// Match reports whether the event passes the filter
+func (f EventFilter) Match(ev notification.Event) bool {
+	if f.Bucket != "" && f.Bucket != ev.S3.Bucket.Name {
+		return false
+	}
+	if len(f.Events) > 0 && !matchEventNames(f.Events, ev.EventName) {
+		return false
+	}
+	key := eventKey(ev)
+	return strings.HasPrefix(key, f.Prefix) && strings.HasSuffix(key, f.Suffix)
+}
+
+// matchEventNames reports whether name matches one of the patterns, "s3:ObjectCreated:*" like
+// patterns match every event of their kind
+func matchEventNames(patterns []notification.EventType, name string) bool {
+	for _, pattern := range patterns {
+		p := string(pattern)
+		if p == name || (strings.HasSuffix(p, "*") && strings.HasPrefix(name, strings.TrimSuffix(p, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// eventKey returns the object key of the event, event records carry it url encoded
+func eventKey(ev notification.Event) string {
+	key, err := url.QueryUnescape(ev.S3.Object.Key)
+	if err != nil {
+		return ev.S3.Object.Key
+	}
+	return key
+}
+
+// eventQueue is an unbounded FIFO of events, publishing never blocks on slow consumers
+type eventQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	events []notification.Event
+	closed bool
+}
+
+func newEventQueue() *eventQueue {
+	q := &eventQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *eventQueue) push(ev notification.Event) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.events = append(q.events, ev)
+	q.cond.Signal()
+}
+
+// pop waits for the next event, it returns false once the queue is closed
+func (q *eventQueue) pop() (notification.Event, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.events) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return notification.Event{}, false
+	}
+	ev := q.events[0]
+	q.events = q.events[1:]
+	return ev, true
+}
+
+func (q *eventQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+type eventSubscriber struct {
+	filter EventFilter
+	queue  *eventQueue
+}
+
+// webhookTarget posts the events routed to it to an HTTP endpoint
+type webhookTarget struct {
+	endpoint string
+	queue    *eventQueue
+}
+
+// WebhookPayload is the body posted to webhook targets, one event per request like MinIO does
+type WebhookPayload struct {
+	EventName string
+	Key       string
+	Records   []notification.Event
+}
+
+// EventBus delivers the events of the MinioServer to in-process subscribers and to the
+// webhook targets of the bucket notification configurations
+type EventBus struct {
+	sync.Mutex
+	ms          *MinioServer
+	closed      bool
+	client      *http.Client
+	nextID      uint64
+	subscribers map[uint64]*eventSubscriber
+	targets     map[string]*webhookTarget
+}
+
+func newEventBus(ms *MinioServer) *EventBus {
+	return &EventBus{
+		ms:          ms,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		subscribers: make(map[uint64]*eventSubscriber),
+		targets:     make(map[string]*webhookTarget),
+	}
+}
+
+// Subscribe returns a channel receiving the events matching filter in order, the cancel function
+// stops the delivery and closes the channel
+func (eb *EventBus) Subscribe(filter EventFilter) (<-chan notification.Event, func()) {
+	eb.Lock()
+	defer eb.Unlock()
+
+	eb.nextID++
+	id := eb.nextID
+	sub := &eventSubscriber{filter: filter, queue: newEventQueue()}
+	if eb.closed {
+		sub.queue.close()
+	} else {
+		eb.subscribers[id] = sub
+	}
+
+	ch := make(chan notification.Event)
+	done := make(chan struct{})
+	go func() {
+		defer close(ch)
+		for {
+			ev, ok := sub.queue.pop()
+			if !ok {
+				return
+			}
+			select {
+			case ch <- ev:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			eb.Lock()
+			delete(eb.subscribers, id)
+			eb.Unlock()
+			sub.queue.close()
+			close(done)
+		})
+	}
+}
+
+// SubscriberCount returns the number of active subscriptions, tests use it to wait for
+// listeners to be connected before they publish events
+func (eb *EventBus) SubscriberCount() int {
+	eb.Lock()
+	defer eb.Unlock()
+
+	return len(eb.subscribers)
+}
+
+// AddWebhook registers a webhook target and returns the ARN notification configurations
+// refer to it with
+func (eb *EventBus) AddWebhook(id, endpoint string) string {
+	eb.Lock()
+	defer eb.Unlock()
+
+	arn := notification.NewArn("minio", "sqs", eb.ms.GetRegion(), id, "webhook").String()
+	if target, ok := eb.targets[arn]; ok {
+		target.queue.close()
+	}
+	target := &webhookTarget{endpoint: endpoint, queue: newEventQueue()}
+	eb.targets[arn] = target
+	go eb.deliver(target)
+	return arn
+}
+
+// RemoveWebhook unregisters the webhook target with the given ARN
+func (eb *EventBus) RemoveWebhook(arn string) {
+	eb.Lock()
+	defer eb.Unlock()
+
+	if target, ok := eb.targets[arn]; ok {
+		target.queue.close()
+		delete(eb.targets, arn)
+	}
+}
+
+// HasTarget reports whether a webhook target is registered with the given ARN
+func (eb *EventBus) HasTarget(arn string) bool {
+	eb.Lock()
+	defer eb.Unlock()
+
+	_, ok := eb.targets[arn]
+	return ok
+}
+
+// Close stops the delivery to every subscriber and webhook target, later subscriptions are
+// closed right away
+func (eb *EventBus) Close() {
+	eb.Lock()
+	defer eb.Unlock()
+
+	eb.closed = true
+	for id, sub := range eb.subscribers {
+		sub.queue.close()
+		delete(eb.subscribers, id)
+	}
+	for arn, target := range eb.targets {
+		target.queue.close()
+		delete(eb.targets, arn)
+	}
+}
+
+func (eb *EventBus) deliver(target *webhookTarget) {
+	for {
+		ev, ok := target.queue.pop()
+		if !ok {
+			return
+		}
+
+		body, err := json.Marshal(WebhookPayload{
+			EventName: ev.EventName,
+			Key:       ev.S3.Bucket.Name + "/" + eventKey(ev),
+			Records:   []notification.Event{ev},
+		})
+		if err != nil {
+			log.Println("encode event err", err)
+			continue
+		}
+		rsp, err := eb.client.Post(target.endpoint, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Println("deliver event err", err)
+			continue
+		}
+		_ = rsp.Body.Close()
+	}
+}
+
+// publish queues the event for the subscribers and the webhook targets of arns
+func (eb *EventBus) publish(ev notification.Event, arns []string) {
+	eb.Lock()
+	defer eb.Unlock()
+
+	for _, sub := range eb.subscribers {
+		if sub.filter.Match(ev) {
+			sub.queue.push(ev)
+		}
+	}
+	for _, arn := range arns {
+		if target, ok := eb.targets[arn]; ok {
+			target.queue.push(ev)
+		}
+	}
+}
+
+// notificationTargets returns the ARNs of the configurations of cfg matching the event
+func notificationTargets(cfg *notification.Configuration, ev notification.Event) []string {
+	if cfg == nil {
+		return nil
+	}
+
+	var arns []string
+	match := func(c notification.Config, arn string) {
+		filter := EventFilter{Events: c.Events}
+		if c.Filter != nil {
+			for _, rule := range c.Filter.S3Key.FilterRules {
+				switch strings.ToLower(rule.Name) {
+				case "prefix":
+					filter.Prefix = rule.Value
+				case "suffix":
+					filter.Suffix = rule.Value
+				}
+			}
+		}
+		if filter.Match(ev) {
+			arns = append(arns, arn)
+		}
+	}
+	for _, c := range cfg.QueueConfigs {
+		match(c.Config, c.Queue)
+	}
+	for _, c := range cfg.TopicConfigs {
+		match(c.Config, c.Topic)
+	}
+	for _, c := range cfg.LambdaConfigs {
+		match(c.Config, c.Lambda)
+	}
+	return arns
+}
+
+// objectEvents are the events of the operations changing objects
+var objectEvents = map[string]notification.EventType{
+	OpPutObject:               notification.ObjectCreatedPut,
+	OpCopyObject:              notification.ObjectCreatedCopy,
+	OpCompleteMultipartUpload: notification.ObjectCreatedCompleteMultipartUpload,
+	OpDeleteObject:            notification.ObjectRemovedDelete,
+	OpPutObjectTagging:        EventObjectTaggingPut,
+	OpDeleteObjectTagging:     EventObjectTaggingDelete,
+}
+
+// notifyHook is the after hook of every operation publishing the events of the changes of objects
+func (ms *MinioServer) notifyHook(info HookInfo) {
+	name, ok := objectEvents[info.Operation]
+	if !ok || info.changed == nil {
+		return
+	}
+
+	ms.Lock()
+	defer ms.Unlock()
+	ms.notify(name, info.Bucket, info.changed)
+}
+
+// notify publishes the event of a change of object, it must be called with the lock held
+func (ms *MinioServer) notify(name notification.EventType, bucket string, oi *ObjectInfo) {
+	if ms.events == nil {
+		return
+	}
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	ev := notification.Event{
+		EventVersion:      "2.0",
+		EventSource:       "minio:s3",
+		AwsRegion:         ms.GetRegion(),
+		EventTime:         now.Format(eventTimeFormat),
+		EventName:         string(name),
+		RequestParameters: map[string]string{"region": ms.GetRegion()},
+		ResponseElements:  map[string]string{},
+	}
+	ev.UserIdentity.PrincipalID = ms.Access
+	ev.S3.SchemaVersion = "1.0"
+	ev.S3.ConfigurationID = "Config"
+	ev.S3.Bucket.Name = bucket
+	ev.S3.Bucket.OwnerIdentity.PrincipalID = ms.Access
+	ev.S3.Bucket.ARN = "arn:aws:s3:::" + bucket
+	ev.S3.Object.Key = url.QueryEscape(oi.Name)
+	ev.S3.Object.Sequencer = fmt.Sprintf("%X", now.UnixNano())
+	if !strings.HasPrefix(string(name), "s3:ObjectRemoved:") {
+		ev.S3.Object.Size = int64(oi.Size)
+		ev.S3.Object.ETag = oi.Etag
+	}
+
+	ms.events.publish(ev, notificationTargets(bd.Info.Notification, ev))
+	// the changes notified are the changes replicated
+	ms.replicator.queue(name, bucket, bd.Info.Replication, oi)
+}
+
+// SetBucketNotification set the bucket notification configuration, every destination must be
+// a webhook target registered on the event bus
+func (ms *MinioServer) SetBucketNotification(bucket string, cfg *notification.Configuration) error {
+	var arns []string
+	var configs []notification.Config
+	for _, c := range cfg.QueueConfigs {
+		arns, configs = append(arns, c.Queue), append(configs, c.Config)
+	}
+	for _, c := range cfg.TopicConfigs {
+		arns, configs = append(arns, c.Topic), append(configs, c.Config)
+	}
+	for _, c := range cfg.LambdaConfigs {
+		arns, configs = append(arns, c.Lambda), append(configs, c.Config)
+	}
+	for i, c := range configs {
+		if ms.events == nil || !ms.events.HasTarget(arns[i]) {
+			apiErr := ErrInvalidArgument
+			apiErr.Description = "A specified destination ARN does not exist or is not well-formed. Verify the destination ARN."
+			return apiErr
+		}
+		for _, name := range c.Events {
+			if !supportedEvents[name] {
+				apiErr := ErrInvalidArgument
+				apiErr.Description = "A specified event is not supported for notifications."
+				return apiErr
+			}
+		}
+		if c.Filter == nil {
+			continue
+		}
+		for _, rule := range c.Filter.S3Key.FilterRules {
+			if name := strings.ToLower(rule.Name); name != "prefix" && name != "suffix" {
+				apiErr := ErrInvalidArgument
+				apiErr.Description = "filter rule name must be either prefix or suffix"
+				return apiErr
+			}
+		}
+	}
+
+	ms.Lock()
+	defer ms.Unlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return ErrBucketNotExists
+	}
+	bd.Info.Notification = cfg
+	return nil
+}
+
+// GetBucketNotification get the bucket notification configuration, it is empty when none is set
+func (ms *MinioServer) GetBucketNotification(bucket string) (*notification.Configuration, error) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return nil, ErrBucketNotExists
+	}
+	if bd.Info.Notification == nil {
+		return &notification.Configuration{}, nil
+	}
+	return bd.Info.Notification, nil
+}
+
+// GetEvents returns the event bus of the server
+func (ms *MinioServer) GetEvents() *EventBus {
+	return ms.events
+}
+
+// listenRecord is a line of the ListenBucketNotification stream
+type listenRecord struct {
+	Records []notification.Event
+}
+
+// defaultListenPing is the interval of the keep-alive whitespace when the client gives none
+const defaultListenPing = 10 * time.Second
+
+// ListenBucketNotification streams the events of the bucket as lines of JSON, the events of
+// every bucket are streamed when the request has no bucket
+func (api *ApiServer) ListenBucketNotification(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	query := ctx.Request.URL.Query()
+	filter := EventFilter{
+		Bucket: bucket,
+		Prefix: query.Get("prefix"),
+		Suffix: query.Get("suffix"),
+	}
+	for _, name := range query["events"] {
+		if !supportedEvents[notification.EventType(name)] {
+			apiErr := ErrInvalidArgument
+			apiErr.Description = "A specified event is not supported for notifications."
+			ErrResponse(ctx, "", bucket, apiErr)
+			return
+		}
+		filter.Events = append(filter.Events, notification.EventType(name))
+	}
+
+	ping := defaultListenPing
+	if value := query.Get("ping"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			apiErr := ErrInvalidArgument
+			apiErr.Description = "ping must be a positive number of seconds"
+			ErrResponse(ctx, "", bucket, apiErr)
+			return
+		}
+		ping = time.Duration(seconds) * time.Second
+	}
+
+	if bucket != "" && !api.GetMS().BucketExists(bucket) {
+		ErrResponse(ctx, "", bucket, ErrNoSuchBucket)
+		return
+	}
+
+	events, cancel := api.GetMS().GetEvents().Subscribe(filter)
+	defer cancel()
+
+	ctx.Writer.Header().Set("Content-Type", "application/json")
+	ctx.Writer.WriteHeader(http.StatusOK)
+	ctx.Writer.Flush()
+
+	keepAlive := time.NewTicker(ping)
+	defer keepAlive.Stop()
+
+	enc := json.NewEncoder(ctx.Writer)
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := enc.Encode(listenRecord{Records: []notification.Event{ev}}); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			// whitespace keeps the connection alive without ending the current line
+			if _, err := ctx.Writer.Write([]byte(" ")); err != nil {
+				return
+			}
+		case <-ctx.Request.Context().Done():
+			return
+		}
+		ctx.Writer.Flush()
+	}
+}
@@ -0,0 +1,214 @@
+package gominio
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/minio/minio-go/v7/pkg/tags"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, events <-chan notification.Event) notification.Event {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+	return notification.Event{}
+}
+
+func TestEventSubscribe(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test", "other"))
+	ctx := context.Background()
+
+	events, cancel := server.GetMS().GetEvents().Subscribe(EventFilter{Bucket: "test", Prefix: "dir/"})
+	defer cancel()
+	created, cancelCreated := server.GetMS().GetEvents().Subscribe(EventFilter{
+		Events: []notification.EventType{notification.ObjectCreatedAll},
+		Suffix: ".txt",
+	})
+	defer cancelCreated()
+
+	content := `hello world`
+	put := func(bucket, object string) {
+		_, err := server.Client.PutObject(ctx, bucket, object, strings.NewReader(content),
+			int64(len(content)), minio.PutObjectOptions{})
+		server.NoError(err)
+	}
+	put("other", "dir/hello.txt")
+	put("test", "hello.txt")
+	put("test", "dir/hello.txt")
+
+	ev := nextEvent(t, events)
+	require.Equal(t, string(notification.ObjectCreatedPut), ev.EventName)
+	require.Equal(t, "test", ev.S3.Bucket.Name)
+	require.Equal(t, "dir%2Fhello.txt", ev.S3.Object.Key)
+	require.Equal(t, int64(len(content)), ev.S3.Object.Size)
+
+	_, err := server.Client.CopyObject(ctx, minio.CopyDestOptions{Bucket: "test", Object: "dir/copy.bin"},
+		minio.CopySrcOptions{Bucket: "test", Object: "dir/hello.txt"})
+	server.NoError(err)
+	require.Equal(t, string(notification.ObjectCreatedCopy), nextEvent(t, events).EventName)
+
+	tag, err := tags.MapToObjectTags(map[string]string{"key": "value"})
+	require.NoError(t, err)
+	server.NoError(server.Client.PutObjectTagging(ctx, "test", "dir/copy.bin", tag, minio.PutObjectTaggingOptions{}))
+	require.Equal(t, string(EventObjectTaggingPut), nextEvent(t, events).EventName)
+	server.NoError(server.Client.RemoveObjectTagging(ctx, "test", "dir/copy.bin", minio.RemoveObjectTaggingOptions{}))
+	require.Equal(t, string(EventObjectTaggingDelete), nextEvent(t, events).EventName)
+
+	core := minio.Core{Client: server.Client}
+	id, err := core.NewMultipartUpload(ctx, "test", "dir/multi.txt", minio.PutObjectOptions{})
+	server.NoError(err)
+	part, err := core.PutObjectPart(ctx, "test", "dir/multi.txt", id, 1,
+		strings.NewReader(content), int64(len(content)), minio.PutObjectPartOptions{})
+	server.NoError(err)
+	_, err = core.CompleteMultipartUpload(ctx, "test", "dir/multi.txt", id,
+		[]minio.CompletePart{{PartNumber: 1, ETag: part.ETag}}, minio.PutObjectOptions{})
+	server.NoError(err)
+	require.Equal(t, string(notification.ObjectCreatedCompleteMultipartUpload), nextEvent(t, events).EventName)
+
+	server.NoError(server.Client.RemoveObject(ctx, "test", "dir/multi.txt", minio.RemoveObjectOptions{}))
+	ev = nextEvent(t, events)
+	require.Equal(t, string(notification.ObjectRemovedDelete), ev.EventName)
+	require.Equal(t, "dir%2Fmulti.txt", ev.S3.Object.Key)
+
+	// events are published by an after hook of the server, vetoed changes publish none and
+	// resetting the hooks keeps publishing
+	hooks := server.GetMS().GetHooks()
+	hooks.Before(OpPutObject, func(HookInfo) error { return ErrAccessDenied })
+	require.ErrorIs(t, server.GetMS().PutObject("test", "dir/vetoed.bin", GetUid(), []byte(content)), ErrAccessDenied)
+	hooks.Reset()
+	server.NoError(server.GetMS().PutObject("test", "dir/direct.bin", GetUid(), []byte(content)))
+	ev = nextEvent(t, events)
+	require.Equal(t, string(notification.ObjectCreatedPut), ev.EventName)
+	require.Equal(t, "dir%2Fdirect.bin", ev.S3.Object.Key)
+
+	// the second subscriber only sees the creation of .txt objects, in every bucket
+	var keys []string
+	for i := 0; i < 4; i++ {
+		ev = nextEvent(t, created)
+		keys = append(keys, ev.S3.Bucket.Name+"/"+eventKey(ev))
+	}
+	require.Equal(t, []string{"other/dir/hello.txt", "test/hello.txt", "test/dir/hello.txt", "test/dir/multi.txt"}, keys)
+
+	cancel()
+	_, ok := <-events
+	require.False(t, ok)
+}
+
+func TestBucketNotificationWebhook(t *testing.T) {
+	received := make(chan WebhookPayload, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err == nil {
+			received <- payload
+		}
+	}))
+	defer webhook.Close()
+
+	server := NewTestServer(t, WithBuckets("test"))
+	ctx := context.Background()
+	arn := server.GetMS().GetEvents().AddWebhook("1", webhook.URL)
+	require.Equal(t, "arn:minio:sqs:us-east-1:1:webhook", arn)
+
+	cfg := notification.Configuration{}
+	unknown, err := notification.NewArnFromString("arn:minio:sqs:us-east-1:2:webhook")
+	require.NoError(t, err)
+	queue := notification.NewConfig(unknown)
+	queue.AddEvents(notification.ObjectCreatedAll)
+	cfg.AddQueue(queue)
+	err = server.Client.SetBucketNotification(ctx, "test", cfg)
+	require.Equal(t, "InvalidArgument", minio.ToErrorResponse(err).Code)
+
+	target, err := notification.NewArnFromString(arn)
+	require.NoError(t, err)
+	queue = notification.NewConfig(target)
+	queue.AddEvents(notification.ObjectCreatedAll, notification.ObjectRemovedAll)
+	queue.AddFilterSuffix(".jpg")
+	cfg = notification.Configuration{}
+	cfg.AddQueue(queue)
+	server.NoError(server.Client.SetBucketNotification(ctx, "test", cfg))
+
+	got, err := server.Client.GetBucketNotification(ctx, "test")
+	server.NoError(err)
+	require.Len(t, got.QueueConfigs, 1)
+	require.Equal(t, arn, got.QueueConfigs[0].Queue)
+
+	content := `hello world`
+	for _, object := range []string{"hello.txt", "photo.jpg"} {
+		_, err = server.Client.PutObject(ctx, "test", object, strings.NewReader(content),
+			int64(len(content)), minio.PutObjectOptions{})
+		server.NoError(err)
+	}
+	server.NoError(server.Client.RemoveObject(ctx, "test", "photo.jpg", minio.RemoveObjectOptions{}))
+
+	var payloads []WebhookPayload
+	for i := 0; i < 2; i++ {
+		select {
+		case payload := <-received:
+			payloads = append(payloads, payload)
+		case <-time.After(5 * time.Second):
+			t.Fatal("webhook not called")
+		}
+	}
+	require.Equal(t, string(notification.ObjectCreatedPut), payloads[0].EventName)
+	require.Equal(t, "test/photo.jpg", payloads[0].Key)
+	require.Len(t, payloads[0].Records, 1)
+	require.Equal(t, string(notification.ObjectRemovedDelete), payloads[1].EventName)
+	require.Len(t, received, 0)
+}
+
+func TestListenBucketNotification(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	infos := server.Client.ListenBucketNotification(ctx, "test", "dir/", "",
+		[]string{string(notification.ObjectCreatedAll), string(notification.ObjectRemovedAll)})
+	all := server.Client.ListenNotification(ctx, "", "", []string{string(notification.ObjectRemovedAll)})
+
+	// the stream is established before the first event is published
+	require.Eventually(t, func() bool {
+		return server.GetMS().GetEvents().SubscriberCount() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	content := `hello world`
+	for _, object := range []string{"hello.txt", "dir/hello.txt"} {
+		_, err := server.Client.PutObject(context.Background(), "test", object, strings.NewReader(content),
+			int64(len(content)), minio.PutObjectOptions{})
+		server.NoError(err)
+	}
+	server.NoError(server.Client.RemoveObject(context.Background(), "test", "dir/hello.txt", minio.RemoveObjectOptions{}))
+
+	nextInfo := func(infos <-chan notification.Info) notification.Event {
+		select {
+		case info := <-infos:
+			require.NoError(t, info.Err)
+			require.Len(t, info.Records, 1)
+			return info.Records[0]
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification received")
+		}
+		return notification.Event{}
+	}
+	ev := nextInfo(infos)
+	require.Equal(t, string(notification.ObjectCreatedPut), ev.EventName)
+	require.Equal(t, "dir/hello.txt", eventKey(ev))
+	require.Equal(t, string(notification.ObjectRemovedDelete), nextInfo(infos).EventName)
+	require.Equal(t, string(notification.ObjectRemovedDelete), nextInfo(all).EventName)
+
+	// unknown buckets and events are rejected
+	info := <-server.Client.ListenBucketNotification(ctx, "missing", "", "", nil)
+	require.Equal(t, "NoSuchBucket", minio.ToErrorResponse(info.Err).Code)
+	info = <-server.Client.ListenBucketNotification(ctx, "test", "", "", []string{"s3:Unknown"})
+	require.Equal(t, "InvalidArgument", minio.ToErrorResponse(info.Err).Code)
+}
@@ -0,0 +1,292 @@
+package gominio
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// FaultAction is the misbehaviour applied to a request matched by a FaultRule
+type FaultAction string
+
+const (
+	// FaultError responds with the S3 error whose code is FaultRule.Error
+	FaultError FaultAction = "error"
+	// FaultDrop closes the connection after FaultRule.Bytes bytes of the response body
+	FaultDrop FaultAction = "drop"
+	// FaultTruncate stops writing the response body after FaultRule.Bytes bytes
+	FaultTruncate FaultAction = "truncate"
+	// FaultCorrupt flips every response body byte from offset FaultRule.Bytes on
+	FaultCorrupt FaultAction = "corrupt"
+	// FaultHang blocks the request until the client cancels it
+	FaultHang FaultAction = "hang"
+)
+
+// controlPrefix is the path prefix of the gominio control endpoints
+const controlPrefix = "/minio/gominio/v1"
+
+// FaultRule describes which requests misbehave and how, zero value match fields match every request
+type FaultRule struct {
+	ID        string      `json:"id,omitempty"`
+	Operation string      `json:"operation,omitempty"`
+	Bucket    string      `json:"bucket,omitempty"`
+	KeyPrefix string      `json:"keyPrefix,omitempty"`
+	Nth       int         `json:"nth,omitempty"`   // only the nth matching request misbehaves
+	Times     int         `json:"times,omitempty"` // the rule is spent after misbehaving Times times
+	Action    FaultAction `json:"action"`
+	Error     string      `json:"error,omitempty"`
+	Bytes     int64       `json:"bytes,omitempty"`
+
+	Matched int `json:"matched"`
+	Fired   int `json:"fired"`
+}
+
+func (fr *FaultRule) validate() error {
+	switch fr.Action {
+	case FaultError:
+		if _, ok := LookupAPIError(fr.Error); !ok {
+			return fmt.Errorf("unknown S3 error code %q", fr.Error)
+		}
+	case FaultDrop, FaultTruncate, FaultCorrupt, FaultHang:
+	default:
+		return fmt.Errorf("unknown fault action %q", fr.Action)
+	}
+	if fr.Nth < 0 || fr.Times < 0 || fr.Bytes < 0 {
+		return fmt.Errorf("nth, times and bytes must not be negative")
+	}
+	return nil
+}
+
+func (fr *FaultRule) match(op, bucket, key string) bool {
+	switch {
+	case fr.Operation != "" && fr.Operation != op:
+		return false
+	case fr.Bucket != "" && fr.Bucket != bucket:
+		return false
+	case fr.KeyPrefix != "" && !strings.HasPrefix(key, fr.KeyPrefix):
+		return false
+	case fr.Times > 0 && fr.Fired >= fr.Times:
+		return false
+	}
+
+	fr.Matched++
+	if fr.Nth > 0 && fr.Matched != fr.Nth {
+		return false
+	}
+	fr.Fired++
+	return true
+}
+
+// FaultInjector holds the fault rules evaluated for every request, the first matching rule wins
+type FaultInjector struct {
+	sync.Mutex
+	rules []*FaultRule
+	seq   int
+}
+
+func NewFaultInjector() *FaultInjector {
+	return &FaultInjector{}
+}
+
+// Add registers a rule and returns its id
+func (fi *FaultInjector) Add(rule FaultRule) (string, error) {
+	if err := rule.validate(); err != nil {
+		return "", err
+	}
+
+	fi.Lock()
+	defer fi.Unlock()
+
+	fi.seq++
+	rule.ID = fmt.Sprintf("fault-%d", fi.seq)
+	rule.Matched, rule.Fired = 0, 0
+	fi.rules = append(fi.rules, &rule)
+	return rule.ID, nil
+}
+
+// Remove removes the rule with the given id
+func (fi *FaultInjector) Remove(id string) bool {
+	fi.Lock()
+	defer fi.Unlock()
+
+	for i, rule := range fi.rules {
+		if rule.ID == id {
+			fi.rules = append(fi.rules[:i], fi.rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Reset removes all rules
+func (fi *FaultInjector) Reset() {
+	fi.Lock()
+	defer fi.Unlock()
+
+	fi.rules = nil
+}
+
+// Rules returns a snapshot of the registered rules and their counters
+func (fi *FaultInjector) Rules() []FaultRule {
+	fi.Lock()
+	defer fi.Unlock()
+
+	rules := make([]FaultRule, 0, len(fi.rules))
+	for _, rule := range fi.rules {
+		rules = append(rules, *rule)
+	}
+	return rules
+}
+
+func (fi *FaultInjector) match(op, bucket, key string) *FaultRule {
+	fi.Lock()
+	defer fi.Unlock()
+
+	for _, rule := range fi.rules {
+		if rule.match(op, bucket, key) {
+			matched := *rule
+			return &matched
+		}
+	}
+	return nil
+}
+
+// faultWriter tampers with the response body according to a fault rule
+type faultWriter struct {
+	gin.ResponseWriter
+	rule    *FaultRule
+	written int64
+}
+
+func (fw *faultWriter) Write(data []byte) (int, error) {
+	n := len(data)
+	switch fw.rule.Action {
+	case FaultTruncate, FaultDrop:
+		remain := fw.rule.Bytes - fw.written
+		if remain <= 0 {
+			return 0, fw.stop()
+		}
+		if int64(len(data)) > remain {
+			if _, err := fw.ResponseWriter.Write(data[:remain]); err != nil {
+				return 0, err
+			}
+			fw.written += remain
+			return int(remain), fw.stop()
+		}
+	case FaultCorrupt:
+		corrupted := make([]byte, len(data))
+		for i, b := range data {
+			if fw.written+int64(i) >= fw.rule.Bytes {
+				b ^= 0xff
+			}
+			corrupted[i] = b
+		}
+		data = corrupted
+	}
+
+	n, err := fw.ResponseWriter.Write(data)
+	fw.written += int64(n)
+	return n, err
+}
+
+func (fw *faultWriter) WriteString(s string) (int, error) {
+	return fw.Write([]byte(s))
+}
+
+// stop ends the response body, dropping the connection when asked to
+func (fw *faultWriter) stop() error {
+	if fw.rule.Action == FaultDrop {
+		fw.ResponseWriter.Flush()
+		conn, _, err := fw.ResponseWriter.Hijack()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}
+	return fmt.Errorf("gominio: response %s by fault %s", fw.rule.Action, fw.rule.ID)
+}
+
+// faultMiddleware applies the first fault rule matching the request
+func (api *ApiServer) faultMiddleware(ctx *gin.Context) {
+	if strings.HasPrefix(ctx.FullPath(), controlPrefix) {
+		return
+	}
+
+	bucket := ctx.Param("bucket")
+	object := ctx.Param("object")
+	rule := api.faults.match(GetOperation(ctx), bucket, object)
+	if rule == nil {
+		return
+	}
+
+	switch rule.Action {
+	case FaultError:
+		apiErr, _ := LookupAPIError(rule.Error)
+		ErrResponse(ctx, object, bucket, apiErr)
+		ctx.Abort()
+	case FaultHang:
+		<-ctx.Request.Context().Done()
+		ctx.Abort()
+	default:
+		ctx.Writer = &faultWriter{ResponseWriter: ctx.Writer, rule: rule}
+	}
+}
+
+// ListFaults list fault rules
+func (api *ApiServer) ListFaults(ctx *gin.Context) {
+	data, err := json.Marshal(api.faults.Rules())
+	if err != nil {
+		ErrResponse(ctx, "", "", ErrInternalError)
+		return
+	}
+	ctx.Writer.Header().Set("Content-Type", "application/json")
+	SuccessResponse(ctx, http.StatusOK, data)
+}
+
+// AddFault add a fault rule, the rule is returned with its id
+func (api *ApiServer) AddFault(ctx *gin.Context) {
+	var rule FaultRule
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&rule); err != nil {
+		apiErr := ErrInvalidRequest
+		apiErr.Description = err.Error()
+		ErrResponse(ctx, "", "", apiErr)
+		return
+	}
+
+	id, err := api.faults.Add(rule)
+	if err != nil {
+		apiErr := ErrInvalidRequest
+		apiErr.Description = err.Error()
+		ErrResponse(ctx, "", "", apiErr)
+		return
+	}
+	rule.ID = id
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		ErrResponse(ctx, "", "", ErrInternalError)
+		return
+	}
+	ctx.Writer.Header().Set("Content-Type", "application/json")
+	SuccessResponse(ctx, http.StatusOK, data)
+}
+
+// DeleteFault delete the fault rule given by the id query, or all rules without it
+func (api *ApiServer) DeleteFault(ctx *gin.Context) {
+	id, ok := ctx.GetQuery("id")
+	if !ok {
+		api.faults.Reset()
+		SuccessResponse(ctx, http.StatusNoContent, nil)
+		return
+	}
+
+	if !api.faults.Remove(id) {
+		apiErr := ErrInvalidRequest
+		apiErr.Description = fmt.Sprintf("fault rule %q not found", id)
+		ErrResponse(ctx, "", "", apiErr)
+		return
+	}
+	SuccessResponse(ctx, http.StatusNoContent, nil)
+}
@@ -0,0 +1,147 @@
+package gominio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/signer"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFaultInjection(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	faults := server.GetFaults()
+
+	// transient errors are retried by the client
+	_, err := faults.Add(FaultRule{Operation: OpPutObject, Bucket: "test", Times: 2,
+		Action: FaultError, Error: ErrSlowDown.Code})
+	require.NoError(t, err)
+
+	content := `hello world`
+	_, err = server.Client.PutObject(context.Background(), "test", "hello.txt",
+		strings.NewReader(content), int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+	server.GetJournal().AssertCalled(t, OpPutObject, 3)
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpPutObject, Status: http.StatusServiceUnavailable}, 2)
+	faults.Reset()
+
+	// only the second download is truncated
+	_, err = faults.Add(FaultRule{Operation: OpGetObject, KeyPrefix: "hello", Nth: 2,
+		Action: FaultTruncate, Bytes: 5})
+	require.NoError(t, err)
+
+	readObject := func(ctx context.Context) ([]byte, error) {
+		obj, err := server.Client.GetObject(ctx, "test", "hello.txt", minio.GetObjectOptions{})
+		if err != nil {
+			return nil, err
+		}
+		defer obj.Close()
+		return io.ReadAll(obj)
+	}
+
+	data, err := readObject(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+	_, err = readObject(context.Background())
+	require.Error(t, err)
+	data, err = readObject(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+	faults.Reset()
+
+	// corrupted and dropped downloads
+	id, err := faults.Add(FaultRule{Operation: OpGetObject, Action: FaultCorrupt, Bytes: 6})
+	require.NoError(t, err)
+	data, err = readObject(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, content[:6], string(data[:6]))
+	require.NotEqual(t, content, string(data))
+	require.True(t, faults.Remove(id))
+
+	_, err = faults.Add(FaultRule{Operation: OpGetObject, Action: FaultDrop, Bytes: 2})
+	require.NoError(t, err)
+	_, err = readObject(context.Background())
+	require.Error(t, err)
+	faults.Reset()
+
+	// hanging requests return once the client gives up
+	_, err = faults.Add(FaultRule{Operation: OpHeadObject, Action: FaultHang})
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = server.Client.StatObject(ctx, "test", "hello.txt", minio.StatObjectOptions{})
+	require.Error(t, err)
+
+	_, err = faults.Add(FaultRule{Action: "explode"})
+	require.Error(t, err)
+}
+
+func TestFaultControlEndpoint(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	url := fmt.Sprintf("http://%s%s/faults", server.Endpoint, controlPrefix)
+
+	rsp, err := server.HTTPClient.Post(url, "application/json",
+		strings.NewReader(`{"operation":"ListBuckets","action":"error","error":"InternalError"}`))
+	require.NoError(t, err)
+	var rule FaultRule
+	require.NoError(t, json.NewDecoder(rsp.Body).Decode(&rule))
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.NotEmpty(t, rule.ID)
+
+	rsp, err = server.HTTPClient.Post(url, "application/json", strings.NewReader(`{"action":"error","error":"Nope"}`))
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = server.Client.ListBuckets(ctx)
+	require.Error(t, err)
+
+	rsp, err = server.HTTPClient.Get(url)
+	require.NoError(t, err)
+	var rules []FaultRule
+	require.NoError(t, json.NewDecoder(rsp.Body).Decode(&rules))
+	_ = rsp.Body.Close()
+	require.Len(t, rules, 1)
+	require.Greater(t, rules[0].Fired, 0)
+
+	req, err := http.NewRequest(http.MethodDelete, url+"?id="+rule.ID, nil)
+	require.NoError(t, err)
+	rsp, err = server.HTTPClient.Do(req)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusNoContent, rsp.StatusCode)
+	require.Empty(t, server.GetFaults().Rules())
+
+	_, err = server.Client.ListBuckets(context.Background())
+	require.NoError(t, err)
+
+	// once the identity store holds users only the root credentials may use the control endpoint
+	iam := server.GetMS().GetIAM()
+	require.NoError(t, iam.SetPolicy("readwrite", []byte(`{"Statement": [
+		{"Effect": "Allow", "Action": "s3:*", "Resource": "arn:aws:s3:::*"}]}`)))
+	require.NoError(t, iam.AddUser("user", "user-secret", "readwrite"))
+	get := func(access, secret string) int {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		if access != "" {
+			req = signer.SignV4(*req, access, secret, "", server.GetMS().GetRegion())
+		}
+		rsp, err := server.HTTPClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, rsp.Body.Close())
+		return rsp.StatusCode
+	}
+	require.Equal(t, http.StatusForbidden, get("", ""))
+	require.Equal(t, http.StatusForbidden, get("user", "user-secret"))
+	require.Equal(t, http.StatusForbidden, get(server.Access, "wrong-secret"))
+	require.Equal(t, http.StatusOK, get(server.Access, server.Secret))
+}
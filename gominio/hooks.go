@@ -0,0 +1,144 @@
+package gominio
+
+import (
+	"github.com/minio/minio-go/v7/pkg/tags"
+	"sync"
+)
+
+// HookInfo describes the change a hook is called for, fields not relevant to the operation are empty
+type HookInfo struct {
+	// Operation is one of the Op constants, like OpPutObject
+	Operation  string
+	Bucket     string
+	Object     string
+	UploadID   string
+	PartNumber int
+	// Size is the size of the written data, for CompleteMultipartUpload and CopyObject it is only
+	// known by the after hooks
+	Size int64
+	// Etag is the etag of the written object, it is only known by the after hooks
+	Etag string
+	Tags *tags.Tags
+	// SrcBucket and SrcObject are the source of CopyObject
+	SrcBucket string
+	SrcObject string
+
+	// changed is the object written or deleted by the change, it is only set for the after hooks
+	// of the changes of objects
+	changed *ObjectInfo
+}
+
+// BeforeHook is called before a change is applied, returning an error vetoes it. Errors are
+// mapped to S3 errors by ToAPIError, so an APIError value picks the error returned to clients.
+type BeforeHook func(info HookInfo) error
+
+// AfterHook is called once a change has been applied
+type AfterHook func(info HookInfo)
+
+type hookEntry struct {
+	id        uint64
+	operation string
+	before    BeforeHook
+	after     AfterHook
+	// builtin hooks are registered by the server itself, they are kept by Reset
+	builtin bool
+}
+
+// Hooks is the registry of the callbacks run around the changes of a MinioServer. Hooks run
+// without the server lock held, they may call the MinioServer methods.
+type Hooks struct {
+	sync.RWMutex
+	nextID  uint64
+	entries []hookEntry
+}
+
+func newHooks() *Hooks {
+	return &Hooks{}
+}
+
+// Before registers fn to run before the changes of operation, every change when operation is empty.
+// The returned function unregisters the hook.
+func (h *Hooks) Before(operation string, fn BeforeHook) func() {
+	return h.add(hookEntry{operation: operation, before: fn})
+}
+
+// After registers fn to run after the changes of operation, every change when operation is empty.
+// The returned function unregisters the hook.
+func (h *Hooks) After(operation string, fn AfterHook) func() {
+	return h.add(hookEntry{operation: operation, after: fn})
+}
+
+// Reset unregisters every hook, the hooks of the server itself publishing events and queueing
+// replications stay registered
+func (h *Hooks) Reset() {
+	h.Lock()
+	defer h.Unlock()
+
+	var builtin []hookEntry
+	for _, e := range h.entries {
+		if e.builtin {
+			builtin = append(builtin, e)
+		}
+	}
+	h.entries = builtin
+}
+
+func (h *Hooks) add(entry hookEntry) func() {
+	h.Lock()
+	defer h.Unlock()
+
+	h.nextID++
+	entry.id = h.nextID
+	h.entries = append(h.entries, entry)
+	return func() {
+		h.Lock()
+		defer h.Unlock()
+
+		for i, e := range h.entries {
+			if e.id == entry.id {
+				h.entries = append(h.entries[:i:i], h.entries[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// run applies the change between the before and after hooks of info.Operation, the change may
+// complete info for the after hooks
+func (h *Hooks) run(info *HookInfo, change func() error) error {
+	if h == nil {
+		return change()
+	}
+
+	h.RLock()
+	var entries []hookEntry
+	for _, e := range h.entries {
+		if e.operation == "" || e.operation == info.Operation {
+			entries = append(entries, e)
+		}
+	}
+	h.RUnlock()
+
+	for _, e := range entries {
+		if e.before == nil {
+			continue
+		}
+		if err := e.before(*info); err != nil {
+			return err
+		}
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.after != nil {
+			e.after(*info)
+		}
+	}
+	return nil
+}
+
+// GetHooks returns the hook registry of the server
+func (ms *MinioServer) GetHooks() *Hooks {
+	return ms.hooks
+}
@@ -0,0 +1,99 @@
+package gominio
+
+import (
+	"context"
+	"errors"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	ms := server.GetMS()
+	ctx := context.Background()
+
+	// before hooks veto changes with the S3 error of their choice
+	quota := ErrEntityTooLarge
+	quota.Description = "bucket quota exceeded"
+	removeQuota := ms.GetHooks().Before(OpPutObject, func(info HookInfo) error {
+		if info.Size > 5 {
+			return quota
+		}
+		return nil
+	})
+	content := `hello world`
+	_, err := server.Client.PutObject(ctx, "test", "hello.txt", strings.NewReader(content),
+		int64(len(content)), minio.PutObjectOptions{})
+	errRsp := minio.ToErrorResponse(err)
+	require.Equal(t, "EntityTooLarge", errRsp.Code)
+	require.Equal(t, "bucket quota exceeded", errRsp.Message)
+	require.NotContains(t, ms.Dump(), "hello.txt")
+
+	removeQuota()
+	_, err = server.Client.PutObject(ctx, "test", "hello.txt", strings.NewReader(content),
+		int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+
+	// errors other than S3 errors are internal errors, the MinioServer errors keep their meaning
+	ms.GetHooks().Before(OpDeleteObject, func(info HookInfo) error {
+		if info.Object == "hello.txt" {
+			return errors.New("object is locked")
+		}
+		return ErrBucketNotExists
+	})
+	err = server.Client.RemoveObject(ctx, "test", "other.txt", minio.RemoveObjectOptions{})
+	require.Equal(t, "NoSuchBucket", minio.ToErrorResponse(err).Code)
+	err = ms.DeleteObject("test", "hello.txt")
+	require.EqualError(t, err, "object is locked")
+	require.Equal(t, "InternalError", ToAPIError(err).Code)
+
+	// after hooks see the applied changes and may use the server, they run on the goroutines
+	// serving the requests so they report to the test over a channel
+	type completion struct {
+		info   HookInfo
+		exists bool
+	}
+	completed := make(chan completion, 1)
+	ms.GetHooks().After("", func(info HookInfo) {
+		if info.Operation == OpCompleteMultipartUpload {
+			completed <- completion{info: info, exists: ms.BucketExists(info.Bucket)}
+		}
+	})
+	core := minio.Core{Client: server.Client}
+	id, err := core.NewMultipartUpload(ctx, "test", "multi.txt", minio.PutObjectOptions{})
+	server.NoError(err)
+	part, err := core.PutObjectPart(ctx, "test", "multi.txt", id, 1,
+		strings.NewReader(content), int64(len(content)), minio.PutObjectPartOptions{})
+	server.NoError(err)
+	_, err = core.CompleteMultipartUpload(ctx, "test", "multi.txt", id,
+		[]minio.CompletePart{{PartNumber: 1, ETag: part.ETag}}, minio.PutObjectOptions{})
+	server.NoError(err)
+	var c completion
+	select {
+	case c = <-completed:
+	default:
+		t.Fatal("the after hook of CompleteMultipartUpload was not called")
+	}
+	require.True(t, c.exists)
+	require.Equal(t, "multi.txt", c.info.Object)
+	require.Equal(t, id, c.info.UploadID)
+	require.Equal(t, int64(len(content)), c.info.Size)
+	require.NotEmpty(t, c.info.Etag)
+	require.Empty(t, completed)
+
+	// hooks apply to direct calls as well
+	ms.GetHooks().Before(OpCreateBucket, func(info HookInfo) error {
+		if strings.HasPrefix(info.Bucket, "tmp-") {
+			return ErrAccessDenied
+		}
+		return nil
+	})
+	require.ErrorIs(t, ms.CreateBucket("tmp-bucket", ""), ErrAccessDenied)
+	require.NoError(t, ms.CreateBucket("bucket", ""))
+
+	ms.GetHooks().Reset()
+	require.NoError(t, ms.CreateBucket("tmp-bucket", ""))
+	require.NoError(t, ms.DeleteObject("test", "hello.txt"))
+}
@@ -0,0 +1,594 @@
+package gominio
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors returned by the IAM methods
+var (
+	ErrUserNotExists   = errors.New("user not exists")
+	ErrPolicyNotExists = errors.New("policy not exists")
+)
+
+const (
+	policyEffectAllow = "Allow"
+	policyEffectDeny  = "Deny"
+
+	// s3ResourcePrefix is the ARN prefix of the buckets and objects policy resources name
+	s3ResourcePrefix = "arn:aws:s3:::"
+	// adminActionPrefix is the prefix of the admin API actions, they apply to the whole server
+	// so statements allowing only admin actions may have no resource
+	adminActionPrefix = "admin:"
+)
+
+// PolicyValues is a list of policy actions or resources, documents may give a single string
+type PolicyValues []string
+
+// UnmarshalJSON accepts both a string and a list of strings
+func (v *PolicyValues) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*v = PolicyValues{value}
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+// PolicyPrincipal is the principal of a bucket policy statement, documents may give "*" for
+// anyone instead of {"AWS": "*"}
+type PolicyPrincipal struct {
+	AWS PolicyValues `json:"AWS,omitempty"`
+}
+
+// UnmarshalJSON accepts both "*" and a principal object
+func (p *PolicyPrincipal) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		p.AWS = PolicyValues{value}
+		return nil
+	}
+	type principal PolicyPrincipal
+	return json.Unmarshal(data, (*principal)(p))
+}
+
+// anyone reports whether the principal is everyone, including anonymous requests
+func (p *PolicyPrincipal) anyone() bool {
+	return p != nil && containsName(p.AWS, "*")
+}
+
+// PolicyStatement allows or denies actions, like s3:GetObject, on resources, like
+// arn:aws:s3:::bucket/prefix*. Actions and resources may contain * and ? wildcards.
+// Statements of admin actions, like admin:CreateUser, may omit the resource.
+// Only the statements of bucket policies have a principal.
+type PolicyStatement struct {
+	Sid       string           `json:"Sid,omitempty"`
+	Effect    string           `json:"Effect"`
+	Principal *PolicyPrincipal `json:"Principal,omitempty"`
+	Action    PolicyValues     `json:"Action"`
+	Resource  PolicyValues     `json:"Resource,omitempty"`
+	// Condition is rejected by ParsePolicy, conditions are not evaluated
+	Condition json.RawMessage `json:"Condition,omitempty"`
+}
+
+// Policy is an IAM-style policy document
+type Policy struct {
+	Version   string            `json:"Version,omitempty"`
+	Statement []PolicyStatement `json:"Statement"`
+}
+
+// ParsePolicy parses and validates a JSON policy document, errors are MalformedPolicy errors
+func ParsePolicy(data []byte) (*Policy, error) {
+	return parsePolicy(data, false)
+}
+
+// ParseBucketPolicy parses and validates a JSON bucket policy document, every statement must
+// have a principal. Errors are MalformedPolicy errors.
+func ParseBucketPolicy(data []byte) (*Policy, error) {
+	return parsePolicy(data, true)
+}
+
+func parsePolicy(data []byte, bucket bool) (*Policy, error) {
+	malformed := func(format string, args ...any) error {
+		apiErr := ErrMalformedPolicy
+		apiErr.Description = fmt.Sprintf(format, args...)
+		return apiErr
+	}
+
+	var policy Policy
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, malformed("invalid policy document: %v", err)
+	}
+	if len(policy.Statement) == 0 {
+		return nil, malformed("policy has no statement")
+	}
+	for i, st := range policy.Statement {
+		switch {
+		case st.Effect != policyEffectAllow && st.Effect != policyEffectDeny:
+			return nil, malformed("statement %d: invalid effect %q", i, st.Effect)
+		case len(st.Action) == 0:
+			return nil, malformed("statement %d: no action", i)
+		case len(st.Resource) == 0 && !st.adminOnly():
+			return nil, malformed("statement %d: no resource", i)
+		case len(st.Condition) > 0:
+			return nil, malformed("statement %d: conditions are not supported", i)
+		case bucket && (st.Principal == nil || len(st.Principal.AWS) == 0):
+			return nil, malformed("statement %d: no principal", i)
+		case !bucket && st.Principal != nil:
+			return nil, malformed("statement %d: principals are only allowed in bucket policies", i)
+		}
+		for _, action := range st.Action {
+			if action != "*" && !strings.HasPrefix(action, "s3:") && !strings.HasPrefix(action, adminActionPrefix) {
+				return nil, malformed("statement %d: unsupported action %q", i, action)
+			}
+		}
+		for _, resource := range st.Resource {
+			if resource != "*" && !strings.HasPrefix(resource, s3ResourcePrefix) {
+				return nil, malformed("statement %d: unsupported resource %q", i, resource)
+			}
+		}
+	}
+	return &policy, nil
+}
+
+// adminOnly reports whether the statement only has admin actions
+func (st *PolicyStatement) adminOnly() bool {
+	for _, action := range st.Action {
+		if !strings.HasPrefix(action, adminActionPrefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluate reports whether the statements of p allow and deny action on resource, statements
+// without resource apply to any resource
+func (p *Policy) evaluate(action, resource string) (allowed, denied bool) {
+	for _, st := range p.Statement {
+		if !matchAnyWildcard(st.Action, action, true) {
+			continue
+		}
+		if len(st.Resource) > 0 && !matchAnyWildcard(st.Resource, resource, false) {
+			continue
+		}
+		if st.Effect == policyEffectDeny {
+			denied = true
+		} else {
+			allowed = true
+		}
+	}
+	return allowed, denied
+}
+
+// allowsAnyone reports whether the statements of p whose principal is everyone allow action on
+// resource and none of them denies it
+func (p *Policy) allowsAnyone(action, resource string) bool {
+	public := &Policy{}
+	for _, st := range p.Statement {
+		if st.Principal.anyone() {
+			public.Statement = append(public.Statement, st)
+		}
+	}
+	allowed, denied := public.evaluate(action, resource)
+	return allowed && !denied
+}
+
+func matchAnyWildcard(patterns []string, value string, fold bool) bool {
+	for _, pattern := range patterns {
+		if fold {
+			pattern, value = strings.ToLower(pattern), strings.ToLower(value)
+		}
+		if matchWildcard(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcard matches value against pattern, * matches any sequence and ? any single character
+func matchWildcard(pattern, value string) bool {
+	var p wildcardPattern
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			p = p.add(wildcardAny, r)
+		case '?':
+			p = p.add(wildcardOne, r)
+		default:
+			p = p.add(wildcardLiteral, r)
+		}
+	}
+	return p.match([]rune(value))
+}
+
+// User is an identity of the server, requests signed with its access key are granted the
+// actions its policies allow
+type User struct {
+	AccessKey string
+	SecretKey string
+	Enabled   bool
+	Policies  []string
+}
+
+// IAM is the identity store of a MinioServer. Once it holds a user or temporary credentials every
+// request must be signed by a known access key: the root credentials of the server are allowed
+// everything while users are allowed what their policies allow and do not deny.
+type IAM struct {
+	sync.RWMutex
+	ms       *MinioServer
+	users    map[string]*User
+	policies map[string]*Policy
+
+	sessions           map[string]*Session
+	openID             *openIDVerifier
+	maxSessionDuration time.Duration
+}
+
+func newIAM(ms *MinioServer) *IAM {
+	return &IAM{
+		ms:       ms,
+		users:    make(map[string]*User),
+		policies: make(map[string]*Policy),
+		sessions: make(map[string]*Session),
+	}
+}
+
+// Enabled reports whether requests are authenticated and authorized, that is when users or
+// temporary credentials exist
+func (iam *IAM) Enabled() bool {
+	iam.RLock()
+	defer iam.RUnlock()
+
+	return len(iam.users) > 0 || len(iam.sessions) > 0
+}
+
+// SetPolicy parses the JSON policy document and stores it under name, replacing any previous one
+func (iam *IAM) SetPolicy(name string, document []byte) error {
+	if name == "" {
+		apiErr := ErrInvalidArgument
+		apiErr.Description = "policy name is empty"
+		return apiErr
+	}
+	policy, err := ParsePolicy(document)
+	if err != nil {
+		return err
+	}
+
+	iam.Lock()
+	defer iam.Unlock()
+
+	iam.policies[name] = policy
+	return nil
+}
+
+// GetPolicy returns the policy stored under name
+func (iam *IAM) GetPolicy(name string) (*Policy, bool) {
+	iam.RLock()
+	defer iam.RUnlock()
+
+	policy, ok := iam.policies[name]
+	return policy, ok
+}
+
+// ListPolicies returns the names of the stored policies in sorted order
+func (iam *IAM) ListPolicies() []string {
+	iam.RLock()
+	defer iam.RUnlock()
+
+	names := make([]string, 0, len(iam.policies))
+	for name := range iam.policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RemovePolicy removes the policy stored under name and detaches it from the users
+func (iam *IAM) RemovePolicy(name string) error {
+	iam.Lock()
+	defer iam.Unlock()
+
+	if _, ok := iam.policies[name]; !ok {
+		return ErrPolicyNotExists
+	}
+	delete(iam.policies, name)
+	for _, user := range iam.users {
+		user.Policies = removeNames(user.Policies, name)
+	}
+	return nil
+}
+
+// AddUser adds an enabled user with the given policies attached, or updates the secret key of
+// an existing one
+func (iam *IAM) AddUser(access, secret string, policies ...string) error {
+	if err := validateCredentials(access, secret); err != nil {
+		return err
+	}
+	if iam.isRoot(access) {
+		apiErr := ErrInvalidArgument
+		apiErr.Description = "the access key of the root credentials cannot be used by a user"
+		return apiErr
+	}
+
+	iam.Lock()
+	defer iam.Unlock()
+
+	if err := iam.checkPolicies(policies); err != nil {
+		return err
+	}
+	user, ok := iam.users[access]
+	if !ok {
+		user = &User{AccessKey: access, Enabled: true}
+		iam.users[access] = user
+	}
+	user.SecretKey = secret
+	user.Policies = appendNames(user.Policies, policies...)
+	return nil
+}
+
+// validateCredentials applies the MinIO access and secret key length limits
+func validateCredentials(access, secret string) error {
+	apiErr := ErrInvalidArgument
+	switch {
+	case len(access) < 3 || len(access) > 128:
+		apiErr.Description = "access key length should be between 3 and 128"
+	case strings.ContainsAny(access, "=,/"):
+		apiErr.Description = "access key contains reserved characters '=', ',' or '/'"
+	case len(secret) < 8 || len(secret) > 40:
+		apiErr.Description = "secret key length should be between 8 and 40"
+	default:
+		return nil
+	}
+	return apiErr
+}
+
+// RemoveUser removes the user with the given access key and the temporary credentials issued to it
+func (iam *IAM) RemoveUser(access string) error {
+	iam.Lock()
+	defer iam.Unlock()
+
+	if _, ok := iam.users[access]; !ok {
+		return ErrUserNotExists
+	}
+	delete(iam.users, access)
+	for key, session := range iam.sessions {
+		if session.Parent == access {
+			delete(iam.sessions, key)
+		}
+	}
+	return nil
+}
+
+// SetUserEnabled enables or disables a user, requests signed by disabled users are denied
+func (iam *IAM) SetUserEnabled(access string, enabled bool) error {
+	iam.Lock()
+	defer iam.Unlock()
+
+	user, ok := iam.users[access]
+	if !ok {
+		return ErrUserNotExists
+	}
+	user.Enabled = enabled
+	return nil
+}
+
+// AttachPolicy attaches existing policies to a user
+func (iam *IAM) AttachPolicy(access string, policies ...string) error {
+	iam.Lock()
+	defer iam.Unlock()
+
+	user, ok := iam.users[access]
+	if !ok {
+		return ErrUserNotExists
+	}
+	if err := iam.checkPolicies(policies); err != nil {
+		return err
+	}
+	user.Policies = appendNames(user.Policies, policies...)
+	return nil
+}
+
+// SetUserPolicies replaces the policies attached to a user
+func (iam *IAM) SetUserPolicies(access string, policies ...string) error {
+	iam.Lock()
+	defer iam.Unlock()
+
+	user, ok := iam.users[access]
+	if !ok {
+		return ErrUserNotExists
+	}
+	if err := iam.checkPolicies(policies); err != nil {
+		return err
+	}
+	user.Policies = appendNames(nil, policies...)
+	return nil
+}
+
+// DetachPolicy detaches policies from a user
+func (iam *IAM) DetachPolicy(access string, policies ...string) error {
+	iam.Lock()
+	defer iam.Unlock()
+
+	user, ok := iam.users[access]
+	if !ok {
+		return ErrUserNotExists
+	}
+	user.Policies = removeNames(user.Policies, policies...)
+	return nil
+}
+
+// GetUser returns a copy of the user with the given access key
+func (iam *IAM) GetUser(access string) (User, bool) {
+	iam.RLock()
+	defer iam.RUnlock()
+
+	user, ok := iam.users[access]
+	if !ok {
+		return User{}, false
+	}
+	return copyUser(user), true
+}
+
+// ListUsers returns a copy of the users sorted by access key
+func (iam *IAM) ListUsers() []User {
+	iam.RLock()
+	defer iam.RUnlock()
+
+	users := make([]User, 0, len(iam.users))
+	for _, user := range iam.users {
+		users = append(users, copyUser(user))
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].AccessKey < users[j].AccessKey
+	})
+	return users
+}
+
+// IsAllowed reports whether the policies of the user allow action on resource, an explicit deny
+// overrides any allow. The root credentials are allowed everything. Temporary credentials are
+// allowed what both the identity they were issued to and their session policy allow.
+func (iam *IAM) IsAllowed(access, action, resource string) bool {
+	iam.RLock()
+	defer iam.RUnlock()
+
+	return iam.isAllowed(access, action, resource)
+}
+
+func (iam *IAM) isAllowed(access, action, resource string) bool {
+	if iam.isRoot(access) {
+		return true
+	}
+
+	var policies []string
+	if session, ok := iam.sessions[access]; ok {
+		if session.Policy != nil {
+			if allow, deny := session.Policy.evaluate(action, resource); deny || !allow {
+				return false
+			}
+		}
+		if session.Parent != "" {
+			return iam.isAllowed(session.Parent, action, resource)
+		}
+		policies = session.Policies
+	} else {
+		user, ok := iam.users[access]
+		if !ok || !user.Enabled {
+			return false
+		}
+		policies = user.Policies
+	}
+
+	allowed := false
+	for _, name := range policies {
+		policy, ok := iam.policies[name]
+		if !ok {
+			continue
+		}
+		allow, deny := policy.evaluate(action, resource)
+		if deny {
+			return false
+		}
+		allowed = allowed || allow
+	}
+	return allowed
+}
+
+// lookupCredentials returns the secret key and the session token of access, the session token
+// is empty for permanent credentials. Unknown access keys are InvalidAccessKeyId errors, disabled
+// users AccessDenied errors and expired temporary credentials ExpiredToken errors.
+func (iam *IAM) lookupCredentials(access string) (secret, token string, err error) {
+	if iam.isRoot(access) {
+		return iam.ms.Secret, "", nil
+	}
+
+	iam.RLock()
+	defer iam.RUnlock()
+
+	if session, ok := iam.sessions[access]; ok {
+		if !session.Expiration.After(time.Now()) {
+			return "", "", ErrExpiredToken
+		}
+		if session.Parent != "" && !iam.isRoot(session.Parent) {
+			if user, ok := iam.users[session.Parent]; !ok || !user.Enabled {
+				return "", "", ErrAccessDenied
+			}
+		}
+		return session.SecretKey, session.SessionToken, nil
+	}
+
+	user, ok := iam.users[access]
+	if !ok {
+		return "", "", ErrInvalidAccessKeyId
+	}
+	if !user.Enabled {
+		apiErr := ErrAccessDenied
+		apiErr.Description = "Your account is disabled; please contact your administrator."
+		return "", "", apiErr
+	}
+	return user.SecretKey, "", nil
+}
+
+func (iam *IAM) isRoot(access string) bool {
+	return iam.ms.Access != "" && access == iam.ms.Access
+}
+
+func (iam *IAM) checkPolicies(policies []string) error {
+	for _, name := range policies {
+		if _, ok := iam.policies[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrPolicyNotExists, name)
+		}
+	}
+	return nil
+}
+
+func copyUser(user *User) User {
+	u := *user
+	u.Policies = append([]string(nil), user.Policies...)
+	return u
+}
+
+func appendNames(names []string, add ...string) []string {
+	for _, name := range add {
+		if !containsName(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func removeNames(names []string, remove ...string) []string {
+	kept := names[:0:0]
+	for _, name := range names {
+		if !containsName(remove, name) {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetIAM returns the identity store of the server
+func (ms *MinioServer) GetIAM() *IAM {
+	return ms.iam
+}
@@ -0,0 +1,191 @@
+package gominio
+
+import (
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const uploaderPolicy = `{
+	"Version": "2012-10-17",
+	"Statement": [
+		{"Effect": "Allow", "Action": "s3:ListBucket", "Resource": "arn:aws:s3:::test"},
+		{"Effect": "Allow", "Action": ["s3:GetObject", "s3:PutObject"], "Resource": ["arn:aws:s3:::test/uploads/*"]},
+		{"Effect": "Deny", "Action": "s3:*", "Resource": "arn:aws:s3:::test/uploads/private/*"}
+	]
+}`
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy([]byte(uploaderPolicy))
+	require.NoError(t, err)
+	require.Len(t, policy.Statement, 3)
+	require.Equal(t, PolicyValues{"s3:ListBucket"}, policy.Statement[0].Action)
+	_, err = ParsePolicy([]byte(`{"Statement": [{"Effect": "Allow", "Action": "admin:*"}]}`))
+	require.NoError(t, err)
+
+	for _, document := range []string{
+		`test policy`,
+		`{"Statement": []}`,
+		`{"Statement": [{"Effect": "Maybe", "Action": "s3:*", "Resource": "*"}]}`,
+		`{"Statement": [{"Effect": "Allow", "Action": "s3:*"}]}`,
+		`{"Statement": [{"Effect": "Allow", "Action": ["admin:*", "s3:*"]}]}`,
+		`{"Statement": [{"Effect": "Allow", "Action": "iam:*", "Resource": "*"}]}`,
+		`{"Statement": [{"Effect": "Allow", "NotAction": "s3:*", "Resource": "*"}]}`,
+		`{"Statement": [{"Effect": "Allow", "Action": "s3:*", "Resource": "*",
+			"Condition": {"StringLike": {"s3:prefix": "home/*"}}}]}`,
+	} {
+		_, err = ParsePolicy([]byte(document))
+		require.Equal(t, "MalformedPolicy", ToAPIError(err).Code, document)
+	}
+
+	require.True(t, matchWildcard("arn:aws:s3:::test/*", "arn:aws:s3:::test/a/b.txt"))
+	require.True(t, matchWildcard("arn:aws:s3:::te?t/*.txt", "arn:aws:s3:::test/a/b.txt"))
+	require.False(t, matchWildcard("arn:aws:s3:::test/*", "arn:aws:s3:::test"))
+	require.False(t, matchWildcard("arn:aws:s3:::te?t", "arn:aws:s3:::tet"))
+	require.True(t, matchWildcard("arn:aws:s3:::t?st/é?.txt", "arn:aws:s3:::test/éa.txt"))
+	// runs of * do not backtrack exponentially
+	require.False(t, matchWildcard(strings.Repeat("*", 24)+"b", strings.Repeat("a", 48)))
+	require.True(t, matchWildcard("**a*?*b", strings.Repeat("a", 47)+"b"))
+}
+
+func TestIAM(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test", "other"), WithServerConfig(func(cfg *ServerConfig) {
+		cfg.Domain = "s3.local"
+	}))
+	ctx := context.Background()
+	iam := server.GetMS().GetIAM()
+	require.False(t, iam.Enabled())
+
+	require.ErrorIs(t, iam.AddUser("uploader", "uploader-secret", "uploads"), ErrPolicyNotExists)
+	require.NoError(t, iam.SetPolicy("uploads", []byte(uploaderPolicy)))
+	require.NoError(t, iam.AddUser("uploader", "uploader-secret", "uploads"))
+	require.Equal(t, "InvalidArgument", ToAPIError(iam.AddUser(server.Access, "uploader-secret")).Code)
+	require.True(t, iam.Enabled())
+
+	newClient := func(access, secret string) *minio.Client {
+		client, err := minio.New(server.Endpoint, &minio.Options{
+			Creds: credentials.NewStaticV4(access, secret, ""),
+		})
+		require.NoError(t, err)
+		return client
+	}
+	client := newClient("uploader", "uploader-secret")
+	put := func(client *minio.Client, bucket, object string) error {
+		content := `hello world`
+		_, err := client.PutObject(ctx, bucket, object, strings.NewReader(content),
+			int64(len(content)), minio.PutObjectOptions{})
+		return err
+	}
+	requireCode := func(code string, err error) {
+		t.Helper()
+		require.Equal(t, code, minio.ToErrorResponse(err).Code)
+	}
+
+	// the root credentials are allowed everything
+	server.NoError(put(server.Client, "other", "hello.txt"))
+	server.NoError(put(server.Client, "test", "uploads/private/secret.txt"))
+
+	// users are allowed what their policies allow and do not deny
+	server.NoError(put(client, "test", "uploads/hello.txt"))
+	_, err := client.StatObject(ctx, "test", "uploads/hello.txt", minio.StatObjectOptions{})
+	server.NoError(err)
+	ok, err := client.BucketExists(ctx, "test")
+	server.NoError(err)
+	require.True(t, ok)
+	_, err = client.BucketExists(ctx, "other")
+	requireCode("AccessDenied", err)
+	requireCode("AccessDenied", put(client, "test", "hello.txt"))
+	requireCode("AccessDenied", put(client, "other", "uploads/hello.txt"))
+	_, err = client.StatObject(ctx, "test", "uploads/private/secret.txt", minio.StatObjectOptions{})
+	requireCode("AccessDenied", err)
+	requireCode("AccessDenied", client.RemoveObject(ctx, "test", "uploads/hello.txt", minio.RemoveObjectOptions{}))
+	_, err = client.ListBuckets(ctx)
+	requireCode("AccessDenied", err)
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpDeleteObject, Status: http.StatusForbidden}, 1)
+
+	// copies need to read the source as well
+	_, err = client.CopyObject(ctx, minio.CopyDestOptions{Bucket: "test", Object: "uploads/copy.txt"},
+		minio.CopySrcOptions{Bucket: "other", Object: "hello.txt"})
+	requireCode("AccessDenied", err)
+	_, err = client.CopyObject(ctx, minio.CopyDestOptions{Bucket: "test", Object: "uploads/copy.txt"},
+		minio.CopySrcOptions{Bucket: "test", Object: "uploads/hello.txt"})
+	server.NoError(err)
+
+	// presigned requests are verified as well
+	u, err := client.PresignedGetObject(ctx, "test", "uploads/hello.txt", time.Minute, nil)
+	require.NoError(t, err)
+	rsp, err := server.HTTPClient.Get(u.String())
+	require.NoError(t, err)
+	data, err := io.ReadAll(rsp.Body)
+	_ = rsp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(data))
+	rsp, err = server.HTTPClient.Get(strings.Replace(u.String(), "hello.txt", "hello.txt2", 1))
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusForbidden, rsp.StatusCode)
+
+	// virtual-hosted-style requests are signed for the path sent by the client
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, server.Endpoint)
+		},
+	}
+	t.Cleanup(transport.CloseIdleConnections)
+	_, port, err := net.SplitHostPort(server.Endpoint)
+	require.NoError(t, err)
+	vhost, err := minio.New("s3.local:"+port, &minio.Options{
+		Creds:        credentials.NewStaticV4("uploader", "uploader-secret", ""),
+		Transport:    transport,
+		BucketLookup: minio.BucketLookupDNS,
+	})
+	require.NoError(t, err)
+	_, err = vhost.StatObject(ctx, "test", "uploads/hello.txt", minio.StatObjectOptions{})
+	server.NoError(err)
+
+	// unknown, anonymous, badly signed and disabled requests are rejected
+	_, err = newClient("unknown", "unknown-secret").StatObject(ctx, "test", "uploads/hello.txt", minio.StatObjectOptions{})
+	requireCode("InvalidAccessKeyId", err)
+	_, err = newClient("uploader", "wrong-secret").StatObject(ctx, "test", "uploads/hello.txt", minio.StatObjectOptions{})
+	requireCode("SignatureDoesNotMatch", err)
+	rsp, err = server.HTTPClient.Get("http://" + server.Endpoint + "/test/uploads/hello.txt")
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusForbidden, rsp.StatusCode)
+
+	require.NoError(t, iam.SetUserEnabled("uploader", false))
+	_, err = client.StatObject(ctx, "test", "uploads/hello.txt", minio.StatObjectOptions{})
+	requireCode("AccessDenied", err)
+	require.NoError(t, iam.SetUserEnabled("uploader", true))
+
+	// policies are evaluated on every request
+	require.NoError(t, iam.DetachPolicy("uploader", "uploads"))
+	_, err = client.StatObject(ctx, "test", "uploads/hello.txt", minio.StatObjectOptions{})
+	requireCode("AccessDenied", err)
+	require.NoError(t, iam.SetPolicy("read", []byte(`{"Statement": [
+		{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::*"}
+	]}`)))
+	require.NoError(t, iam.AttachPolicy("uploader", "read"))
+	_, err = client.StatObject(ctx, "test", "uploads/hello.txt", minio.StatObjectOptions{})
+	server.NoError(err)
+	user, ok := iam.GetUser("uploader")
+	require.True(t, ok)
+	require.Equal(t, []string{"read"}, user.Policies)
+
+	// without users the server is open again
+	require.NoError(t, iam.RemoveUser("uploader"))
+	require.ErrorIs(t, iam.RemoveUser("uploader"), ErrUserNotExists)
+	require.Empty(t, iam.ListUsers())
+	rsp, err = server.HTTPClient.Get("http://" + server.Endpoint + "/test/uploads/hello.txt")
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+}
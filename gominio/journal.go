@@ -0,0 +1,202 @@
+package gominio
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JournalEntry is a request recorded by the journal
+type JournalEntry struct {
+	RequestID string
+	Operation string
+	Method    string
+	Bucket    string
+	Key       string
+	Query     url.Values
+	Header    http.Header
+	Status    int
+	BytesIn   int64
+	BytesOut  int64
+	Duration  time.Duration
+	Time      time.Time
+}
+
+// JournalFilter selects journal entries, zero value fields match every entry
+type JournalFilter struct {
+	Operation string
+	Method    string
+	Bucket    string
+	Key       string
+	KeyPrefix string
+	Status    int
+}
+
+func (f JournalFilter) match(e *JournalEntry) bool {
+	switch {
+	case f.Operation != "" && f.Operation != e.Operation:
+		return false
+	case f.Method != "" && f.Method != e.Method:
+		return false
+	case f.Bucket != "" && f.Bucket != e.Bucket:
+		return false
+	case f.Key != "" && f.Key != e.Key:
+		return false
+	case f.KeyPrefix != "" && !strings.HasPrefix(e.Key, f.KeyPrefix):
+		return false
+	case f.Status != 0 && f.Status != e.Status:
+		return false
+	}
+	return true
+}
+
+// Journal records every request served by an ApiServer
+type Journal struct {
+	sync.RWMutex
+	entries []JournalEntry
+}
+
+func NewJournal() *Journal {
+	return &Journal{}
+}
+
+// Record append an entry to the journal
+func (j *Journal) Record(e JournalEntry) {
+	j.Lock()
+	defer j.Unlock()
+
+	j.entries = append(j.entries, e)
+}
+
+// Entries returns a copy of all recorded entries in arrival order
+func (j *Journal) Entries() []JournalEntry {
+	return j.Filter(JournalFilter{})
+}
+
+// Filter returns the entries matching f in arrival order
+func (j *Journal) Filter(f JournalFilter) []JournalEntry {
+	j.RLock()
+	defer j.RUnlock()
+
+	var entries []JournalEntry
+	for i := range j.entries {
+		if f.match(&j.entries[i]) {
+			entries = append(entries, j.entries[i])
+		}
+	}
+	return entries
+}
+
+// Count returns the number of entries matching f
+func (j *Journal) Count(f JournalFilter) int {
+	j.RLock()
+	defer j.RUnlock()
+
+	var n int
+	for i := range j.entries {
+		if f.match(&j.entries[i]) {
+			n++
+		}
+	}
+	return n
+}
+
+// Counts returns the number of recorded requests per operation
+func (j *Journal) Counts() map[string]int {
+	j.RLock()
+	defer j.RUnlock()
+
+	counts := make(map[string]int)
+	for i := range j.entries {
+		counts[j.entries[i].Operation]++
+	}
+	return counts
+}
+
+// Reset drops all recorded entries
+func (j *Journal) Reset() {
+	j.Lock()
+	defer j.Unlock()
+
+	j.entries = nil
+}
+
+// TestingT is the subset of testing.TB the journal assertions report failures to
+type TestingT interface {
+	Errorf(format string, args ...any)
+}
+
+// AssertCalled asserts that operation op was requested exactly times times
+func (j *Journal) AssertCalled(t TestingT, op string, times int, msgAndArgs ...any) bool {
+	return j.AssertCount(t, JournalFilter{Operation: op}, times, msgAndArgs...)
+}
+
+// AssertNotCalled asserts that operation op was never requested
+func (j *Journal) AssertNotCalled(t TestingT, op string, msgAndArgs ...any) bool {
+	return j.AssertCount(t, JournalFilter{Operation: op}, 0, msgAndArgs...)
+}
+
+// AssertCount asserts that exactly n recorded entries match f
+func (j *Journal) AssertCount(t TestingT, f JournalFilter, n int, msgAndArgs ...any) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	if got := j.Count(f); got != n {
+		msg := fmt.Sprintf("expected %d request(s) matching %+v, got %d\nrecorded: %v", n, f, got, j.Counts())
+		if len(msgAndArgs) > 0 {
+			if format, ok := msgAndArgs[0].(string); ok {
+				msg += "\n" + fmt.Sprintf(format, msgAndArgs[1:]...)
+			}
+		}
+		t.Errorf("%s", msg)
+		return false
+	}
+	return true
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// journalMiddleware records the served request once the handler returns
+func (api *ApiServer) journalMiddleware(ctx *gin.Context) {
+	start := time.Now()
+	body := &countingReader{ReadCloser: ctx.Request.Body}
+	if ctx.Request.Body != nil {
+		ctx.Request.Body = body
+	}
+
+	ctx.Next()
+
+	bytesOut := int64(ctx.Writer.Size())
+	if bytesOut < 0 {
+		bytesOut = 0
+	}
+	api.journal.Record(JournalEntry{
+		RequestID: ctx.Writer.Header().Get(amzRequestID),
+		Operation: GetOperation(ctx),
+		Method:    ctx.Request.Method,
+		Bucket:    ctx.Param("bucket"),
+		Key:       ctx.Param("object"),
+		Query:     ctx.Request.URL.Query(),
+		Header:    ctx.Request.Header.Clone(),
+		Status:    ctx.Writer.Status(),
+		BytesIn:   body.n,
+		BytesOut:  bytesOut,
+		Duration:  time.Since(start),
+		Time:      start,
+	})
+}
@@ -0,0 +1,70 @@
+package gominio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	journal := server.GetJournal()
+
+	content := bytes.Repeat([]byte("a"), 12<<20)
+	_, err := server.Client.PutObject(context.Background(), "test", "big.bin",
+		bytes.NewReader(content), int64(len(content)), minio.PutObjectOptions{PartSize: 5 << 20})
+	server.NoError(err)
+
+	journal.AssertCalled(t, OpCreateMultipartUpload, 1)
+	journal.AssertCalled(t, OpUploadPart, 3)
+	journal.AssertCalled(t, OpCompleteMultipartUpload, 1)
+	journal.AssertNotCalled(t, OpDeleteObject)
+
+	// failed assertions report the recorded operations to t
+	failed := &recordingT{}
+	require.False(t, journal.AssertCalled(failed, OpDeleteObject, 1, "delete %s", "big.bin"))
+	require.Len(t, failed.errors, 1)
+	require.Contains(t, failed.errors[0], "expected 1 request(s) matching")
+	require.Contains(t, failed.errors[0], "UploadPart:3")
+	require.Contains(t, failed.errors[0], "delete big.bin")
+
+	parts := journal.Filter(JournalFilter{Operation: OpUploadPart, Bucket: "test", Key: "big.bin"})
+	require.Len(t, parts, 3)
+	var total int64
+	for _, e := range parts {
+		require.Equal(t, 200, e.Status)
+		total += e.BytesIn
+	}
+	require.GreaterOrEqual(t, total, int64(len(content)))
+
+	journal.Reset()
+	require.Empty(t, journal.Entries())
+
+	_, err = server.Client.StatObject(context.Background(), "test", "big.bin", minio.StatObjectOptions{})
+	server.NoError(err)
+	require.Equal(t, map[string]int{OpHeadObject: 1}, journal.Counts())
+
+	// part copies are not implemented and not journaled as uploaded parts
+	journal.Reset()
+	req, err := http.NewRequest(http.MethodPut, "/test/copy.bin?partNumber=1&uploadId=upload", nil)
+	require.NoError(t, err)
+	req.Header.Set(amzCopySource, "/test/big.bin")
+	rsp, err := server.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, rsp.Body.Close())
+	require.Equal(t, http.StatusNotImplemented, rsp.StatusCode)
+	require.Equal(t, map[string]int{OpUploadPartCopy: 1}, journal.Counts())
+}
+
+// recordingT records the failures reported to it
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
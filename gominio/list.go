@@ -0,0 +1,250 @@
+package gominio
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxListKeys is the maximum number of keys listed by one ListObjects request
+const maxListKeys = 1000
+
+// ObjectListing is a page of the objects of a bucket
+type ObjectListing struct {
+	Objects []*ObjectInfo
+	// Prefixes are the common prefixes of the keys grouped by the delimiter
+	Prefixes    []string
+	IsTruncated bool
+	// NextMarker is the last key or common prefix of a truncated page
+	NextMarker string
+}
+
+// ListObjects lists up to maxKeys objects of a bucket whose key starts with prefix and follows
+// marker in lexical order, the keys containing delimiter after the prefix are grouped in a
+// common prefix ending with the delimiter
+func (ms *MinioServer) ListObjects(bucket, prefix, delimiter, marker string, maxKeys int) (*ObjectListing, error) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return nil, ErrBucketNotExists
+	}
+	keys := make([]string, 0, len(bd.Objects))
+	for key := range bd.Objects {
+		if strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	listing := new(ObjectListing)
+	count := 0
+	for _, key := range keys {
+		commonPrefix := ""
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				commonPrefix = key[:len(prefix)+i+len(delimiter)]
+				// the prefix was listed by the previous page when it is the marker, or by this one
+				if commonPrefix == marker || listing.lastPrefix() == commonPrefix {
+					continue
+				}
+			}
+		}
+		if count == maxKeys {
+			listing.IsTruncated = true
+			break
+		}
+		count++
+		if commonPrefix != "" {
+			listing.Prefixes = append(listing.Prefixes, commonPrefix)
+			listing.NextMarker = commonPrefix
+			continue
+		}
+		listing.Objects = append(listing.Objects, bd.Objects[key])
+		listing.NextMarker = key
+	}
+	if !listing.IsTruncated {
+		listing.NextMarker = ""
+	}
+	return listing, nil
+}
+
+func (l *ObjectListing) lastPrefix() string {
+	if len(l.Prefixes) == 0 {
+		return ""
+	}
+	return l.Prefixes[len(l.Prefixes)-1]
+}
+
+type ListObjectsContent struct {
+	Key          string
+	LastModified time.Time
+	ETag         string
+	Size         uint64
+	StorageClass string
+}
+
+type CommonPrefix struct {
+	Prefix string
+}
+
+type ListBucketResult struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult" json:"-"`
+
+	Name           string
+	Prefix         string
+	Marker         string
+	NextMarker     string `xml:",omitempty"`
+	MaxKeys        int
+	Delimiter      string `xml:",omitempty"`
+	IsTruncated    bool
+	EncodingType   string `xml:",omitempty"`
+	Contents       []ListObjectsContent
+	CommonPrefixes []CommonPrefix
+}
+
+func (lr ListBucketResult) Encode() []byte {
+	return encodeAny(lr)
+}
+
+type ListBucketV2Result struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult" json:"-"`
+
+	Name                  string
+	Prefix                string
+	StartAfter            string `xml:",omitempty"`
+	ContinuationToken     string `xml:",omitempty"`
+	NextContinuationToken string `xml:",omitempty"`
+	KeyCount              int
+	MaxKeys               int
+	Delimiter             string `xml:",omitempty"`
+	IsTruncated           bool
+	EncodingType          string `xml:",omitempty"`
+	Contents              []ListObjectsContent
+	CommonPrefixes        []CommonPrefix
+}
+
+func (lr ListBucketV2Result) Encode() []byte {
+	return encodeAny(lr)
+}
+
+// listObjects serves ListObjects and ListObjectsV2 (list-type=2), the continuation token of V2
+// is the base64 encoded key the next page starts after
+func (api *ApiServer) listObjects(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	invalid := func(description string) {
+		apiErr := ErrInvalidArgument
+		apiErr.Description = description
+		ErrResponse(ctx, "", bucket, apiErr)
+	}
+
+	maxKeys := maxListKeys
+	if value, ok := ctx.GetQuery("max-keys"); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			invalid("Provided max-keys not an integer or within integer range")
+			return
+		}
+		if n < maxKeys {
+			maxKeys = n
+		}
+	}
+	encodingType := ctx.Query("encoding-type")
+	if encodingType != "" && !strings.EqualFold(encodingType, "url") {
+		invalid("Invalid Encoding Method specified in Request")
+		return
+	}
+	encode := func(s string) string {
+		if encodingType == "" {
+			return s
+		}
+		return url.QueryEscape(s)
+	}
+
+	prefix, delimiter := ctx.Query("prefix"), ctx.Query("delimiter")
+	v2 := ctx.Query("list-type") == "2"
+	marker := ctx.Query("marker")
+	token, hasToken := ctx.GetQuery("continuation-token")
+	if v2 {
+		marker = ctx.Query("start-after")
+		if hasToken {
+			decoded, err := base64.StdEncoding.DecodeString(token)
+			if err != nil {
+				invalid("The continuation token provided is incorrect")
+				return
+			}
+			marker = string(decoded)
+		}
+	}
+
+	listing, err := api.GetMS().ListObjects(bucket, prefix, delimiter, marker, maxKeys)
+	if err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+	var contents []ListObjectsContent
+	for _, oi := range listing.Objects {
+		contents = append(contents, ListObjectsContent{
+			Key:          encode(oi.Name),
+			LastModified: oi.LastModified.UTC(),
+			ETag:         "\"" + oi.Etag + "\"",
+			Size:         oi.Size,
+			StorageClass: oi.GetStorageClass(),
+		})
+	}
+	var prefixes []CommonPrefix
+	for _, p := range listing.Prefixes {
+		prefixes = append(prefixes, CommonPrefix{Prefix: encode(p)})
+	}
+
+	if !v2 {
+		var nextMarker string
+		// S3 only returns the next marker of truncated pages listed with a delimiter
+		if delimiter != "" {
+			nextMarker = encode(listing.NextMarker)
+		}
+		SuccessResponse(ctx, http.StatusOK, ListBucketResult{
+			Name:           bucket,
+			Prefix:         encode(prefix),
+			Marker:         encode(marker),
+			NextMarker:     nextMarker,
+			MaxKeys:        maxKeys,
+			Delimiter:      encode(delimiter),
+			IsTruncated:    listing.IsTruncated,
+			EncodingType:   encodingType,
+			Contents:       contents,
+			CommonPrefixes: prefixes,
+		}.Encode())
+		return
+	}
+
+	var next string
+	if listing.IsTruncated {
+		next = base64.StdEncoding.EncodeToString([]byte(listing.NextMarker))
+	}
+	var startAfter string
+	if !hasToken {
+		startAfter = encode(marker)
+	}
+	SuccessResponse(ctx, http.StatusOK, ListBucketV2Result{
+		Name:                  bucket,
+		Prefix:                encode(prefix),
+		StartAfter:            startAfter,
+		ContinuationToken:     token,
+		NextContinuationToken: next,
+		KeyCount:              len(contents) + len(prefixes),
+		MaxKeys:               maxKeys,
+		Delimiter:             encode(delimiter),
+		IsTruncated:           listing.IsTruncated,
+		EncodingType:          encodingType,
+		Contents:              contents,
+		CommonPrefixes:        prefixes,
+	}.Encode())
+}
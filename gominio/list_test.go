@@ -0,0 +1,102 @@
+package gominio
+
+import (
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestListObjects(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test", "empty"))
+	ctx := context.Background()
+	put := func(object, class string) {
+		_, err := server.Client.PutObject(ctx, "test", object, strings.NewReader(object), int64(len(object)),
+			minio.PutObjectOptions{StorageClass: class})
+		require.NoError(t, err)
+	}
+	put("a.txt", "")
+	put("dir/b.txt", StorageClassReducedRedundancy)
+	put("dir/c.txt", "")
+	put("dir/sub/d.txt", StorageClassStandardIA)
+	put("e f.txt", "")
+
+	list := func(bucket string, opts minio.ListObjectsOptions) map[string]string {
+		listed := make(map[string]string)
+		for info := range server.Client.ListObjects(ctx, bucket, opts) {
+			require.NoError(t, info.Err)
+			listed[info.Key] = info.StorageClass
+		}
+		return listed
+	}
+	all := map[string]string{
+		"a.txt":         StorageClassStandard,
+		"dir/b.txt":     StorageClassReducedRedundancy,
+		"dir/c.txt":     StorageClassStandard,
+		"dir/sub/d.txt": StorageClassStandardIA,
+		"e f.txt":       StorageClassStandard,
+	}
+	// pages of 2 keys are followed by the continuation token or the marker of V1
+	require.Equal(t, all, list("test", minio.ListObjectsOptions{Recursive: true, MaxKeys: 2}))
+	require.Equal(t, all, list("test", minio.ListObjectsOptions{Recursive: true, MaxKeys: 2, UseV1: true}))
+	require.Equal(t, map[string]string{
+		"a.txt":   StorageClassStandard,
+		"dir/":    "",
+		"e f.txt": StorageClassStandard,
+	}, list("test", minio.ListObjectsOptions{UseV1: true}))
+	require.Equal(t, map[string]string{
+		"dir/b.txt": StorageClassReducedRedundancy,
+		"dir/c.txt": StorageClassStandard,
+		"dir/sub/":  "",
+	}, list("test", minio.ListObjectsOptions{Prefix: "dir/", MaxKeys: 1}))
+	require.Equal(t, map[string]string{
+		"dir/c.txt":     StorageClassStandard,
+		"dir/sub/d.txt": StorageClassStandardIA,
+	}, list("test", minio.ListObjectsOptions{Prefix: "dir/", StartAfter: "dir/b.txt", Recursive: true}))
+	// a marker inside a common prefix lists the prefix of the keys following it
+	require.Equal(t, map[string]string{
+		"dir/":    "",
+		"e f.txt": StorageClassStandard,
+	}, list("test", minio.ListObjectsOptions{StartAfter: "dir/b.txt"}))
+	require.Empty(t, list("empty", minio.ListObjectsOptions{}))
+	for info := range server.Client.ListObjects(ctx, "missing", minio.ListObjectsOptions{}) {
+		require.Equal(t, "NoSuchBucket", minio.ToErrorResponse(info.Err).Code)
+	}
+
+	do := func(path string) (int, string) {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+		rsp, err := server.Do(req)
+		require.NoError(t, err)
+		data, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		require.NoError(t, rsp.Body.Close())
+		return rsp.StatusCode, string(data)
+	}
+	status, body := do("/test?list-type=2&encoding-type=url&prefix=e")
+	require.Equal(t, http.StatusOK, status)
+	require.Contains(t, body, "<Key>e+f.txt</Key>")
+	require.Contains(t, body, "<EncodingType>url</EncodingType>")
+	require.Contains(t, body, "<KeyCount>1</KeyCount>")
+	status, body = do("/test?max-keys=1&delimiter=/")
+	require.Equal(t, http.StatusOK, status)
+	require.Contains(t, body, "<IsTruncated>true</IsTruncated>")
+	require.Contains(t, body, "<NextMarker>a.txt</NextMarker>")
+	for _, path := range []string{
+		"/test?max-keys=-1",
+		"/test?encoding-type=base64",
+		"/test?list-type=2&continuation-token=%25",
+	} {
+		status, body = do(path)
+		require.Equal(t, http.StatusBadRequest, status, path)
+		require.Contains(t, body, "<Code>InvalidArgument</Code>", path)
+	}
+	status, body = do("/missing?list-type=2")
+	require.Equal(t, http.StatusNotFound, status)
+	require.Contains(t, body, "<Code>NoSuchBucket</Code>")
+
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpListObjects, Status: http.StatusOK}, 15)
+}
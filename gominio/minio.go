@@ -3,18 +3,30 @@ package gominio
 import (
 	"bytes"
 	"encoding/xml"
+	"fmt"
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/minio/minio-go/v7/pkg/replication"
 	"github.com/minio/minio-go/v7/pkg/tags"
 	"io"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+const defaultRegion = "us-east-1"
+
 func NewMinioServer(access, secret string) *MinioServer {
 	minio := &MinioServer{
 		Access:  access,
 		Secret:  secret,
 		Buckets: make(map[string]*BucketData),
 	}
+	minio.events = newEventBus(minio)
+	minio.hooks = newHooks()
+	minio.hooks.add(hookEntry{after: minio.notifyHook, builtin: true})
+	minio.iam = newIAM(minio)
+	minio.replicator = newReplicator(minio)
 	return minio
 }
 
@@ -23,18 +35,42 @@ type MinioServer struct {
 	Access  string
 	Secret  string
 	Buckets map[string]*BucketData
+
+	// Region is the region of the server, requests must be signed for it when it is set
+	Region string
+	// LenientNames accepts bucket names MinIO accepts but S3 rejects, like uppercase names
+	LenientNames bool
+	// MinPartSize is the minimum size of the non-final parts of multipart uploads, 5 MiB when zero
+	MinPartSize int64
+	// RestoreDelay is the time restoring an archived object takes, restores complete at once when zero
+	RestoreDelay time.Duration
+	// Clock returns the current time, time.Now when nil. Tests may advance it to complete restores.
+	Clock func() time.Time
+
+	events     *EventBus
+	hooks      *Hooks
+	iam        *IAM
+	replicator *Replicator
 }
 
 type BucketData struct {
 	Info    BucketInfo
 	Objects map[string]*ObjectInfo
+	Uploads map[string]*ObjectInfo
 }
 
 type BucketInfo struct {
-	Quota   uint64
-	Used    uint64
-	Policy  string
-	Created time.Time
+	Quota    uint64
+	Used     uint64
+	Policy   string
+	Location string
+	Created  time.Time
+	Tags     *tags.Tags
+
+	Notification *notification.Configuration
+	CORS         *CORSConfiguration
+	Replication  *replication.Config
+	Website      *WebsiteConfiguration
 }
 
 type ObjectInfo struct {
@@ -43,17 +79,110 @@ type ObjectInfo struct {
 	Etag string
 	Data []byte
 	Tags *tags.Tags
+	// Checksum is the verified checksum of the object, it only has an algorithm while the
+	// multipart upload is in progress
+	Checksum *Checksum
+	// StorageClass is one of the StorageClass constants, STANDARD when empty
+	StorageClass string
+	// Restore is the restored copy of an archived object, nil until RestoreObject is called
+	Restore *ObjectRestore
+	// ReplicationStatus is one of the Replication constants, empty when the object is not replicated
+	ReplicationStatus string
 
 	IsMultipart bool
 	UploadId    string
 	Parts       map[int]Multipart
+	// ObjectParts are the parts of a completed multipart object in order, nil for other objects
+	ObjectParts []ObjectPart
 
 	LastModified time.Time
 }
 
 type Multipart struct {
-	Etag string
-	Data []byte
+	Etag     string
+	Data     []byte
+	Checksum *Checksum
+}
+
+// ObjectPart is the part of a completed multipart object, the part data is Size bytes of the
+// object data following the previous parts
+type ObjectPart struct {
+	PartNumber int
+	Etag       string
+	Size       int64
+	Checksum   *Checksum
+}
+
+// Dump returns a human readable snapshot of all buckets and objects, used to diagnose failing tests
+func (ms *MinioServer) Dump() string {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	var sb strings.Builder
+	buckets := make([]string, 0, len(ms.Buckets))
+	for bk := range ms.Buckets {
+		buckets = append(buckets, bk)
+	}
+	sort.Strings(buckets)
+
+	fmt.Fprintf(&sb, "minio server: %d bucket(s)\n", len(buckets))
+	for _, bk := range buckets {
+		bd := ms.Buckets[bk]
+		fmt.Fprintf(&sb, "bucket %q location=%s created=%s objects=%d\n",
+			bk, bd.Info.Location, bd.Info.Created.Format(time.RFC3339), len(bd.Objects))
+		if bd.Info.Tags != nil && bd.Info.Tags.String() != "" {
+			fmt.Fprintf(&sb, "  tags=%q\n", bd.Info.Tags.String())
+		}
+
+		objects := make([]string, 0, len(bd.Objects))
+		for name := range bd.Objects {
+			objects = append(objects, name)
+		}
+		sort.Strings(objects)
+		for _, name := range objects {
+			oi := bd.Objects[name]
+			fmt.Fprintf(&sb, "  object %q size=%d etag=%q", name, oi.Size, oi.Etag)
+			if oi.Tags != nil && oi.Tags.String() != "" {
+				fmt.Fprintf(&sb, " tags=%q", oi.Tags.String())
+			}
+			sb.WriteString("\n")
+		}
+
+		uploads := make([]string, 0, len(bd.Uploads))
+		for id := range bd.Uploads {
+			uploads = append(uploads, id)
+		}
+		sort.Strings(uploads)
+		for _, id := range uploads {
+			oi := bd.Uploads[id]
+			fmt.Fprintf(&sb, "  upload %q object %q parts=%d\n", id, oi.Name, len(oi.Parts))
+		}
+	}
+	return sb.String()
+}
+
+// GetRegion returns the region of the server, us-east-1 when it is not set
+func (ms *MinioServer) GetRegion() string {
+	if ms.Region == "" {
+		return defaultRegion
+	}
+	return ms.Region
+}
+
+// now returns the current time of the server clock
+func (ms *MinioServer) now() time.Time {
+	if ms.Clock != nil {
+		return ms.Clock()
+	}
+	return time.Now()
+}
+
+// GetMinPartSize returns the minimum size of the non-final parts of multipart uploads
+func (ms *MinioServer) GetMinPartSize() int64 {
+	if ms.MinPartSize <= 0 {
+		return defaultMinPartSize
+	}
+	return ms.MinPartSize
 }
 
 func encodeAny(v any) []byte {
@@ -67,9 +196,6 @@ func encodeAny(v any) []byte {
 	return bytesBuffer.Bytes()
 }
 
-func decodeAny(r io.Reader, v any) {
-	err := xml.NewDecoder(r).Decode(v)
-	if err != nil {
-		return
-	}
+func decodeAny(r io.Reader, v any) error {
+	return xml.NewDecoder(r).Decode(v)
 }
@@ -0,0 +1,102 @@
+package gominio
+
+import (
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMultipartUpload(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"), WithServerConfig(func(cfg *ServerConfig) {
+		cfg.MinPartSize = 8
+	}))
+	core := minio.Core{Client: server.Client}
+	ctx := context.Background()
+
+	putPart := func(id string, num int, content string) minio.CompletePart {
+		part, err := core.PutObjectPart(ctx, "test", "big.txt", id, num,
+			strings.NewReader(content), int64(len(content)), minio.PutObjectPartOptions{})
+		server.NoError(err, "put part %d", num)
+		return minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag}
+	}
+
+	id, err := core.NewMultipartUpload(ctx, "test", "big.txt", minio.PutObjectOptions{})
+	server.NoError(err)
+	part1 := putPart(id, 1, "hello wo")
+	part2 := putPart(id, 2, "rld")
+	short := putPart(id, 3, "!")
+
+	// part numbers are limited to 1-10000
+	_, err = core.PutObjectPart(ctx, "test", "big.txt", id, 10001,
+		strings.NewReader("x"), 1, minio.PutObjectPartOptions{})
+	require.Equal(t, "InvalidArgument", minio.ToErrorResponse(err).Code)
+
+	_, err = core.CompleteMultipartUpload(ctx, "test", "big.txt", id,
+		[]minio.CompletePart{part2, part1}, minio.PutObjectOptions{})
+	require.Equal(t, "InvalidPartOrder", minio.ToErrorResponse(err).Code)
+
+	_, err = core.CompleteMultipartUpload(ctx, "test", "big.txt", id,
+		[]minio.CompletePart{part1, part1}, minio.PutObjectOptions{})
+	require.Equal(t, "InvalidPartOrder", minio.ToErrorResponse(err).Code)
+
+	_, err = core.CompleteMultipartUpload(ctx, "test", "big.txt", id,
+		[]minio.CompletePart{part1, part2, short}, minio.PutObjectOptions{})
+	require.Equal(t, "EntityTooSmall", minio.ToErrorResponse(err).Code)
+
+	_, err = core.CompleteMultipartUpload(ctx, "test", "big.txt", id,
+		[]minio.CompletePart{part1, {PartNumber: 2, ETag: "wrong"}}, minio.PutObjectOptions{})
+	require.Equal(t, "InvalidPart", minio.ToErrorResponse(err).Code)
+
+	// the upload is not visible as an object until it is completed
+	_, err = server.Client.StatObject(ctx, "test", "big.txt", minio.StatObjectOptions{})
+	require.Equal(t, "NoSuchKey", minio.ToErrorResponse(err).Code)
+
+	_, err = core.CompleteMultipartUpload(ctx, "test", "big.txt", id,
+		[]minio.CompletePart{part1, part2}, minio.PutObjectOptions{})
+	server.NoError(err)
+
+	oi, err := server.Client.GetObject(ctx, "test", "big.txt", minio.GetObjectOptions{})
+	require.NoError(t, err)
+	data, err := io.ReadAll(oi)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(data))
+
+	_, err = core.CompleteMultipartUpload(ctx, "test", "big.txt", id,
+		[]minio.CompletePart{part1, part2}, minio.PutObjectOptions{})
+	require.Equal(t, "NoSuchUpload", minio.ToErrorResponse(err).Code)
+}
+
+func TestAbortMultipartUpload(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	core := minio.Core{Client: server.Client}
+	ctx := context.Background()
+
+	content := `hello world`
+	_, err := server.Client.PutObject(ctx, "test", "hello.txt",
+		strings.NewReader(content), int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+
+	// uploading to an existing key keeps the object until the upload completes
+	id, err := core.NewMultipartUpload(ctx, "test", "hello.txt", minio.PutObjectOptions{})
+	server.NoError(err)
+	_, err = core.PutObjectPart(ctx, "test", "hello.txt", id, 1,
+		strings.NewReader("new"), 3, minio.PutObjectPartOptions{})
+	server.NoError(err)
+	require.Contains(t, server.GetMS().Dump(), `upload "`+id+`" object "hello.txt" parts=1`)
+
+	server.NoError(core.AbortMultipartUpload(ctx, "test", "hello.txt", id))
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpAbortMultipartUpload}, 1)
+	require.NotContains(t, server.GetMS().Dump(), "upload")
+
+	err = core.AbortMultipartUpload(ctx, "test", "hello.txt", id)
+	require.Equal(t, "NoSuchUpload", minio.ToErrorResponse(err).Code)
+
+	oi, err := server.Client.GetObject(ctx, "test", "hello.txt", minio.GetObjectOptions{})
+	require.NoError(t, err)
+	data, err := io.ReadAll(oi)
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+}
@@ -2,164 +2,518 @@ package gominio
 
 import (
 	"encoding/xml"
-	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7/pkg/tags"
 	"io"
-	"sort"
+	"strings"
 	"time"
 )
 
 // PutObjectTagging put object tagging
 func (ms *MinioServer) PutObjectTagging(bucket, object string, tags *tags.Tags) error {
+	info := &HookInfo{Operation: OpPutObjectTagging, Bucket: bucket, Object: object, Tags: tags}
+	return ms.hooks.run(info, func() error {
+		var err error
+		info.changed, err = ms.putObjectTagging(bucket, object, tags)
+		return err
+	})
+}
+
+func (ms *MinioServer) putObjectTagging(bucket, object string, tags *tags.Tags) (*ObjectInfo, error) {
 	ms.Lock()
 	defer ms.Unlock()
 
 	oi, err := ms.getObjectInfo(bucket, object)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	oi = ms.replaceObject(bucket, oi)
 	oi.Tags = tags
 
-	return nil
+	return oi, nil
 }
 
 // RemoveObjectTagging remove object tagging
 func (ms *MinioServer) RemoveObjectTagging(bucket, object string) error {
+	info := &HookInfo{Operation: OpDeleteObjectTagging, Bucket: bucket, Object: object}
+	return ms.hooks.run(info, func() error {
+		var err error
+		info.changed, err = ms.removeObjectTagging(bucket, object)
+		return err
+	})
+}
+
+func (ms *MinioServer) removeObjectTagging(bucket, object string) (*ObjectInfo, error) {
 	ms.Lock()
 	defer ms.Unlock()
 
 	oi, err := ms.getObjectInfo(bucket, object)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	tag, err := tags.MapToObjectTags(map[string]string{})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	oi = ms.replaceObject(bucket, oi)
 	oi.Tags = tag
 
-	return nil
+	return oi, nil
+}
+
+// ObjectOptions are the options objects are created with
+type ObjectOptions struct {
+	// Tags the object is tagged with, the object has no tags when it is nil
+	Tags *tags.Tags
+	// Checksum is verified against the object data, the value of a checksum without one is set to
+	// the computed checksum. Multipart uploads only use its algorithm.
+	Checksum *Checksum
+	// StorageClass is one of the StorageClass constants, STANDARD when empty
+	StorageClass string
 }
 
 // PutObject put object
 func (ms *MinioServer) PutObject(bucket, object, etag string, content []byte) error {
+	return ms.PutObjectWithOptions(bucket, object, etag, content, ObjectOptions{})
+}
+
+// PutObjectWithOptions put object created with opts
+func (ms *MinioServer) PutObjectWithOptions(bucket, object, etag string, content []byte, opts ObjectOptions) error {
+	info := &HookInfo{
+		Operation: OpPutObject,
+		Bucket:    bucket,
+		Object:    object,
+		Size:      int64(len(content)),
+		Etag:      etag,
+		Tags:      opts.Tags,
+	}
+	return ms.hooks.run(info, func() error {
+		var err error
+		info.changed, err = ms.putObject(bucket, object, etag, content, opts)
+		return err
+	})
+}
+
+func (ms *MinioServer) putObject(bucket, object, etag string, content []byte, opts ObjectOptions) (*ObjectInfo, error) {
+	if err := CheckObjectName(object); err != nil {
+		return nil, err
+	}
+	if opts.Checksum != nil {
+		if err := opts.Checksum.Verify(content); err != nil {
+			return nil, err
+		}
+	}
+	class, err := ParseStorageClass(opts.StorageClass)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := objectTagsOrEmpty(opts.Tags)
+	if err != nil {
+		return nil, err
+	}
+
 	ms.Lock()
 	defer ms.Unlock()
 
 	var ok bool
 	var bd *BucketData
 	if bd, ok = ms.Buckets[bucket]; !ok {
-		return errors.New("bucket not exists")
+		return nil, ErrBucketNotExists
 	}
-
-	tag, err := tags.MapToObjectTags(map[string]string{})
-	if err != nil {
-		return err
+	if err = bd.checkQuota(object, uint64(len(content))); err != nil {
+		return nil, err
 	}
 
-	bd.Objects[object] = &ObjectInfo{
+	oi := &ObjectInfo{
 		Name:         object,
 		Size:         uint64(len(content)),
 		Etag:         etag,
 		Data:         content,
-		Tags:         tag,
-		LastModified: time.Now(),
+		Tags:         tags,
+		Checksum:     opts.Checksum,
+		StorageClass: class,
+		LastModified: ms.now(),
 	}
-	return nil
+	bd.Objects[object] = oi
+	return oi, nil
 }
 
-// PutObjectPart put object part
-func (ms *MinioServer) PutObjectPart(bucket, object, id, etag string, num int, content []byte) error {
+// CopyObject copy the source object to the destination object created with opts, the tags of the
+// source object are copied as well when opts.Tags is nil. The checksum of the source object is kept.
+func (ms *MinioServer) CopyObject(srcBucket, srcObject, bucket, object, etag string, opts ObjectOptions) (*ObjectInfo, error) {
+	var oi *ObjectInfo
+	info := &HookInfo{
+		Operation: OpCopyObject,
+		Bucket:    bucket,
+		Object:    object,
+		Etag:      etag,
+		Tags:      opts.Tags,
+		SrcBucket: srcBucket,
+		SrcObject: srcObject,
+	}
+	err := ms.hooks.run(info, func() error {
+		var err error
+		if oi, err = ms.copyObject(srcBucket, srcObject, bucket, object, etag, opts); err != nil {
+			return err
+		}
+		info.Size = int64(oi.Size)
+		info.Tags = oi.Tags
+		info.changed = oi
+		return nil
+	})
+	return oi, err
+}
+
+func (ms *MinioServer) copyObject(srcBucket, srcObject, bucket, object, etag string, opts ObjectOptions) (*ObjectInfo, error) {
+	if err := CheckObjectName(object); err != nil {
+		return nil, err
+	}
+	class, err := ParseStorageClass(opts.StorageClass)
+	if err != nil {
+		return nil, err
+	}
+
 	ms.Lock()
 	defer ms.Unlock()
 
-	var ok bool
-	var bd *BucketData
-	if bd, ok = ms.Buckets[bucket]; !ok {
-		return errors.New("bucket not exists")
+	src, err := ms.getObjectInfo(srcBucket, srcObject)
+	if err != nil {
+		return nil, err
+	}
+	if err = src.checkReadable(ms.now()); err != nil {
+		return nil, err
+	}
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return nil, ErrBucketNotExists
 	}
 
-	var oi *ObjectInfo
-	if oi, ok = bd.Objects[object]; !ok {
-		tag, err := tags.MapToObjectTags(map[string]string{})
+	tags := opts.Tags
+	if tags == nil {
+		tags = src.Tags
+	}
+	tags, err = objectTagsOrEmpty(tags)
+	if err != nil {
+		return nil, err
+	}
+	if err = bd.checkQuota(object, src.Size); err != nil {
+		return nil, err
+	}
+
+	oi := &ObjectInfo{
+		Name:         object,
+		Size:         src.Size,
+		Etag:         etag,
+		Data:         src.Data,
+		Tags:         tags,
+		Checksum:     src.Checksum,
+		StorageClass: class,
+		LastModified: ms.now(),
+	}
+	bd.Objects[object] = oi
+	return oi, nil
+}
+
+// InitiateMultipartUpload initiate the multipart upload id, the completed object is created with opts.
+// With a checksum algorithm the checksum of every part is computed with it and the completed object
+// has the composite checksum of the parts.
+func (ms *MinioServer) InitiateMultipartUpload(bucket, object, id string, opts ObjectOptions) error {
+	info := &HookInfo{Operation: OpCreateMultipartUpload, Bucket: bucket, Object: object, UploadID: id, Tags: opts.Tags}
+	return ms.hooks.run(info, func() error {
+		return ms.initiateMultipartUpload(bucket, object, id, opts)
+	})
+}
+
+func (ms *MinioServer) initiateMultipartUpload(bucket, object, id string, opts ObjectOptions) error {
+	if err := CheckObjectName(object); err != nil {
+		return err
+	}
+	tags, err := objectTagsOrEmpty(opts.Tags)
+	if err != nil {
+		return err
+	}
+	var checksum *Checksum
+	if opts.Checksum != nil && opts.Checksum.Algorithm != "" {
+		algorithm, err := ParseChecksumAlgorithm(opts.Checksum.Algorithm)
 		if err != nil {
 			return err
 		}
-		oi = &ObjectInfo{
-			Name:        object,
-			Tags:        tag,
-			IsMultipart: true,
-			UploadId:    id,
-			Parts:       make(map[int]Multipart),
-		}
-		bd.Objects[object] = oi
+		checksum = &Checksum{Algorithm: algorithm}
+	}
+	class, err := ParseStorageClass(opts.StorageClass)
+	if err != nil {
+		return err
+	}
+
+	ms.Lock()
+	defer ms.Unlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return ErrBucketNotExists
+	}
+
+	if _, ok = bd.Uploads[id]; ok {
+		return nil
+	}
+	bd.Uploads[id] = &ObjectInfo{
+		Name:         object,
+		Tags:         tags,
+		Checksum:     checksum,
+		StorageClass: class,
+		IsMultipart:  true,
+		UploadId:     id,
+		Parts:        make(map[int]Multipart),
+	}
+	return nil
+}
+
+func objectTagsOrEmpty(tag *tags.Tags) (*tags.Tags, error) {
+	if tag != nil {
+		return tag, nil
+	}
+	return tags.MapToObjectTags(map[string]string{})
+}
+
+// PutObjectPart put object part, a part number of 0 initiates the multipart upload id
+func (ms *MinioServer) PutObjectPart(bucket, object, id, etag string, num int, content []byte) error {
+	return ms.PutObjectPartWithChecksum(bucket, object, id, etag, num, content, nil)
+}
+
+// PutObjectPartWithChecksum put object part after verifying its checksum, the checksum of the
+// upload algorithm is computed when it is nil
+func (ms *MinioServer) PutObjectPartWithChecksum(bucket, object, id, etag string, num int, content []byte,
+	checksum *Checksum) error {
+	if num == 0 {
+		return ms.InitiateMultipartUpload(bucket, object, id, ObjectOptions{})
+	}
+
+	info := &HookInfo{
+		Operation:  OpUploadPart,
+		Bucket:     bucket,
+		Object:     object,
+		UploadID:   id,
+		PartNumber: num,
+		Size:       int64(len(content)),
+		Etag:       etag,
+	}
+	return ms.hooks.run(info, func() error {
+		return ms.putObjectPart(bucket, object, id, etag, num, content, checksum)
+	})
+}
+
+func (ms *MinioServer) putObjectPart(bucket, object, id, etag string, num int, content []byte, checksum *Checksum) error {
+	if err := CheckObjectName(object); err != nil {
+		return err
+	}
+	if num < 1 || num > maxPartNumber {
+		apiErr := ErrInvalidArgument
+		apiErr.Description = "Part number must be an integer between 1 and 10000, inclusive"
+		return apiErr
+	}
+	if int64(len(content)) > maxPartSize {
+		return ErrEntityTooLarge
 	}
 
-	if num != 0 && etag != "" {
+	ms.Lock()
+	defer ms.Unlock()
+
+	oi, err := ms.getUpload(bucket, object, id)
+	if err != nil {
+		return err
+	}
+	if checksum == nil && oi.Checksum != nil {
+		checksum = &Checksum{Algorithm: oi.Checksum.Algorithm}
+	}
+	if checksum != nil {
+		if oi.Checksum != nil && checksum.Algorithm != oi.Checksum.Algorithm {
+			apiErr := ErrInvalidRequest
+			apiErr.Description = fmt.Sprintf("Checksum Type mismatch occurred, expected checksum Type: %s, actual checksum Type: %s",
+				oi.Checksum.Algorithm, checksum.Algorithm)
+			return apiErr
+		}
+		if err = checksum.Verify(content); err != nil {
+			return err
+		}
+	}
+	if etag != "" {
 		oi.Parts[num] = Multipart{
-			Etag: etag,
-			Data: content,
+			Etag:     etag,
+			Data:     content,
+			Checksum: checksum,
 		}
 	}
 
 	return nil
 }
 
-// CompleteObjectPart merge object parts
+// CompleteObjectPart merge object parts, the parts must be listed in ascending order and every
+// part but the last one must be at least MinPartSize large
 func (ms *MinioServer) CompleteObjectPart(bucket, object, id string, parts *CompleteMultiPart) (string, error) {
+	var etag = GetUid()
+	info := &HookInfo{Operation: OpCompleteMultipartUpload, Bucket: bucket, Object: object, UploadID: id}
+	err := ms.hooks.run(info, func() error {
+		oi, err := ms.completeObjectPart(bucket, object, id, etag, parts)
+		if err != nil {
+			return err
+		}
+		info.Size = int64(oi.Size)
+		info.Etag = oi.Etag
+		info.Tags = oi.Tags
+		info.changed = oi
+		return nil
+	})
+	return etag, err
+}
+
+func (ms *MinioServer) completeObjectPart(bucket, object, id, etag string, parts *CompleteMultiPart) (*ObjectInfo, error) {
 	ms.Lock()
 	defer ms.Unlock()
 
-	var etag = GetUid()
 	var oi *ObjectInfo
 	var err error
 
-	oi, err = ms.getObjectInfo(bucket, object)
+	oi, err = ms.getUpload(bucket, object, id)
 	if err != nil {
-		return etag, err
+		return nil, err
 	}
 
-	if oi.UploadId != id {
-		return etag, errors.New("object not exists")
+	if len(parts.Parts) == 0 {
+		apiErr := ErrInvalidRequest
+		apiErr.Description = "You must specify at least one part"
+		return nil, apiErr
+	}
+
+	for i := 1; i < len(parts.Parts); i++ {
+		if parts.Parts[i].PartNumber <= parts.Parts[i-1].PartNumber {
+			return nil, ErrInvalidPartOrder
+		}
 	}
 
-	sort.Sort(parts)
-	for _, v := range parts.Parts {
+	var data []byte
+	var checksums []*Checksum
+	var objectParts []ObjectPart
+	for i, v := range parts.Parts {
 		var part Multipart
 		var ok bool
 		if part, ok = oi.Parts[v.PartNumber]; !ok {
-			return etag, errors.New("object parts not exists")
+			return nil, ErrPartNotExists
 		}
-		if part.Etag != v.ETag {
-			return etag, errors.New("object parts etag not same")
+		if part.Etag != strings.Trim(v.ETag, "\"") {
+			return nil, ErrPartEtagMismatch
 		}
-		oi.Data = append(oi.Data, part.Data...)
+		if i < len(parts.Parts)-1 && int64(len(part.Data)) < ms.GetMinPartSize() {
+			return nil, ErrEntityTooSmall
+		}
+		if oi.Checksum != nil {
+			fields := v.checksumFields
+			if expected := *fields.field(oi.Checksum.Algorithm); expected != "" && expected != part.Checksum.Value {
+				apiErr := ErrInvalidPart
+				apiErr.Description = fmt.Sprintf("The %s of part %d did not match the uploaded part.",
+					part.Checksum.Header(), v.PartNumber)
+				return nil, apiErr
+			}
+			checksums = append(checksums, part.Checksum)
+		}
+		data = append(data, part.Data...)
+		objectParts = append(objectParts, ObjectPart{
+			PartNumber: v.PartNumber,
+			Etag:       part.Etag,
+			Size:       int64(len(part.Data)),
+			Checksum:   part.Checksum,
+		})
+	}
+	var checksum *Checksum
+	if oi.Checksum != nil {
+		if checksum, err = compositeChecksum(oi.Checksum.Algorithm, checksums); err != nil {
+			return nil, err
+		}
+		if expected := parts.Checksum; expected != nil && expected.Value != "" && (expected.Algorithm != checksum.Algorithm ||
+			strings.SplitN(expected.Value, "-", 2)[0] != strings.SplitN(checksum.Value, "-", 2)[0]) {
+			apiErr := ErrBadDigest
+			apiErr.Description = fmt.Sprintf("The %s you specified did not match the calculated checksum.", expected.Header())
+			return nil, apiErr
+		}
+	}
+	parts.Checksum = checksum
+	bd := ms.Buckets[bucket]
+	if err = bd.checkQuota(object, uint64(len(data))); err != nil {
+		return nil, err
 	}
+
+	oi.Data = data
+	oi.Parts = nil
+	oi.ObjectParts = objectParts
+	oi.Checksum = checksum
 	oi.Etag = etag
 	oi.Size = uint64(len(oi.Data))
-	oi.LastModified = time.Now()
-	return etag, nil
+	oi.LastModified = ms.now()
+	delete(bd.Uploads, id)
+	bd.Objects[object] = oi
+	return oi, nil
+}
+
+// AbortMultipartUpload abort a multipart upload and drop its parts
+func (ms *MinioServer) AbortMultipartUpload(bucket, object, id string) error {
+	info := &HookInfo{Operation: OpAbortMultipartUpload, Bucket: bucket, Object: object, UploadID: id}
+	return ms.hooks.run(info, func() error {
+		return ms.abortMultipartUpload(bucket, object, id)
+	})
+}
+
+func (ms *MinioServer) abortMultipartUpload(bucket, object, id string) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	if _, err := ms.getUpload(bucket, object, id); err != nil {
+		return err
+	}
+	delete(ms.Buckets[bucket].Uploads, id)
+	return nil
+}
+
+func (ms *MinioServer) getUpload(bucket, object, id string) (*ObjectInfo, error) {
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return nil, ErrBucketNotExists
+	}
+
+	oi, ok := bd.Uploads[id]
+	if !ok || oi.Name != object {
+		return nil, ErrUploadNotExists
+	}
+	return oi, nil
 }
 
 // DeleteObject delete object
 func (ms *MinioServer) DeleteObject(bucket, object string) error {
+	info := &HookInfo{Operation: OpDeleteObject, Bucket: bucket, Object: object}
+	return ms.hooks.run(info, func() error {
+		var err error
+		info.changed, err = ms.deleteObject(bucket, object)
+		return err
+	})
+}
+
+func (ms *MinioServer) deleteObject(bucket, object string) (*ObjectInfo, error) {
 	ms.Lock()
 	defer ms.Unlock()
 
 	var ok bool
 	var bd *BucketData
 	if bd, ok = ms.Buckets[bucket]; !ok {
-		return errors.New("bucket not exists")
+		return nil, ErrBucketNotExists
 	}
 
-	if _, ok = bd.Objects[object]; !ok {
-		return errors.New("object not exists")
+	var oi *ObjectInfo
+	if oi, ok = bd.Objects[object]; !ok {
+		return nil, ErrObjectNotExists
 	}
 	delete(bd.Objects, object)
-	return nil
+	return oi, nil
 }
 
 func (ms *MinioServer) getObjectInfo(bucket, object string) (*ObjectInfo, error) {
@@ -170,18 +524,19 @@ func (ms *MinioServer) getObjectInfo(bucket, object string) (*ObjectInfo, error)
 	)
 
 	if bd, ok = ms.Buckets[bucket]; !ok {
-		return nil, errors.New("bucket not exists")
+		return nil, ErrBucketNotExists
 	}
 
 	oi, ok = bd.Objects[object]
 	if !ok {
-		return nil, errors.New("object not exists")
+		return nil, ErrObjectNotExists
 	}
 
 	return oi, nil
 }
 
-// GetObject get object
+// GetObject get the stored object, it must not be modified. Changes like tagging or the completion
+// of its replication store a new copy of the object instead of updating it.
 func (ms *MinioServer) GetObject(bucket, object string) (*ObjectInfo, error) {
 	ms.RLock()
 	defer ms.RUnlock()
@@ -189,6 +544,15 @@ func (ms *MinioServer) GetObject(bucket, object string) (*ObjectInfo, error) {
 	return ms.getObjectInfo(bucket, object)
 }
 
+// replaceObject stores a copy of the current object oi of bucket and returns it to be updated,
+// stored objects are read without the lock so they are never updated in place. The lock must be
+// held.
+func (ms *MinioServer) replaceObject(bucket string, oi *ObjectInfo) *ObjectInfo {
+	updated := *oi
+	ms.Buckets[bucket].Objects[oi.Name] = &updated
+	return &updated
+}
+
 // GetUid get uid as etag
 func GetUid() string {
 	var id string
@@ -203,20 +567,42 @@ func GetUid() string {
 type CompletePart struct {
 	PartNumber int
 	ETag       string
+	checksumFields
 }
 
 type CompleteMultiPart struct {
 	Parts []CompletePart `xml:"Part"`
+	// Checksum is the expected checksum of the completed object, it is set to the composite
+	// checksum of the parts once completed, nil when the upload has no checksum algorithm
+	Checksum *Checksum `xml:"-"`
 }
 
+const (
+	maxPartNumber       = 10000
+	maxPartSize   int64 = 5 << 30
+	// defaultMinPartSize is the minimum size of every part of a multipart upload but the last one
+	defaultMinPartSize = 5 << 20
+)
+
 func (cmp CompleteMultiPart) Len() int      { return len(cmp.Parts) }
 func (cmp CompleteMultiPart) Swap(i, j int) { cmp.Parts[i], cmp.Parts[j] = cmp.Parts[j], cmp.Parts[i] }
 func (cmp CompleteMultiPart) Less(i, j int) bool {
 	return cmp.Parts[i].PartNumber < cmp.Parts[j].PartNumber
 }
 
-func (cmp *CompleteMultiPart) Decode(r io.Reader) {
-	decodeAny(r, cmp)
+func (cmp *CompleteMultiPart) Decode(r io.Reader) error {
+	return decodeAny(r, cmp)
+}
+
+type CopyObjectResult struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CopyObjectResult" json:"-"`
+
+	ETag         string
+	LastModified time.Time
+}
+
+func (cr CopyObjectResult) Encode() []byte {
+	return encodeAny(cr)
 }
 
 type InitiateMultipartUploadResult struct {
@@ -238,6 +624,7 @@ type CompleteMultipartUploadResponse struct {
 	Bucket   string
 	Key      string
 	ETag     string
+	checksumFields
 }
 
 func (cr CompleteMultipartUploadResponse) Encode() []byte {
@@ -0,0 +1,257 @@
+package gominio
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// OpenIDConfig configures the verification of the web identity tokens exchanged by
+// AssumeRoleWithWebIdentity for temporary credentials
+type OpenIDConfig struct {
+	// HMACKey verifies HS256, HS384 and HS512 tokens
+	HMACKey []byte
+	// JWKS is a JSON Web Key Set verifying RS256, RS384, RS512, ES256, ES384 and ES512 tokens,
+	// keys are picked by the kid of the token header
+	JWKS []byte
+	// ClientID is the audience tokens must be issued for, any audience is accepted when empty
+	ClientID string
+	// ClaimName is the claim listing the policies of the identity, "policy" when empty. Like with
+	// MinIO the claim is a list of policy names or a comma separated string.
+	ClaimName string
+}
+
+const defaultPolicyClaim = "policy"
+
+type openIDVerifier struct {
+	cfg  OpenIDConfig
+	keys map[string]crypto.PublicKey
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func newOpenIDVerifier(cfg OpenIDConfig) (*openIDVerifier, error) {
+	v := &openIDVerifier{cfg: cfg, keys: make(map[string]crypto.PublicKey)}
+	if v.cfg.ClaimName == "" {
+		v.cfg.ClaimName = defaultPolicyClaim
+	}
+	if len(cfg.JWKS) == 0 {
+		return v, nil
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(cfg.JWKS, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	for _, jwk := range set.Keys {
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %q: %w", jwk.Kid, err)
+		}
+		v.keys[jwk.Kid] = key
+	}
+	return v, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// jwtClaims are the claims of a verified web identity token
+type jwtClaims map[string]any
+
+// verify checks the signature, the validity period and the audience of a JWT
+func (v *openIDVerifier) verify(token string) (jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+	if err := v.verifySignature(header.Alg, header.Kid, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := float64(time.Now().Unix())
+	exp, ok := claims["exp"].(float64)
+	switch {
+	case !ok:
+		return nil, errors.New("token has no expiry")
+	case exp <= now:
+		return nil, errors.New("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && nbf > now {
+		return nil, errors.New("token is not valid yet")
+	}
+	if v.cfg.ClientID != "" && !containsName(claimStrings(claims["aud"], false), v.cfg.ClientID) {
+		return nil, errors.New("token is issued for another audience")
+	}
+	return claims, nil
+}
+
+func (v *openIDVerifier) verifySignature(alg, kid, signed string, signature []byte) error {
+	var newHash func() hash.Hash
+	var ch crypto.Hash
+	switch alg {
+	case "HS256", "RS256", "ES256":
+		newHash, ch = sha256.New, crypto.SHA256
+	case "HS384", "RS384", "ES384":
+		newHash, ch = sha512.New384, crypto.SHA384
+	case "HS512", "RS512", "ES512":
+		newHash, ch = sha512.New, crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+
+	if strings.HasPrefix(alg, "HS") {
+		if len(v.cfg.HMACKey) == 0 {
+			return errors.New("no HMAC key is configured")
+		}
+		mac := hmac.New(newHash, v.cfg.HMACKey)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	}
+
+	key, ok := v.keys[kid]
+	if !ok && kid == "" && len(v.keys) == 1 {
+		for _, k := range v.keys {
+			key, ok = k, true
+		}
+	}
+	if !ok {
+		return fmt.Errorf("unknown token key %q", kid)
+	}
+	h := newHash()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "RS") && rsa.VerifyPKCS1v15(pub, ch, digest, signature) == nil {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if strings.HasPrefix(alg, "ES") && len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			if ecdsa.Verify(pub, digest, r, s) {
+				return nil
+			}
+		}
+	}
+	return errors.New("invalid token signature")
+}
+
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("invalid token encoding: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+	return nil
+}
+
+// claimStrings returns the strings of a claim given as a string or a list, a string is split on
+// commas when split is set
+func claimStrings(claim any, split bool) []string {
+	var values []string
+	switch c := claim.(type) {
+	case string:
+		if !split {
+			return []string{c}
+		}
+		for _, value := range strings.Split(c, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	case []any:
+		for _, value := range c {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
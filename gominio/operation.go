@@ -0,0 +1,260 @@
+package gominio
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Operation names attached to every request, they follow the S3 API action names and the
+// MinIO admin API handler names
+const (
+	OpUnknown = "Unknown"
+
+	OpListBuckets         = "ListBuckets"
+	OpHeadBucket          = "HeadBucket"
+	OpCreateBucket        = "CreateBucket"
+	OpDeleteBucket        = "DeleteBucket"
+	OpListObjects         = "ListObjects"
+	OpGetBucketLocation   = "GetBucketLocation"
+	OpGetBucketPolicy     = "GetBucketPolicy"
+	OpPutBucketPolicy     = "PutBucketPolicy"
+	OpGetBucketLifecycle  = "GetBucketLifecycle"
+	OpPutBucketLifecycle  = "PutBucketLifecycle"
+	OpGetBucketEncryption = "GetBucketEncryption"
+	OpPutBucketEncryption = "PutBucketEncryption"
+	OpGetBucketVersioning = "GetBucketVersioning"
+	OpPutBucketVersioning = "PutBucketVersioning"
+	OpGetBucketTagging    = "GetBucketTagging"
+	OpPutBucketTagging    = "PutBucketTagging"
+	OpDeleteBucketTagging = "DeleteBucketTagging"
+	OpGetBucketCors       = "GetBucketCors"
+	OpPutBucketCors       = "PutBucketCors"
+	OpDeleteBucketCors    = "DeleteBucketCors"
+	OpOptionsObject       = "OptionsObject"
+
+	OpGetBucketReplication    = "GetBucketReplication"
+	OpPutBucketReplication    = "PutBucketReplication"
+	OpDeleteBucketReplication = "DeleteBucketReplication"
+	OpGetBucketWebsite        = "GetBucketWebsite"
+	OpPutBucketWebsite        = "PutBucketWebsite"
+	OpDeleteBucketWebsite     = "DeleteBucketWebsite"
+
+	OpGetBucketNotification    = "GetBucketNotification"
+	OpPutBucketNotification    = "PutBucketNotification"
+	OpListenNotification       = "ListenNotification"
+	OpListenBucketNotification = "ListenBucketNotification"
+
+	OpAssumeRole                = "AssumeRole"
+	OpAssumeRoleWithWebIdentity = "AssumeRoleWithWebIdentity"
+
+	OpAddUser                 = "AddUser"
+	OpRemoveUser              = "RemoveUser"
+	OpListUsers               = "ListUsers"
+	OpGetUserInfo             = "GetUserInfo"
+	OpSetUserStatus           = "SetUserStatus"
+	OpAddCannedPolicy         = "AddCannedPolicy"
+	OpRemoveCannedPolicy      = "RemoveCannedPolicy"
+	OpListCannedPolicies      = "ListCannedPolicies"
+	OpInfoCannedPolicy        = "InfoCannedPolicy"
+	OpSetPolicyForUserOrGroup = "SetPolicyForUserOrGroup"
+	OpPutBucketQuotaConfig    = "PutBucketQuotaConfig"
+	OpGetBucketQuotaConfig    = "GetBucketQuotaConfig"
+	OpServerInfo              = "ServerInfo"
+
+	OpHeadObject              = "HeadObject"
+	OpGetObject               = "GetObject"
+	OpPutObject               = "PutObject"
+	OpCopyObject              = "CopyObject"
+	OpDeleteObject            = "DeleteObject"
+	OpGetObjectTagging        = "GetObjectTagging"
+	OpPutObjectTagging        = "PutObjectTagging"
+	OpDeleteObjectTagging     = "DeleteObjectTagging"
+	OpGetObjectRetention      = "GetObjectRetention"
+	OpPutObjectRetention      = "PutObjectRetention"
+	OpGetObjectLegalHold      = "GetObjectLegalHold"
+	OpPutObjectLegalHold      = "PutObjectLegalHold"
+	OpCreateMultipartUpload   = "CreateMultipartUpload"
+	OpUploadPart              = "UploadPart"
+	OpUploadPartCopy          = "UploadPartCopy"
+	OpCompleteMultipartUpload = "CompleteMultipartUpload"
+	OpAbortMultipartUpload    = "AbortMultipartUpload"
+	OpSelectObjectContent     = "SelectObjectContent"
+	OpGetObjectAttributes     = "GetObjectAttributes"
+	OpRestoreObject           = "RestoreObject"
+)
+
+const operationKey = "gominio.operation"
+
+// GetOperation returns the S3 operation name of the request handled by ctx
+func GetOperation(ctx *gin.Context) string {
+	if op := ctx.GetString(operationKey); op != "" {
+		return op
+	}
+	op := resolveOperation(ctx)
+	ctx.Set(operationKey, op)
+	return op
+}
+
+// resolveOperation returns the S3 operation a routed request is dispatched to,
+// it mirrors the route and query dispatch of the ApiServer handlers.
+func resolveOperation(ctx *gin.Context) string {
+	bucket := ctx.Param("bucket")
+	object := ctx.Param("object")
+	method := ctx.Request.Method
+
+	has := func(key string) bool {
+		_, ok := ctx.GetQuery(key)
+		return ok
+	}
+
+	switch {
+	case method == http.MethodOptions:
+		return OpOptionsObject
+	case bucket == "" && object == "":
+		if op, ok := adminOperations[ctx.FullPath()]; ok {
+			return op
+		}
+		if method == http.MethodGet && ctx.FullPath() == "/" {
+			if has("events") || has("ping") {
+				return OpListenNotification
+			}
+			return OpListBuckets
+		}
+		if method == http.MethodPost && ctx.FullPath() == "/" {
+			switch stsForm(ctx).Get("Action") {
+			case stsActionAssumeRole:
+				return OpAssumeRole
+			case stsActionAssumeRoleWithWebIdentity:
+				return OpAssumeRoleWithWebIdentity
+			}
+		}
+	case object == "":
+		return resolveBucketOperation(method, has)
+	default:
+		return resolveObjectOperation(method, has, ctx.Request.Header)
+	}
+	return OpUnknown
+}
+
+func resolveBucketOperation(method string, has func(string) bool) string {
+	switch method {
+	case http.MethodHead:
+		return OpHeadBucket
+	case http.MethodGet:
+		switch {
+		case has("events") || has("ping"):
+			return OpListenBucketNotification
+		case has("location"):
+			return OpGetBucketLocation
+		case has("policy"):
+			return OpGetBucketPolicy
+		case has("lifecycle"):
+			return OpGetBucketLifecycle
+		case has("encryption"):
+			return OpGetBucketEncryption
+		case has("versioning"):
+			return OpGetBucketVersioning
+		case has("tagging"):
+			return OpGetBucketTagging
+		case has("notification"):
+			return OpGetBucketNotification
+		case has("cors"):
+			return OpGetBucketCors
+		case has("replication"):
+			return OpGetBucketReplication
+		case has("website"):
+			return OpGetBucketWebsite
+		}
+		return OpListObjects
+	case http.MethodPut:
+		switch {
+		case has("policy"):
+			return OpPutBucketPolicy
+		case has("lifecycle"):
+			return OpPutBucketLifecycle
+		case has("encryption"):
+			return OpPutBucketEncryption
+		case has("versioning"):
+			return OpPutBucketVersioning
+		case has("tagging"):
+			return OpPutBucketTagging
+		case has("notification"):
+			return OpPutBucketNotification
+		case has("cors"):
+			return OpPutBucketCors
+		case has("replication"):
+			return OpPutBucketReplication
+		case has("website"):
+			return OpPutBucketWebsite
+		}
+		return OpCreateBucket
+	case http.MethodDelete:
+		switch {
+		case has("tagging"):
+			return OpDeleteBucketTagging
+		case has("cors"):
+			return OpDeleteBucketCors
+		case has("replication"):
+			return OpDeleteBucketReplication
+		case has("website"):
+			return OpDeleteBucketWebsite
+		}
+		return OpDeleteBucket
+	}
+	return OpUnknown
+}
+
+func resolveObjectOperation(method string, has func(string) bool, header http.Header) string {
+	switch method {
+	case http.MethodHead:
+		return OpHeadObject
+	case http.MethodGet:
+		switch {
+		case has("tagging"):
+			return OpGetObjectTagging
+		case has("retention"):
+			return OpGetObjectRetention
+		case has("legal-hold"):
+			return OpGetObjectLegalHold
+		case has("attributes"):
+			return OpGetObjectAttributes
+		}
+		return OpGetObject
+	case http.MethodPut:
+		switch {
+		case has("tagging"):
+			return OpPutObjectTagging
+		case has("retention"):
+			return OpPutObjectRetention
+		case has("legal-hold"):
+			return OpPutObjectLegalHold
+		case has("partNumber") && header.Get(amzCopySource) != "":
+			return OpUploadPartCopy
+		case has("partNumber"):
+			return OpUploadPart
+		case header.Get(amzCopySource) != "":
+			return OpCopyObject
+		}
+		return OpPutObject
+	case http.MethodPost:
+		switch {
+		case has("select"):
+			return OpSelectObjectContent
+		case has("restore"):
+			return OpRestoreObject
+		case has("uploads"):
+			return OpCreateMultipartUpload
+		case has("uploadId"):
+			return OpCompleteMultipartUpload
+		}
+	case http.MethodDelete:
+		switch {
+		case has("tagging"):
+			return OpDeleteObjectTagging
+		case has("uploadId"):
+			return OpAbortMultipartUpload
+		}
+		return OpDeleteObject
+	}
+	return OpUnknown
+}
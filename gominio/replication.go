@@ -0,0 +1,477 @@
+package gominio
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/minio/minio-go/v7/pkg/replication"
+	"github.com/minio/minio-go/v7/pkg/tags"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const amzReplicationStatus = "x-amz-replication-status"
+
+// Replication statuses of the x-amz-replication-status header, source objects are PENDING until
+// they are replicated and their copies in the destination bucket are REPLICA
+const (
+	ReplicationPending   = "PENDING"
+	ReplicationCompleted = "COMPLETED"
+	ReplicationFailed    = "FAILED"
+	ReplicationReplica   = "REPLICA"
+)
+
+// replicationBucketARN is the prefix of the destinations in the server of the source bucket
+const replicationBucketARN = "arn:aws:s3:::"
+
+// replicationTarget is the bucket objects are replicated to
+type replicationTarget struct {
+	ms     *MinioServer
+	bucket string
+}
+
+// replicationTask replicates a change of the object oi of bucket
+type replicationTask struct {
+	bucket       string
+	oi           *ObjectInfo
+	delete       bool
+	target       replicationTarget
+	storageClass string
+}
+
+// Replicator copies the changes of the objects matching the bucket replication configurations to
+// their destination in the background, one change at a time in the order they were made
+type Replicator struct {
+	sync.Mutex
+	cond    *sync.Cond
+	ms      *MinioServer
+	targets map[string]replicationTarget
+	tasks   []replicationTask
+	// busy is set while the worker replicates a task, the worker is started with the first task
+	busy    bool
+	running bool
+	closed  bool
+}
+
+func newReplicator(ms *MinioServer) *Replicator {
+	r := &Replicator{ms: ms, targets: make(map[string]replicationTarget)}
+	r.cond = sync.NewCond(&r.Mutex)
+	return r
+}
+
+// AddTarget registers bucket of the target server as a replication destination and returns the
+// ARN replication rules refer to it with. The buckets of the server itself can also be referred
+// to with arn:aws:s3:::<bucket>.
+func (r *Replicator) AddTarget(id string, target *MinioServer, bucket string) string {
+	r.Lock()
+	defer r.Unlock()
+
+	arn := fmt.Sprintf("arn:minio:replication:%s:%s:%s", r.ms.GetRegion(), id, bucket)
+	r.targets[arn] = replicationTarget{ms: target, bucket: bucket}
+	return arn
+}
+
+// RemoveTarget unregisters the replication destination with the given ARN
+func (r *Replicator) RemoveTarget(arn string) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.targets, arn)
+}
+
+// resolve returns the destination of a replication rule
+func (r *Replicator) resolve(arn string) (replicationTarget, bool) {
+	if bucket := strings.TrimPrefix(arn, replicationBucketARN); bucket != arn && bucket != "" {
+		return replicationTarget{ms: r.ms, bucket: bucket}, true
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	target, ok := r.targets[arn]
+	return target, ok
+}
+
+// Wait waits until the queued changes are replicated, tests use it before they inspect the
+// destination buckets
+func (r *Replicator) Wait() {
+	r.Lock()
+	defer r.Unlock()
+
+	for (len(r.tasks) > 0 || r.busy) && !r.closed {
+		r.cond.Wait()
+	}
+}
+
+// Close stops the replication, the queued changes are dropped
+func (r *Replicator) Close() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.closed = true
+	r.tasks = nil
+	r.cond.Broadcast()
+}
+
+func (r *Replicator) push(task replicationTask) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.closed {
+		return
+	}
+	r.tasks = append(r.tasks, task)
+	if !r.running {
+		r.running = true
+		go r.run()
+	}
+	r.cond.Broadcast()
+}
+
+func (r *Replicator) run() {
+	for {
+		r.Lock()
+		r.busy = false
+		r.cond.Broadcast()
+		for len(r.tasks) == 0 && !r.closed {
+			r.cond.Wait()
+		}
+		if r.closed {
+			r.Unlock()
+			return
+		}
+		task := r.tasks[0]
+		r.tasks = r.tasks[1:]
+		r.busy = true
+		r.Unlock()
+
+		r.replicate(task)
+	}
+}
+
+// replicate applies the change of the task to its destination and reports the outcome in the
+// replication status of the source object unless it was replaced meanwhile
+func (r *Replicator) replicate(task replicationTask) {
+	if task.delete {
+		if err := task.target.ms.deleteReplica(task.target.bucket, task.oi.Name); err != nil {
+			log.Println("replicate delete err", err)
+		}
+		return
+	}
+
+	// objects replaced or deleted meanwhile are replicated by their own tasks
+	r.ms.RLock()
+	current := r.ms.isCurrent(task.bucket, task.oi)
+	replica := cloneObject(task.oi)
+	r.ms.RUnlock()
+	if !current {
+		return
+	}
+	replica.ReplicationStatus = ReplicationReplica
+	if task.storageClass != "" {
+		replica.StorageClass = task.storageClass
+	}
+	replica.Restore = nil
+
+	status := ReplicationCompleted
+	if err := task.target.ms.putReplica(task.target.bucket, &replica); err != nil {
+		log.Println("replicate object err", err)
+		status = ReplicationFailed
+	}
+
+	r.ms.Lock()
+	defer r.ms.Unlock()
+	r.ms.replaceReplicationStatus(task.bucket, task.oi, status)
+}
+
+// cloneObject returns a copy of oi sharing no data with it, so the replica is not changed along
+// with its source object
+func cloneObject(oi *ObjectInfo) ObjectInfo {
+	c := *oi
+	c.Data = append([]byte(nil), oi.Data...)
+	if oi.Tags != nil {
+		if t, err := tags.NewTags(oi.Tags.ToMap(), true); err == nil {
+			c.Tags = t
+		}
+	}
+	if oi.Checksum != nil {
+		checksum := *oi.Checksum
+		c.Checksum = &checksum
+	}
+	if oi.Parts != nil {
+		c.Parts = make(map[int]Multipart, len(oi.Parts))
+		for n, part := range oi.Parts {
+			part.Data = append([]byte(nil), part.Data...)
+			if part.Checksum != nil {
+				checksum := *part.Checksum
+				part.Checksum = &checksum
+			}
+			c.Parts[n] = part
+		}
+	}
+	if oi.ObjectParts != nil {
+		c.ObjectParts = make([]ObjectPart, len(oi.ObjectParts))
+		for i, part := range oi.ObjectParts {
+			if part.Checksum != nil {
+				checksum := *part.Checksum
+				part.Checksum = &checksum
+			}
+			c.ObjectParts[i] = part
+		}
+	}
+	return c
+}
+
+// replaceReplicationStatus replaces oi by a copy with the replication status and returns it, nil
+// when oi is not the current object of its key anymore. The lock of the server must be held.
+func (ms *MinioServer) replaceReplicationStatus(bucket string, oi *ObjectInfo, status string) *ObjectInfo {
+	if !ms.isCurrent(bucket, oi) {
+		return nil
+	}
+	updated := ms.replaceObject(bucket, ms.Buckets[bucket].Objects[oi.Name])
+	updated.ReplicationStatus = status
+	return updated
+}
+
+// isCurrent reports whether oi is the current version of its key, the copies stored by
+// replaceObject are the same version. It must be called with the lock of the server held.
+func (ms *MinioServer) isCurrent(bucket string, oi *ObjectInfo) bool {
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return false
+	}
+	current, ok := bd.Objects[oi.Name]
+	return ok && current.Etag == oi.Etag && current.LastModified.Equal(oi.LastModified)
+}
+
+// queue queues the replication of a change of object matching the replication configuration of
+// its bucket, it must be called with the lock of the server held. Replicas are not replicated
+// again and deletes are only replicated by the rules enabling delete marker replication.
+func (r *Replicator) queue(name notification.EventType, bucket string, cfg *replication.Config, oi *ObjectInfo) {
+	if cfg == nil || oi.ReplicationStatus == ReplicationReplica {
+		return
+	}
+	rule := matchReplicationRule(cfg, oi)
+	if rule == nil {
+		return
+	}
+
+	deleted := strings.HasPrefix(string(name), "s3:ObjectRemoved:")
+	if deleted && rule.DeleteMarkerReplication.Status != replication.Enabled &&
+		rule.DeleteReplication.Status != replication.Enabled {
+		return
+	}
+	target, ok := r.resolve(rule.Destination.Bucket)
+	if !ok {
+		if !deleted {
+			r.ms.replaceReplicationStatus(bucket, oi, ReplicationFailed)
+		}
+		return
+	}
+	if !deleted {
+		if oi = r.ms.replaceReplicationStatus(bucket, oi, ReplicationPending); oi == nil {
+			return
+		}
+	}
+	r.push(replicationTask{
+		bucket:       bucket,
+		oi:           oi,
+		delete:       deleted,
+		target:       target,
+		storageClass: rule.Destination.StorageClass,
+	})
+}
+
+// matchReplicationRule returns the enabled rule with the highest priority whose prefix and tags
+// match the object, nil when there is none
+func matchReplicationRule(cfg *replication.Config, oi *ObjectInfo) *replication.Rule {
+	var objectTags map[string]string
+	if oi.Tags != nil {
+		objectTags = oi.Tags.ToMap()
+	}
+
+	var matched *replication.Rule
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if rule.Status != replication.Enabled || !strings.HasPrefix(oi.Name, rule.Prefix()) {
+			continue
+		}
+		ruleTags := rule.Filter.And.Tags
+		if !rule.Filter.Tag.IsEmpty() {
+			ruleTags = []replication.Tag{rule.Filter.Tag}
+		}
+		match := true
+		for _, tag := range ruleTags {
+			if value, ok := objectTags[tag.Key]; !ok || value != tag.Value {
+				match = false
+			}
+		}
+		if match && (matched == nil || rule.Priority > matched.Priority) {
+			matched = rule
+		}
+	}
+	return matched
+}
+
+// putReplica stores the replica of an object replicated to bucket
+func (ms *MinioServer) putReplica(bucket string, oi *ObjectInfo) error {
+	info := &HookInfo{
+		Operation: OpPutObject,
+		Bucket:    bucket,
+		Object:    oi.Name,
+		Size:      int64(oi.Size),
+		Etag:      oi.Etag,
+		Tags:      oi.Tags,
+	}
+	return ms.hooks.run(info, func() error {
+		ms.Lock()
+		defer ms.Unlock()
+
+		bd, ok := ms.Buckets[bucket]
+		if !ok {
+			return ErrBucketNotExists
+		}
+		if err := bd.checkQuota(oi.Name, oi.Size); err != nil {
+			return err
+		}
+		bd.Objects[oi.Name] = oi
+		info.changed = oi
+		return nil
+	})
+}
+
+// deleteReplica deletes the object of bucket whose source object was deleted, the bucket has no
+// versioning so the delete marker removes the object
+func (ms *MinioServer) deleteReplica(bucket, object string) error {
+	err := ms.DeleteObject(bucket, object)
+	if errors.Is(err, ErrObjectNotExists) {
+		return nil
+	}
+	return err
+}
+
+// GetReplicator returns the replicator of the server
+func (ms *MinioServer) GetReplicator() *Replicator {
+	return ms.replicator
+}
+
+// SetBucketReplication sets the replication configuration of bucket, the destination of every
+// rule must be another bucket of the server or a target registered on the replicator. Only the
+// objects changed after the configuration is set are replicated.
+func (ms *MinioServer) SetBucketReplication(bucket string, cfg *replication.Config) error {
+	if len(cfg.Rules) == 0 {
+		apiErr := ErrMalformedXML
+		apiErr.Description = "The replication configuration must have at least one rule."
+		return apiErr
+	}
+	ids := make(map[string]bool)
+	for _, rule := range cfg.Rules {
+		if err := rule.Validate(); err != nil {
+			apiErr := ErrMalformedXML
+			apiErr.Description = err.Error()
+			return apiErr
+		}
+		if rule.ID != "" && ids[rule.ID] {
+			apiErr := ErrInvalidRequest
+			apiErr.Description = "Rule id must be unique."
+			return apiErr
+		}
+		ids[rule.ID] = true
+
+		target, ok := ms.replicator.resolve(rule.Destination.Bucket)
+		if !ok {
+			apiErr := ErrInvalidArgument
+			apiErr.Description = "The destination bucket ARN is not valid or is not a registered replication target."
+			return apiErr
+		}
+		if target.ms == ms && target.bucket == bucket {
+			apiErr := ErrInvalidRequest
+			apiErr.Description = "Destination bucket cannot be the same as the source bucket."
+			return apiErr
+		}
+		if !target.ms.BucketExists(target.bucket) {
+			apiErr := ErrInvalidRequest
+			apiErr.Description = "Destination bucket must exist."
+			return apiErr
+		}
+		if _, err := ParseStorageClass(rule.Destination.StorageClass); err != nil {
+			return err
+		}
+	}
+
+	ms.Lock()
+	defer ms.Unlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return ErrBucketNotExists
+	}
+	bd.Info.Replication = cfg
+	return nil
+}
+
+// GetBucketReplication returns the replication configuration of bucket,
+// ErrReplicationConfigurationNotFound is returned when the bucket has none
+func (ms *MinioServer) GetBucketReplication(bucket string) (*replication.Config, error) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return nil, ErrBucketNotExists
+	}
+	if bd.Info.Replication == nil {
+		return nil, ErrReplicationConfigurationNotFound
+	}
+	return bd.Info.Replication, nil
+}
+
+// RemoveBucketReplication removes the replication configuration of bucket, the queued changes
+// are still replicated
+func (ms *MinioServer) RemoveBucketReplication(bucket string) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return ErrBucketNotExists
+	}
+	bd.Info.Replication = nil
+	return nil
+}
+
+func (api *ApiServer) getBucketReplication(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	cfg, err := api.GetMS().GetBucketReplication(bucket)
+	if err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+	SuccessResponse(ctx, http.StatusOK, encodeAny(cfg))
+}
+
+func (api *ApiServer) putBucketReplication(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	var cfg = new(replication.Config)
+	if err := decodeAny(ctx.Request.Body, cfg); err != nil {
+		ErrResponse(ctx, "", bucket, ErrMalformedXML)
+		return
+	}
+
+	if err := api.GetMS().SetBucketReplication(bucket, cfg); err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, nil)
+}
+
+// setReplicationStatus reports the replication status of replicated objects and of replicas
+func setReplicationStatus(header http.Header, oi *ObjectInfo) {
+	if oi.ReplicationStatus != "" {
+		header.Set(amzReplicationStatus, oi.ReplicationStatus)
+	}
+}
@@ -0,0 +1,171 @@
+package gominio
+
+import (
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/replication"
+	"github.com/minio/minio-go/v7/pkg/tags"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBucketReplication(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("src", "dst"))
+	dr := NewTestServer(t, WithBuckets("backup"))
+	ctx := context.Background()
+	replicator := server.GetMS().GetReplicator()
+	backupARN := replicator.AddTarget("dr", dr.GetMS(), "backup")
+
+	put := func(object string, userTags map[string]string) {
+		_, err := server.Client.PutObject(ctx, "src", object, strings.NewReader(object), int64(len(object)),
+			minio.PutObjectOptions{UserTags: userTags})
+		require.NoError(t, err)
+	}
+	status := func(client *minio.Client, bucket, object string) string {
+		oi, err := client.StatObject(ctx, bucket, object, minio.StatObjectOptions{})
+		require.NoError(t, err)
+		return oi.ReplicationStatus
+	}
+
+	cfg, err := server.Client.GetBucketReplication(ctx, "src")
+	require.NoError(t, err)
+	require.True(t, cfg.Empty())
+	for arn, code := range map[string]string{
+		"arn:minio:replication::unknown:backup": "InvalidArgument",
+		"arn:aws:s3:::src":                      "InvalidRequest",
+		"arn:aws:s3:::missing":                  "InvalidRequest",
+	} {
+		err = server.Client.SetBucketReplication(ctx, "src", replication.Config{Rules: []replication.Rule{{
+			Status:      replication.Enabled,
+			Destination: replication.Destination{Bucket: arn},
+		}}})
+		require.Equal(t, code, minio.ToErrorResponse(err).Code, arn)
+	}
+
+	cfg = replication.Config{Rules: []replication.Rule{
+		{
+			ID:                      "docs",
+			Status:                  replication.Enabled,
+			Priority:                1,
+			DeleteMarkerReplication: replication.DeleteMarkerReplication{Status: replication.Enabled},
+			Destination:             replication.Destination{Bucket: "arn:aws:s3:::dst"},
+			Filter:                  replication.Filter{Prefix: "docs/"},
+		},
+		{
+			ID:                      "reports",
+			Status:                  replication.Enabled,
+			Priority:                2,
+			DeleteMarkerReplication: replication.DeleteMarkerReplication{Status: replication.Disabled},
+			Destination:             replication.Destination{Bucket: backupARN, StorageClass: StorageClassStandardIA},
+			Filter: replication.Filter{And: replication.And{
+				Prefix: "reports/",
+				Tags:   []replication.Tag{{Key: "dr", Value: "yes"}},
+			}},
+		},
+	}}
+	require.NoError(t, server.Client.SetBucketReplication(ctx, "src", cfg))
+	stored, err := server.Client.GetBucketReplication(ctx, "src")
+	require.NoError(t, err)
+	require.Len(t, stored.Rules, 2)
+	require.Equal(t, backupARN, stored.Rules[1].Destination.Bucket)
+
+	// objects are pending until the replicator copied them to their destination
+	dr.GetMS().Lock()
+	put("reports/q1.txt", map[string]string{"dr": "yes"})
+	require.Equal(t, ReplicationPending, status(server.Client, "src", "reports/q1.txt"))
+	dr.GetMS().Unlock()
+	put("docs/readme.txt", nil)
+	put("reports/q2.txt", nil)
+	put("other.txt", nil)
+	replicator.Wait()
+
+	require.Equal(t, ReplicationCompleted, status(server.Client, "src", "reports/q1.txt"))
+	require.Equal(t, ReplicationCompleted, status(server.Client, "src", "docs/readme.txt"))
+	require.Empty(t, status(server.Client, "src", "reports/q2.txt"))
+	require.Empty(t, status(server.Client, "src", "other.txt"))
+
+	require.Equal(t, ReplicationReplica, status(server.Client, "dst", "docs/readme.txt"))
+	obj, err := dr.Client.GetObject(ctx, "backup", "reports/q1.txt", minio.GetObjectOptions{})
+	require.NoError(t, err)
+	data, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	require.Equal(t, "reports/q1.txt", string(data))
+	replica, err := dr.GetMS().GetObject("backup", "reports/q1.txt")
+	require.NoError(t, err)
+	require.Equal(t, StorageClassStandardIA, replica.StorageClass)
+	require.Equal(t, map[string]string{"dr": "yes"}, replica.Tags.ToMap())
+
+	// GetObject returns the stored object and replicas share no data with their source
+	source, err := server.GetMS().GetObject("src", "reports/q1.txt")
+	require.NoError(t, err)
+	again, err := server.GetMS().GetObject("src", "reports/q1.txt")
+	require.NoError(t, err)
+	require.Same(t, source, again)
+	require.NotSame(t, source.Tags, replica.Tags)
+	require.NotSame(t, &source.Data[0], &replica.Data[0])
+	_, err = server.Client.PutObject(ctx, "src", "reports/q1.txt", strings.NewReader("updated"), 7,
+		minio.PutObjectOptions{})
+	require.NoError(t, err)
+	replicator.Wait()
+	require.Equal(t, "reports/q1.txt", string(source.Data))
+	replica, err = dr.GetMS().GetObject("backup", "reports/q1.txt")
+	require.NoError(t, err)
+	require.Equal(t, "reports/q1.txt", string(replica.Data))
+
+	// changes store a new copy of the object, the object returned before is read while it changes
+	other, err := server.GetMS().GetObject("src", "other.txt")
+	require.NoError(t, err)
+	otherTags, err := tags.MapToObjectTags(map[string]string{"owner": "ops"})
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Client.PutObjectTagging(ctx, "src", "other.txt", otherTags, minio.PutObjectTaggingOptions{})
+	}()
+	otherTagsBefore := other.Tags
+	require.NoError(t, <-done)
+	require.Equal(t, otherTagsBefore, other.Tags)
+	other, err = server.GetMS().GetObject("src", "other.txt")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"owner": "ops"}, other.Tags.ToMap())
+
+	_, err = dr.Client.StatObject(ctx, "backup", "reports/q2.txt", minio.StatObjectOptions{})
+	require.Equal(t, "NoSuchKey", minio.ToErrorResponse(err).Code)
+
+	// tagging an object replicates it once it matches a rule
+	tagged, err := tags.MapToObjectTags(map[string]string{"dr": "yes"})
+	require.NoError(t, err)
+	require.NoError(t, server.Client.PutObjectTagging(ctx, "src", "reports/q2.txt", tagged, minio.PutObjectTaggingOptions{}))
+	replicator.Wait()
+	require.Equal(t, ReplicationReplica, status(dr.Client, "backup", "reports/q2.txt"))
+
+	// deletes are only replicated by the rules replicating delete markers
+	require.NoError(t, server.Client.RemoveObject(ctx, "src", "docs/readme.txt", minio.RemoveObjectOptions{}))
+	require.NoError(t, server.Client.RemoveObject(ctx, "src", "reports/q1.txt", minio.RemoveObjectOptions{}))
+	replicator.Wait()
+	_, err = server.Client.StatObject(ctx, "dst", "docs/readme.txt", minio.StatObjectOptions{})
+	require.Equal(t, "NoSuchKey", minio.ToErrorResponse(err).Code)
+	require.Equal(t, ReplicationReplica, status(dr.Client, "backup", "reports/q1.txt"))
+
+	// replication fails once the destination bucket is gone
+	require.NoError(t, dr.GetMS().DelBucket("backup", true))
+	put("reports/q3.txt", map[string]string{"dr": "yes"})
+	replicator.Wait()
+	require.Equal(t, ReplicationFailed, status(server.Client, "src", "reports/q3.txt"))
+
+	require.NoError(t, server.Client.RemoveBucketReplication(ctx, "src"))
+	req, err := http.NewRequest(http.MethodGet, "/src?replication", nil)
+	require.NoError(t, err)
+	rsp, err := server.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, rsp.Body.Close())
+	require.Equal(t, http.StatusNotFound, rsp.StatusCode)
+	put("docs/later.txt", nil)
+	replicator.Wait()
+	require.Empty(t, status(server.Client, "src", "docs/later.txt"))
+
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpPutBucketReplication, Status: http.StatusOK}, 1)
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpGetBucketReplication}, 3)
+}
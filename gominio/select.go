@@ -0,0 +1,368 @@
+package gominio
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/gin-gonic/gin"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxSelectRequestSize is the maximum size of a SelectObjectContent request body
+	maxSelectRequestSize = 256 << 10
+	// selectRecordsSize is the maximum payload size of a Records message
+	selectRecordsSize = 128 << 10
+)
+
+// SelectRequest is the SelectObjectContent request of a SQL expression on a CSV or JSON object
+type SelectRequest struct {
+	XMLName            xml.Name `xml:"SelectObjectContentRequest"`
+	Expression         string
+	ExpressionType     string
+	InputSerialization struct {
+		CompressionType string
+		CSV             *SelectCSVInput
+		JSON            *SelectJSONInput
+		Parquet         *struct{}
+	}
+	OutputSerialization struct {
+		CSV  *SelectCSVOutput
+		JSON *SelectJSONOutput
+	}
+	RequestProgress struct {
+		Enabled bool
+	}
+}
+
+// SelectCSVInput describes the format of a CSV object, FileHeaderInfo is NONE, IGNORE or USE
+type SelectCSVInput struct {
+	FileHeaderInfo       string
+	RecordDelimiter      string
+	FieldDelimiter       string
+	QuoteCharacter       string
+	QuoteEscapeCharacter string
+	Comments             string
+}
+
+// SelectJSONInput describes the format of a JSON object, Type is DOCUMENT or LINES
+type SelectJSONInput struct {
+	Type string
+}
+
+// SelectCSVOutput describes the CSV records, QuoteFields is ALWAYS or ASNEEDED
+type SelectCSVOutput struct {
+	QuoteFields          string
+	RecordDelimiter      string
+	FieldDelimiter       string
+	QuoteCharacter       string
+	QuoteEscapeCharacter string
+}
+
+// SelectJSONOutput describes the JSON records
+type SelectJSONOutput struct {
+	RecordDelimiter string
+}
+
+// selectStats is the payload of the Stats and Progress messages
+type selectStats struct {
+	XMLName        xml.Name
+	BytesScanned   int64
+	BytesProcessed int64
+	BytesReturned  int64
+}
+
+// orDefault returns def when s is empty
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// decompress returns the uncompressed object data
+func (req *SelectRequest) decompress(data []byte) ([]byte, error) {
+	var r io.Reader = bytes.NewReader(data)
+	switch strings.ToUpper(req.InputSerialization.CompressionType) {
+	case "", "NONE":
+		return data, nil
+	case "GZIP":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, ErrInvalidCompressionFormat
+		}
+		r = gz
+	case "BZIP2":
+		r = bzip2.NewReader(r)
+	default:
+		return nil, ErrInvalidCompressionFormat
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return nil, ErrInvalidCompressionFormat
+	}
+	return plain, nil
+}
+
+// records returns the record reader of the uncompressed object data
+func (req *SelectRequest) records(query *sqlQuery, data []byte) (func() (sqlRecord, error), error) {
+	in := req.InputSerialization
+	switch {
+	case in.CSV != nil:
+		return csvRecords(in.CSV, data)
+	case in.JSON != nil:
+		return jsonRecords(in.JSON, query, data)
+	case in.Parquet != nil:
+		apiErr := ErrNotImplemented
+		apiErr.Description = "Parquet objects can not be selected."
+		return nil, apiErr
+	}
+	apiErr := ErrInvalidRequest
+	apiErr.Description = "The InputSerialization must have a CSV or JSON format."
+	return nil, apiErr
+}
+
+func csvRecords(in *SelectCSVInput, data []byte) (func() (sqlRecord, error), error) {
+	invalid := func(name, value string) error {
+		apiErr := ErrInvalidRequest
+		apiErr.Description = "Unsupported CSV " + name + " " + value
+		return apiErr
+	}
+
+	delimiter := orDefault(in.FieldDelimiter, ",")
+	if utf8.RuneCountInString(delimiter) != 1 {
+		return nil, invalid("FieldDelimiter", delimiter)
+	}
+	if quote := orDefault(in.QuoteCharacter, `"`); quote != `"` {
+		return nil, invalid("QuoteCharacter", quote)
+	}
+	if rd := orDefault(in.RecordDelimiter, "\n"); rd != "\n" && rd != "\r\n" {
+		data = bytes.ReplaceAll(data, []byte(rd), []byte("\n"))
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma, _ = utf8.DecodeRuneInString(delimiter)
+	if in.Comments != "" {
+		r.Comment, _ = utf8.DecodeRuneInString(in.Comments)
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var names []string
+	header := strings.ToUpper(in.FileHeaderInfo)
+	if header == "USE" || header == "IGNORE" {
+		record, err := r.Read()
+		if err != nil && err != io.EOF {
+			return nil, ErrCSVParsingError
+		}
+		if header == "USE" {
+			names = record
+		}
+	}
+	return func() (sqlRecord, error) {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil, err
+		}
+		if err != nil {
+			return nil, sqlError(ErrCSVParsingError, "%v", err)
+		}
+		return &csvRecord{names: names, values: record}, nil
+	}, nil
+}
+
+func jsonRecords(in *SelectJSONInput, query *sqlQuery, data []byte) (func() (sqlRecord, error), error) {
+	switch strings.ToUpper(in.Type) {
+	case "", "DOCUMENT", "LINES":
+	default:
+		apiErr := ErrInvalidRequest
+		apiErr.Description = "Unsupported JSON Type " + in.Type
+		return nil, apiErr
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var pending []any
+	return func() (sqlRecord, error) {
+		for len(pending) == 0 {
+			v, err := decodeJSONValue(dec)
+			if err == io.EOF {
+				return nil, err
+			}
+			if err != nil {
+				return nil, sqlError(ErrJSONParsingError, "%v", err)
+			}
+			array, ok := v.([]any)
+			if !ok || !query.fromArray {
+				return &jsonRecord{value: v}, nil
+			}
+			pending = array
+		}
+		v := pending[0]
+		pending = pending[1:]
+		return &jsonRecord{value: v}, nil
+	}, nil
+}
+
+// writer returns the function writing the selected records to buf in the output format
+func (req *SelectRequest) writer(buf *bytes.Buffer) (func(names []string, values []any) error, error) {
+	out := req.OutputSerialization
+	switch {
+	case out.CSV != nil:
+		delimiter := orDefault(out.CSV.FieldDelimiter, ",")
+		rd := orDefault(out.CSV.RecordDelimiter, "\n")
+		quote := orDefault(out.CSV.QuoteCharacter, `"`)
+		escape := orDefault(out.CSV.QuoteEscapeCharacter, quote)
+		always := strings.EqualFold(out.CSV.QuoteFields, "ALWAYS")
+		return func(_ []string, values []any) error {
+			for i, v := range values {
+				if i > 0 {
+					buf.WriteString(delimiter)
+				}
+				s := formatSQLValue(v)
+				if always || strings.Contains(s, delimiter) || strings.Contains(s, quote) ||
+					strings.ContainsAny(s, "\r\n") {
+					s = quote + strings.ReplaceAll(s, quote, escape+quote) + quote
+				}
+				buf.WriteString(s)
+			}
+			buf.WriteString(rd)
+			return nil
+		}, nil
+	case out.JSON != nil:
+		rd := orDefault(out.JSON.RecordDelimiter, "\n")
+		return func(names []string, values []any) error {
+			obj := &jsonObject{}
+			for i, name := range names {
+				obj.set(name, values[i])
+			}
+			data, err := obj.MarshalJSON()
+			if err != nil {
+				return sqlError(ErrInvalidDataType, "%v", err)
+			}
+			buf.Write(data)
+			buf.WriteString(rd)
+			return nil
+		}, nil
+	}
+	apiErr := ErrInvalidRequest
+	apiErr.Description = "The OutputSerialization must have a CSV or JSON format."
+	return nil, apiErr
+}
+
+// run runs the request on the object data and returns the selected records and the stats
+func (req *SelectRequest) run(data []byte) ([]byte, *selectStats, error) {
+	if !strings.EqualFold(req.ExpressionType, "SQL") {
+		return nil, nil, ErrInvalidExpressionType
+	}
+	query, err := parseSQL(req.Expression)
+	if err != nil {
+		return nil, nil, err
+	}
+	plain, err := req.decompress(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	next, err := req.records(query, plain)
+	if err != nil {
+		return nil, nil, err
+	}
+	var buf bytes.Buffer
+	out, err := req.writer(&buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = query.run(next, out); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), &selectStats{
+		BytesScanned:   int64(len(data)),
+		BytesProcessed: int64(len(plain)),
+		BytesReturned:  int64(buf.Len()),
+	}, nil
+}
+
+// writeSelectMessage writes an event stream message with string headers given as name, value
+// pairs: the prelude of the total and headers lengths, its CRC32, the headers, the payload and
+// the CRC32 of the whole message
+func writeSelectMessage(buf *bytes.Buffer, payload []byte, headers ...string) {
+	var hdr bytes.Buffer
+	for i := 0; i+1 < len(headers); i += 2 {
+		hdr.WriteByte(byte(len(headers[i])))
+		hdr.WriteString(headers[i])
+		hdr.WriteByte(7) // string value type
+		_ = binary.Write(&hdr, binary.BigEndian, uint16(len(headers[i+1])))
+		hdr.WriteString(headers[i+1])
+	}
+
+	msg := make([]byte, 12, 16+hdr.Len()+len(payload))
+	binary.BigEndian.PutUint32(msg[0:], uint32(cap(msg)))
+	binary.BigEndian.PutUint32(msg[4:], uint32(hdr.Len()))
+	binary.BigEndian.PutUint32(msg[8:], crc32.ChecksumIEEE(msg[:8]))
+	msg = append(msg, hdr.Bytes()...)
+	msg = append(msg, payload...)
+	buf.Write(msg)
+	_ = binary.Write(buf, binary.BigEndian, crc32.ChecksumIEEE(msg))
+}
+
+// writeSelectEvent writes an event message of eventType
+func writeSelectEvent(buf *bytes.Buffer, eventType string, payload []byte) {
+	headers := []string{":message-type", "event", ":event-type", eventType}
+	switch eventType {
+	case "Records":
+		headers = append(headers, ":content-type", "application/octet-stream")
+	case "Stats", "Progress":
+		headers = append(headers, ":content-type", "text/xml")
+	}
+	writeSelectMessage(buf, payload, headers...)
+}
+
+// SelectObjectContent runs a SQL expression on a CSV or JSON object and streams the selected
+// records as Records messages followed by the Progress, Stats and End messages
+func (api *ApiServer) SelectObjectContent(ctx *gin.Context) {
+	bucket, object := ctx.Param("bucket"), ctx.Param("object")
+	var req = new(SelectRequest)
+	if err := decodeAny(io.LimitReader(ctx.Request.Body, maxSelectRequestSize), req); err != nil {
+		ErrResponse(ctx, object, bucket, ErrMalformedXML)
+		return
+	}
+	oi, err := api.GetMS().GetObject(bucket, object)
+	if err == nil {
+		err = oi.checkReadable(api.GetMS().now())
+	}
+	if err != nil {
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
+		return
+	}
+	records, stats, err := req.run(oi.Data)
+	if err != nil {
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
+		return
+	}
+
+	var buf bytes.Buffer
+	for len(records) > 0 {
+		n := len(records)
+		if n > selectRecordsSize {
+			n = selectRecordsSize
+		}
+		writeSelectEvent(&buf, "Records", records[:n])
+		records = records[n:]
+	}
+	if req.RequestProgress.Enabled {
+		stats.XMLName.Local = "Progress"
+		writeSelectEvent(&buf, "Progress", encodeAny(stats))
+	}
+	stats.XMLName.Local = "Stats"
+	writeSelectEvent(&buf, "Stats", encodeAny(stats))
+	writeSelectEvent(&buf, "End", nil)
+	ctx.Writer.Header().Set("Content-Type", "application/octet-stream")
+	SuccessResponse(ctx, http.StatusOK, buf.Bytes())
+}
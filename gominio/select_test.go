@@ -0,0 +1,145 @@
+package gominio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const selectCSV = `name,city,age
+alice,Paris,31
+bob,"Berlin, DE",25
+carol,Paris,47
+dave,Rome,19
+`
+
+const selectJSONLines = `{"name":"alice","address":{"city":"Paris"},"age":31}
+{"name":"bob","address":{"city":"Berlin"},"age":25}
+{"name":"carol","address":{"city":"Paris"},"age":47,"admin":true}
+`
+
+func TestSelectObjectContent(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	ctx := context.Background()
+	put := func(object string, data []byte) {
+		_, err := server.Client.PutObject(ctx, "test", object, bytes.NewReader(data), int64(len(data)),
+			minio.PutObjectOptions{})
+		server.NoError(err)
+	}
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, err := w.Write([]byte(selectCSV))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	put("people.csv", []byte(selectCSV))
+	put("people.csv.gz", gz.Bytes())
+	put("people.json", []byte(selectJSONLines))
+	put("people.doc.json", []byte(`[{"name":"erin","age":52},{"name":"frank","age":8}]`))
+
+	csvInput := minio.SelectObjectInputSerialization{
+		CSV: &minio.CSVInputOptions{FileHeaderInfo: minio.CSVFileHeaderInfoUse},
+	}
+	csvOutput := minio.SelectObjectOutputSerialization{CSV: &minio.CSVOutputOptions{}}
+	jsonOutput := minio.SelectObjectOutputSerialization{JSON: &minio.JSONOutputOptions{}}
+	query := func(object, expression string, in minio.SelectObjectInputSerialization,
+		out minio.SelectObjectOutputSerialization) (string, *minio.SelectResults) {
+		res, err := server.Client.SelectObjectContent(ctx, "test", object, minio.SelectObjectOptions{
+			Expression:          expression,
+			ExpressionType:      minio.QueryExpressionTypeSQL,
+			InputSerialization:  in,
+			OutputSerialization: out,
+		})
+		require.NoError(t, err)
+		data, err := io.ReadAll(res)
+		require.NoError(t, err)
+		require.NoError(t, res.Close())
+		return string(data), res
+	}
+
+	for _, tc := range []struct {
+		expression string
+		records    string
+	}{
+		{"SELECT * FROM S3Object", "alice,Paris,31\nbob,\"Berlin, DE\",25\ncarol,Paris,47\ndave,Rome,19\n"},
+		{"SELECT name FROM S3Object s WHERE s.city = 'Paris' AND age > 40", "carol\n"},
+		{"select s.name, s.age from S3Object s where city like 'B%' or age < 20", "bob,25\ndave,19\n"},
+		{"SELECT name FROM S3Object WHERE name NOT LIKE '%a%' LIMIT 1", "bob\n"},
+		{"SELECT _1, UPPER(_2) FROM S3Object WHERE CAST(age AS INT) BETWEEN 20 AND 40", "alice,PARIS\nbob,\"BERLIN, DE\"\n"},
+		{"SELECT COUNT(*), SUM(age), AVG(age), MIN(age), MAX(name) FROM S3Object", "4,122,30.5,19,dave\n"},
+		{"SELECT COUNT(*) FROM S3Object WHERE city IN ('Rome', 'Paris')", "3\n"},
+		{"SELECT age * 2 AS double FROM S3Object LIMIT 2", "62\n50\n"},
+		// runs of % do not backtrack exponentially
+		{"SELECT name FROM S3Object WHERE '" + strings.Repeat("a", 48) + "' LIKE '" + strings.Repeat("%", 24) + "b'", ""},
+		{"SELECT name FROM S3Object WHERE '" + strings.Repeat("a", 47) + "b' LIKE '%%a%_%b' LIMIT 1", "alice\n"},
+		{"SELECT name FROM S3Object WHERE '50%_off' LIKE '%!%!_o%' ESCAPE '!' LIMIT 1", "alice\n"},
+		{"SELECT name FROM S3Object WHERE '50% off' LIKE '%!%!_o%' ESCAPE '!' LIMIT 1", ""},
+	} {
+		records, _ := query("people.csv", tc.expression, csvInput, csvOutput)
+		require.Equal(t, tc.records, records, tc.expression)
+	}
+
+	// compressed objects report their compressed and uncompressed sizes
+	gzInput := csvInput
+	gzInput.CompressionType = minio.SelectCompressionGZIP
+	records, res := query("people.csv.gz", "SELECT name, age FROM S3Object WHERE age >= 31", gzInput, jsonOutput)
+	require.Equal(t, "{\"name\":\"alice\",\"age\":\"31\"}\n{\"name\":\"carol\",\"age\":\"47\"}\n", records)
+	stats := res.Stats()
+	require.Equal(t, int64(gz.Len()), stats.BytesScanned)
+	require.Equal(t, int64(len(selectCSV)), stats.BytesProcessed)
+	require.Equal(t, int64(len(records)), stats.BytesReturned)
+
+	jsonLines := minio.SelectObjectInputSerialization{JSON: &minio.JSONInputOptions{Type: minio.JSONLinesType}}
+	records, _ = query("people.json", "SELECT * FROM S3Object s WHERE s.address.city = 'Paris'", jsonLines, jsonOutput)
+	require.Equal(t, `{"name":"alice","address":{"city":"Paris"},"age":31}
+{"name":"carol","address":{"city":"Paris"},"age":47,"admin":true}
+`, records)
+	records, _ = query("people.json", "SELECT s.name, s.address.city FROM S3Object s WHERE s.admin IS NULL", jsonLines, csvOutput)
+	require.Equal(t, "alice,Paris\nbob,Berlin\n", records)
+	records, _ = query("people.json", "SELECT MAX(age) AS oldest FROM S3Object", jsonLines, jsonOutput)
+	require.Equal(t, "{\"oldest\":47}\n", records)
+
+	jsonDocument := minio.SelectObjectInputSerialization{JSON: &minio.JSONInputOptions{Type: minio.JSONDocumentType}}
+	records, _ = query("people.doc.json", "SELECT name FROM S3Object[*] WHERE age > 10", jsonDocument, jsonOutput)
+	require.Equal(t, "{\"name\":\"erin\"}\n", records)
+
+	// invalid queries are rejected before the event stream starts
+	for expression, code := range map[string]string{
+		"SELECT name FROM":                        "ParseUnexpectedToken",
+		"SELECT name FROM S3Object WHERE":         "ParseUnexpectedToken",
+		"SELECT name, COUNT(*) FROM S3Object":     "UnsupportedSqlOperation",
+		"SELECT REVERSE(name) FROM S3Object":      "UnsupportedSqlOperation",
+		"SELECT CAST(name AS INT) FROM S3Object":  "CastFailed",
+		"SELECT SUM(city) FROM S3Object":          "InvalidDataType",
+		"SELECT name FROM S3Object WHERE 'a' = '": "ParseUnexpectedToken",
+	} {
+		_, err = server.Client.SelectObjectContent(ctx, "test", "people.csv", minio.SelectObjectOptions{
+			Expression:          expression,
+			ExpressionType:      minio.QueryExpressionTypeSQL,
+			InputSerialization:  csvInput,
+			OutputSerialization: csvOutput,
+		})
+		require.Error(t, err, expression)
+		require.Equal(t, code, minio.ToErrorResponse(err).Code, expression)
+	}
+	_, err = server.Client.SelectObjectContent(ctx, "test", "people.csv", minio.SelectObjectOptions{
+		Expression:          "SELECT * FROM S3Object",
+		ExpressionType:      minio.QueryExpressionTypeSQL,
+		InputSerialization:  gzInput,
+		OutputSerialization: csvOutput,
+	})
+	require.Equal(t, "InvalidCompressionFormat", minio.ToErrorResponse(err).Code)
+	_, err = server.Client.SelectObjectContent(ctx, "test", "missing.csv", minio.SelectObjectOptions{
+		Expression:          "SELECT * FROM S3Object",
+		ExpressionType:      minio.QueryExpressionTypeSQL,
+		InputSerialization:  csvInput,
+		OutputSerialization: csvOutput,
+	})
+	require.Equal(t, "NoSuchKey", minio.ToErrorResponse(err).Code)
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpSelectObjectContent, Status: http.StatusOK}, 17)
+}
@@ -2,6 +2,8 @@ package gominio
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"log"
@@ -18,6 +20,38 @@ type ServerConfig struct {
 	Access string
 	Secret string
 	Port   int
+
+	// Region is the region of the server, requests must be signed for the region of the
+	// bucket they address when it is set. Buckets are created in us-east-1 when it is empty.
+	Region string
+
+	// LenientNames accepts bucket names MinIO accepts but S3 rejects, like uppercase names
+	LenientNames bool
+
+	// MinPartSize is the minimum size of the non-final parts of multipart uploads, 5 MiB when
+	// zero. Tests may lower it to exercise multipart uploads with small payloads.
+	MinPartSize int64
+
+	// RestoreDelay is the time restoring an archived object takes and Clock the time source of
+	// the server, time.Now when nil. Tests may advance Clock to complete restores.
+	RestoreDelay time.Duration
+	Clock        func() time.Time
+
+	// Domain enables virtual-hosted-style requests addressed to bucket.<Domain>
+	Domain string
+	// WebsiteDomain enables the website endpoints of the buckets addressed to bucket.<WebsiteDomain>,
+	// they serve anonymous reads allowed by the bucket policy
+	WebsiteDomain string
+
+	// TLS serves HTTPS with the CertFile and KeyFile key pair, or with a certificate issued by an
+	// auto-generated in-memory CA when they are empty
+	TLS      bool
+	CertFile string
+	KeyFile  string
+	// RequireClientCert enables mutual TLS, client certificates are verified against ClientCAFile
+	// or against the auto-generated CA
+	RequireClientCert bool
+	ClientCAFile      string
 }
 
 type Server struct {
@@ -27,6 +61,10 @@ type Server struct {
 	router *gin.Engine
 	server *http.Server
 	done   chan struct{}
+
+	tlsConfig *tls.Config
+	certPool  *x509.CertPool
+	ca        *certAuthority
 }
 
 func NewServer(cfg *ServerConfig) (*Server, error) {
@@ -44,17 +82,36 @@ func NewServer(cfg *ServerConfig) (*Server, error) {
 func (s *Server) Start() (int, error) {
 	// New minio server
 	s.minio = NewMinioServer(s.config.Access, s.config.Secret)
+	s.minio.Region = s.config.Region
+	s.minio.LenientNames = s.config.LenientNames
+	s.minio.MinPartSize = s.config.MinPartSize
+	s.minio.RestoreDelay = s.config.RestoreDelay
+	s.minio.Clock = s.config.Clock
 
 	// Define routes
 	s.api = RegisterApiRouter(s.router, s.minio)
+	s.api.SetDomain(s.config.Domain)
+	s.api.SetWebsiteDomain(s.config.WebsiteDomain)
 
 	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", s.config.Port))
 	if err != nil {
 		return 0, err
 	}
 
-	s.server = &http.Server{Handler: s.router}
+	if s.config.TLS {
+		if err = s.setupTLS(); err != nil {
+			_ = ln.Close()
+			return 0, err
+		}
+		ln = tls.NewListener(ln, s.tlsConfig)
+	}
+
+	s.server = &http.Server{Handler: s.api}
+	// streaming requests like ListenBucketNotification end when the event bus is closed
+	s.server.RegisterOnShutdown(s.minio.GetEvents().Close)
+	s.server.RegisterOnShutdown(s.minio.GetReplicator().Close)
 	go func() {
+		defer close(s.done)
 		if err := s.server.Serve(ln); err != nil && err != http.ErrServerClosed {
 			log.Printf("Error serving requests: %v", err)
 		}
@@ -68,8 +125,46 @@ func (s *Server) Start() (int, error) {
 	return addr.Port, nil
 }
 
+// GetMS returns the minio server backing s, it is nil before Start is called.
+func (s *Server) GetMS() *MinioServer {
+	return s.minio
+}
+
+// GetApi returns the api server handling requests, it is nil before Start is called.
+func (s *Server) GetApi() *ApiServer {
+	return s.api
+}
+
+// GetJournal returns the journal of requests served since Start, it is nil before Start is called.
+func (s *Server) GetJournal() *Journal {
+	if s.api == nil {
+		return nil
+	}
+	return s.api.GetJournal()
+}
+
+// GetFaults returns the fault rules applied to requests since Start, it is nil before Start is called.
+func (s *Server) GetFaults() *FaultInjector {
+	if s.api == nil {
+		return nil
+	}
+	return s.api.GetFaults()
+}
+
+// GetShaper returns the latency and throughput rules applied to requests since Start, it is nil before Start is called.
+func (s *Server) GetShaper() *Shaper {
+	if s.api == nil {
+		return nil
+	}
+	return s.api.GetShaper()
+}
+
 // Close shuts down the server.
 func (s *Server) Close() error {
+	if s.server == nil {
+		return nil
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -3,12 +3,16 @@ package gominio
 import (
 	"bytes"
 	"context"
+	"encoding/xml"
 	"fmt"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/minio/minio-go/v7/pkg/tags"
 	"github.com/stretchr/testify/require"
 	"io"
+	"net"
+	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -19,6 +23,9 @@ func TestServerStart(t *testing.T) {
 	}
 	srv, err := NewServer(cfg)
 	require.NoError(t, err)
+	require.Nil(t, srv.GetJournal())
+	require.Nil(t, srv.GetFaults())
+	require.Nil(t, srv.GetShaper())
 
 	// Start server
 	port, err := srv.Start()
@@ -28,36 +35,12 @@ func TestServerStart(t *testing.T) {
 	require.Greater(t, port, 0)
 }
 
-func newServer(access, secret string) (*Server, error) {
-	cfg := &ServerConfig{
-		Access: access,
-		Secret: secret,
-		Port:   0,
-	}
-	srv, err := NewServer(cfg)
-	if err != nil {
-		return srv, err
-	}
-
-	// Start server
-	port, err := srv.Start()
-	srv.config.Port = port
-	return srv, err
-}
-
 func TestBucket(t *testing.T) {
-	server, err := newServer("minioadmin", "minioadmin")
-	require.NoError(t, err)
-	defer server.Close()
-
-	// Initialize minio client object.
-	minioClient, err := minio.New(fmt.Sprintf("127.0.0.1:%d", server.config.Port), &minio.Options{
-		Creds: credentials.NewStaticV4("minioadmin", "minioadmin", ""),
-	})
-	require.NoError(t, err)
+	server := NewTestServer(t)
+	minioClient := server.Client
 
 	// Test make bucket
-	err = minioClient.MakeBucket(context.Background(), "test", minio.MakeBucketOptions{})
+	err := minioClient.MakeBucket(context.Background(), "test", minio.MakeBucketOptions{})
 	require.NoError(t, err)
 
 	err = minioClient.MakeBucket(context.Background(), "test1", minio.MakeBucketOptions{})
@@ -114,18 +97,11 @@ func TestBucket(t *testing.T) {
 }
 
 func TestObject(t *testing.T) {
-	server, err := newServer("minioadmin", "minioadmin")
-	require.NoError(t, err)
-	defer server.Close()
-
-	// Initialize minio client object.
-	minioClient, err := minio.New(fmt.Sprintf("127.0.0.1:%d", server.config.Port), &minio.Options{
-		Creds: credentials.NewStaticV4("minioadmin", "minioadmin", ""),
-	})
-	require.NoError(t, err)
+	server := NewTestServer(t)
+	minioClient := server.Client
 
 	// make bucket
-	err = minioClient.MakeBucket(context.Background(), "test", minio.MakeBucketOptions{})
+	err := minioClient.MakeBucket(context.Background(), "test", minio.MakeBucketOptions{})
 	require.NoError(t, err)
 
 	// test put object
@@ -149,10 +125,9 @@ func TestObject(t *testing.T) {
 	require.Equal(t, content, string(data))
 	t.Log(string(data))
 
-	// test delete object
+	// test delete object, deleting a missing key succeeds like S3
 	err = minioClient.RemoveObject(context.Background(), "test", "hello1.txt", minio.RemoveObjectOptions{})
-	require.NotNil(t, err)
-	t.Log(err)
+	require.NoError(t, err)
 
 	err = minioClient.RemoveObject(context.Background(), "test", "hello.txt", minio.RemoveObjectOptions{})
 	require.NoError(t, err)
@@ -165,18 +140,11 @@ func TestObject(t *testing.T) {
 }
 
 func TestObjectOther(t *testing.T) {
-	server, err := newServer("minioadmin", "minioadmin")
-	require.NoError(t, err)
-	defer server.Close()
-
-	// Initialize minio client object.
-	minioClient, err := minio.New(fmt.Sprintf("127.0.0.1:%d", server.config.Port), &minio.Options{
-		Creds: credentials.NewStaticV4("minioadmin", "minioadmin", ""),
-	})
-	require.NoError(t, err)
+	server := NewTestServer(t)
+	minioClient := server.Client
 
 	// prepare bucket object
-	err = minioClient.MakeBucket(context.Background(), "test", minio.MakeBucketOptions{})
+	err := minioClient.MakeBucket(context.Background(), "test", minio.MakeBucketOptions{})
 	require.NoError(t, err)
 
 	content := `hello world`
@@ -212,3 +180,275 @@ func TestObjectOther(t *testing.T) {
 	require.NoError(t, err)
 	t.Log("after remove tag: ", tagx.String())
 }
+
+func TestNewTestServer(t *testing.T) {
+	server := NewTestServer(t, WithCredentials("access", "secret"), WithBuckets("test", "test1"))
+	require.Equal(t, "access", server.Access)
+	require.Equal(t, "secret", server.Secret)
+
+	bi, err := server.Client.ListBuckets(context.Background())
+	server.NoError(err)
+	require.Equal(t, 2, len(bi))
+
+	content := `hello world`
+	_, err = server.Client.PutObject(context.Background(), "test", "hello.txt",
+		bytes.NewBuffer([]byte(content)), int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err, "put object")
+	require.Contains(t, server.GetMS().Dump(), `object "hello.txt" size=11`)
+
+	rsp, err := server.HTTPClient.Get("http://" + server.Endpoint + "/test/hello.txt")
+	require.NoError(t, err)
+	data, err := io.ReadAll(rsp.Body)
+	_ = rsp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+}
+
+func TestVirtualHostStyle(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"), WithServerConfig(func(cfg *ServerConfig) {
+		cfg.Domain = "s3.local"
+	}))
+
+	// every bucket.s3.local host resolves to the test server
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, server.Endpoint)
+		},
+	}
+	t.Cleanup(transport.CloseIdleConnections)
+	_, port, err := net.SplitHostPort(server.Endpoint)
+	require.NoError(t, err)
+	client, err := minio.New("s3.local:"+port, &minio.Options{
+		Creds:        credentials.NewStaticV4(server.Access, server.Secret, ""),
+		Transport:    transport,
+		BucketLookup: minio.BucketLookupDNS,
+	})
+	require.NoError(t, err)
+
+	content := `hello world`
+	_, err = client.PutObject(context.Background(), "test", "dir/sub/hello.txt",
+		strings.NewReader(content), int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpPutObject, Bucket: "test", Key: "dir/sub/hello.txt"}, 1)
+
+	ok, err := client.BucketExists(context.Background(), "test")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// path-style requests keep working
+	oi, err := server.Client.GetObject(context.Background(), "test", "dir/sub/hello.txt", minio.GetObjectOptions{})
+	require.NoError(t, err)
+	data, err := io.ReadAll(oi)
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+
+	oi, err = client.GetObject(context.Background(), "test", "dir/sub/hello.txt", minio.GetObjectOptions{})
+	require.NoError(t, err)
+	data, err = io.ReadAll(oi)
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+server.Endpoint+"/dir/sub/hello.txt", nil)
+	require.NoError(t, err)
+	req.Host = "test.s3.local"
+	rsp, err := server.HTTPClient.Do(req)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	data, err = io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+}
+
+func TestRegion(t *testing.T) {
+	server := NewTestServer(t, WithServerConfig(func(cfg *ServerConfig) {
+		cfg.Region = "eu-west-1"
+	}))
+
+	// buckets without location constraint are created in the server region
+	require.True(t, server.GetMS().MakeBucket("test"))
+	err := server.Client.MakeBucket(context.Background(), "test1", minio.MakeBucketOptions{Region: "ap-south-1"})
+	server.NoError(err)
+
+	location, err := server.Client.GetBucketLocation(context.Background(), "test")
+	require.NoError(t, err)
+	require.Equal(t, "eu-west-1", location)
+	location, err = server.Client.GetBucketLocation(context.Background(), "test1")
+	require.NoError(t, err)
+	require.Equal(t, "ap-south-1", location)
+
+	content := `hello world`
+	_, err = server.Client.PutObject(context.Background(), "test1", "hello.txt",
+		strings.NewReader(content), int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+
+	req, err := http.NewRequest(http.MethodHead, "http://"+server.Endpoint+"/test1/", nil)
+	require.NoError(t, err)
+	rsp, err := server.HTTPClient.Do(req)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, "ap-south-1", rsp.Header.Get("x-amz-bucket-region"))
+
+	// malformed configurations are rejected instead of creating the bucket in the server region
+	req, err = http.NewRequest(http.MethodPut, "/test2/", strings.NewReader("<CreateBucketConfiguration><LocationConstraint>"))
+	require.NoError(t, err)
+	rsp, err = server.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(rsp.Body)
+	_ = rsp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	require.Contains(t, string(body), ErrMalformedXML.Code)
+	require.False(t, server.GetMS().BucketExists("test2"))
+
+	// a client pinned to another region is told the expected one
+	client, err := minio.New(server.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(server.Access, server.Secret, ""),
+		Region: "us-east-1",
+	})
+	require.NoError(t, err)
+	_, err = client.StatObject(context.Background(), "test1", "hello.txt", minio.StatObjectOptions{})
+	require.Error(t, err)
+	_, err = client.GetObjectTagging(context.Background(), "test1", "hello.txt", minio.GetObjectTaggingOptions{})
+	errRsp := minio.ToErrorResponse(err)
+	require.Equal(t, "AuthorizationHeaderMalformed", errRsp.Code)
+	require.Equal(t, "ap-south-1", errRsp.Region)
+
+	_, err = client.ListBuckets(context.Background())
+	require.NoError(t, err)
+}
+
+func TestBucketTagging(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	ctx := context.Background()
+
+	_, err := server.Client.GetBucketTagging(ctx, "test")
+	require.Equal(t, "NoSuchTagSet", minio.ToErrorResponse(err).Code)
+
+	tag, err := tags.MapToBucketTags(map[string]string{
+		"team":        "storage",
+		"cost-center": "42",
+	})
+	require.NoError(t, err)
+	server.NoError(server.Client.SetBucketTagging(ctx, "test", tag))
+
+	tagx, err := server.Client.GetBucketTagging(ctx, "test")
+	server.NoError(err)
+	require.Equal(t, tag.ToMap(), tagx.ToMap())
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpGetBucketTagging}, 2)
+
+	// tag sets breaking the S3 limits are rejected
+	putTagging := func(body string) string {
+		req, err := http.NewRequest(http.MethodPut, "/test/?tagging=", strings.NewReader(body))
+		require.NoError(t, err)
+		rsp, err := server.Do(req)
+		require.NoError(t, err)
+		defer rsp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+		var apiRsp APIErrorResponse
+		require.NoError(t, xml.NewDecoder(rsp.Body).Decode(&apiRsp))
+		return apiRsp.Code
+	}
+	var sb strings.Builder
+	for i := 0; i < 51; i++ {
+		fmt.Fprintf(&sb, "<Tag><Key>key%d</Key><Value>value</Value></Tag>", i)
+	}
+	require.Equal(t, "InvalidTag", putTagging("<Tagging><TagSet>"+sb.String()+"</TagSet></Tagging>"))
+	require.Equal(t, "InvalidTag", putTagging("<Tagging><TagSet><Tag><Key>"+strings.Repeat("k", 129)+
+		"</Key><Value>value</Value></Tag></TagSet></Tagging>"))
+	require.Equal(t, "MalformedXML", putTagging("<Tagging><TagSet>"))
+
+	tagx, err = server.Client.GetBucketTagging(ctx, "test")
+	server.NoError(err)
+	require.Equal(t, tag.ToMap(), tagx.ToMap())
+
+	server.NoError(server.Client.RemoveBucketTagging(ctx, "test"))
+	_, err = server.Client.GetBucketTagging(ctx, "test")
+	require.Equal(t, "NoSuchTagSet", minio.ToErrorResponse(err).Code)
+
+	err = server.Client.SetBucketTagging(ctx, "test1", tag)
+	require.Equal(t, "NoSuchBucket", minio.ToErrorResponse(err).Code)
+}
+
+func TestObjectTaggingHeader(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	ctx := context.Background()
+
+	content := `hello world`
+	userTags := map[string]string{"team": "storage", "path": "a/b c+d@e"}
+	_, err := server.Client.PutObject(ctx, "test", "hello.txt", strings.NewReader(content),
+		int64(len(content)), minio.PutObjectOptions{UserTags: userTags})
+	server.NoError(err)
+
+	stat, err := server.Client.StatObject(ctx, "test", "hello.txt", minio.StatObjectOptions{})
+	server.NoError(err)
+	require.Equal(t, 2, stat.UserTagCount)
+	tagx, err := server.Client.GetObjectTagging(ctx, "test", "hello.txt", minio.GetObjectTaggingOptions{})
+	server.NoError(err)
+	require.Equal(t, userTags, tagx.ToMap())
+
+	// the tags are validated like the tagging API does
+	putObject := func(tagging string) string {
+		req, err := http.NewRequest(http.MethodPut, "/test/bad.txt", strings.NewReader(content))
+		require.NoError(t, err)
+		req.Header.Set("x-amz-tagging", tagging)
+		rsp, err := server.Do(req)
+		require.NoError(t, err)
+		defer rsp.Body.Close()
+		var apiRsp APIErrorResponse
+		_ = xml.NewDecoder(rsp.Body).Decode(&apiRsp)
+		return apiRsp.Code
+	}
+	var pairs []string
+	for i := 0; i < 11; i++ {
+		pairs = append(pairs, fmt.Sprintf("key%d=value", i))
+	}
+	require.Equal(t, "InvalidTag", putObject(strings.Join(pairs, "&")))
+	require.Equal(t, "InvalidTag", putObject("key=a&key=b"))
+	require.Equal(t, "InvalidTag", putObject(strings.Repeat("k", 129)+"=value"))
+	require.Equal(t, "", putObject(strings.Join(pairs[:10], "&")))
+
+	// tags given on upload creation survive the completion
+	core := minio.Core{Client: server.Client}
+	id, err := core.NewMultipartUpload(ctx, "test", "multi.txt", minio.PutObjectOptions{UserTags: userTags})
+	server.NoError(err)
+	part, err := core.PutObjectPart(ctx, "test", "multi.txt", id, 1,
+		strings.NewReader(content), int64(len(content)), minio.PutObjectPartOptions{})
+	server.NoError(err)
+	_, err = core.CompleteMultipartUpload(ctx, "test", "multi.txt", id,
+		[]minio.CompletePart{{PartNumber: 1, ETag: part.ETag}}, minio.PutObjectOptions{})
+	server.NoError(err)
+	tagx, err = server.Client.GetObjectTagging(ctx, "test", "multi.txt", minio.GetObjectTaggingOptions{})
+	server.NoError(err)
+	require.Equal(t, userTags, tagx.ToMap())
+
+	// copies keep the source tags unless they are replaced
+	_, err = server.Client.CopyObject(ctx, minio.CopyDestOptions{Bucket: "test", Object: "copy.txt"},
+		minio.CopySrcOptions{Bucket: "test", Object: "hello.txt"})
+	server.NoError(err)
+	tagx, err = server.Client.GetObjectTagging(ctx, "test", "copy.txt", minio.GetObjectTaggingOptions{})
+	server.NoError(err)
+	require.Equal(t, userTags, tagx.ToMap())
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpCopyObject}, 1)
+
+	_, err = server.Client.CopyObject(ctx, minio.CopyDestOptions{
+		Bucket:      "test",
+		Object:      "replaced.txt",
+		UserTags:    map[string]string{"copy": "true"},
+		ReplaceTags: true,
+	}, minio.CopySrcOptions{Bucket: "test", Object: "hello.txt"})
+	server.NoError(err)
+	stat, err = server.Client.StatObject(ctx, "test", "replaced.txt", minio.StatObjectOptions{})
+	server.NoError(err)
+	require.Equal(t, 1, stat.UserTagCount)
+
+	oi, err := server.Client.GetObject(ctx, "test", "replaced.txt", minio.GetObjectOptions{})
+	require.NoError(t, err)
+	data, err := io.ReadAll(oi)
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+
+	_, err = server.Client.CopyObject(ctx, minio.CopyDestOptions{Bucket: "test", Object: "copy.txt"},
+		minio.CopySrcOptions{Bucket: "test", Object: "missing.txt"})
+	require.Equal(t, "NoSuchKey", minio.ToErrorResponse(err).Code)
+}
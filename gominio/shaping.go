@@ -0,0 +1,210 @@
+package gominio
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyFunc returns the delay applied to a request before it is handled
+type LatencyFunc func() time.Duration
+
+// FixedLatency delays every request by d
+func FixedLatency(d time.Duration) LatencyFunc {
+	return func() time.Duration {
+		return d
+	}
+}
+
+// UniformLatency delays requests by a uniformly distributed duration in [min, max)
+func UniformLatency(min, max time.Duration) LatencyFunc {
+	return func() time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(rand.Int63n(int64(max-min)))
+	}
+}
+
+// NormalLatency delays requests by a normally distributed duration, negative samples are clamped to zero
+func NormalLatency(mean, stddev time.Duration) LatencyFunc {
+	return func() time.Duration {
+		d := time.Duration(rand.NormFloat64()*float64(stddev)) + mean
+		if d < 0 {
+			return 0
+		}
+		return d
+	}
+}
+
+// ShapingRule describes the latency and throughput of requests, zero values disable the shaping
+type ShapingRule struct {
+	Latency             LatencyFunc
+	UploadBytesPerSec   int64
+	DownloadBytesPerSec int64
+}
+
+// Shaper holds the shaping rules per operation, the rule of the empty operation applies to every other one
+type Shaper struct {
+	sync.RWMutex
+	rules map[string]ShapingRule
+}
+
+func NewShaper() *Shaper {
+	return &Shaper{
+		rules: make(map[string]ShapingRule),
+	}
+}
+
+// Set sets the shaping rule of operation op, the empty operation sets the default rule
+func (sh *Shaper) Set(op string, rule ShapingRule) {
+	sh.Lock()
+	defer sh.Unlock()
+
+	sh.rules[op] = rule
+}
+
+// Remove removes the shaping rule of operation op
+func (sh *Shaper) Remove(op string) {
+	sh.Lock()
+	defer sh.Unlock()
+
+	delete(sh.rules, op)
+}
+
+// Reset removes all shaping rules
+func (sh *Shaper) Reset() {
+	sh.Lock()
+	defer sh.Unlock()
+
+	sh.rules = make(map[string]ShapingRule)
+}
+
+func (sh *Shaper) rule(op string) (ShapingRule, bool) {
+	sh.RLock()
+	defer sh.RUnlock()
+
+	if rule, ok := sh.rules[op]; ok {
+		return rule, true
+	}
+	rule, ok := sh.rules[""]
+	return rule, ok
+}
+
+// throttle paces a stream of bytes to a fixed rate
+type throttle struct {
+	ctx   context.Context
+	rate  int64
+	start time.Time
+	total int64
+}
+
+func newThrottle(ctx context.Context, rate int64) *throttle {
+	return &throttle{ctx: ctx, rate: rate, start: time.Now()}
+}
+
+// chunk returns the largest amount of bytes to transfer at once, a tenth of a second worth of data
+func (th *throttle) chunk() int {
+	if n := th.rate / 10; n > 0 {
+		return int(n)
+	}
+	return 1
+}
+
+// wait blocks until n more bytes may have been transferred at the throttle rate
+func (th *throttle) wait(n int) error {
+	th.total += int64(n)
+	due := th.start.Add(time.Duration(float64(th.total) / float64(th.rate) * float64(time.Second)))
+	return sleepContext(th.ctx, time.Until(due))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type throttledReader struct {
+	io.ReadCloser
+	th *throttle
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > tr.th.chunk() {
+		p = p[:tr.th.chunk()]
+	}
+	n, err := tr.ReadCloser.Read(p)
+	if werr := tr.th.wait(n); werr != nil && err == nil {
+		err = werr
+	}
+	return n, err
+}
+
+type throttledWriter struct {
+	gin.ResponseWriter
+	th *throttle
+}
+
+func (tw *throttledWriter) Write(data []byte) (int, error) {
+	var written int
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > tw.th.chunk() {
+			chunk = chunk[:tw.th.chunk()]
+		}
+		n, err := tw.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		tw.ResponseWriter.Flush()
+		if err = tw.th.wait(n); err != nil {
+			return written, err
+		}
+		data = data[n:]
+	}
+	return written, nil
+}
+
+func (tw *throttledWriter) WriteString(s string) (int, error) {
+	return tw.Write([]byte(s))
+}
+
+// shapingMiddleware delays the request and caps the throughput of its bodies
+func (api *ApiServer) shapingMiddleware(ctx *gin.Context) {
+	if strings.HasPrefix(ctx.FullPath(), controlPrefix) {
+		return
+	}
+
+	rule, ok := api.shaper.rule(GetOperation(ctx))
+	if !ok {
+		return
+	}
+
+	reqCtx := ctx.Request.Context()
+	if rule.Latency != nil {
+		if err := sleepContext(reqCtx, rule.Latency()); err != nil {
+			ctx.Abort()
+			return
+		}
+	}
+	if rule.UploadBytesPerSec > 0 && ctx.Request.Body != nil {
+		ctx.Request.Body = &throttledReader{ReadCloser: ctx.Request.Body, th: newThrottle(reqCtx, rule.UploadBytesPerSec)}
+	}
+	if rule.DownloadBytesPerSec > 0 {
+		ctx.Writer = &throttledWriter{ResponseWriter: ctx.Writer, th: newThrottle(reqCtx, rule.DownloadBytesPerSec)}
+	}
+}
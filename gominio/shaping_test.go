@@ -0,0 +1,51 @@
+package gominio
+
+import (
+	"bytes"
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestShaping(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	shaper := server.GetShaper()
+
+	shaper.Set(OpHeadBucket, ShapingRule{Latency: FixedLatency(200 * time.Millisecond)})
+	start := time.Now()
+	_, err := server.Client.BucketExists(context.Background(), "test")
+	server.NoError(err)
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	// the request gives up before the latency elapses
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = server.Client.BucketExists(ctx, "test")
+	require.Error(t, err)
+	shaper.Remove(OpHeadBucket)
+
+	content := bytes.Repeat([]byte("a"), 64<<10)
+	shaper.Set(OpPutObject, ShapingRule{UploadBytesPerSec: 256 << 10})
+	start = time.Now()
+	_, err = server.Client.PutObject(context.Background(), "test", "a.bin",
+		bytes.NewReader(content), int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	shaper.Reset()
+	shaper.Set("", ShapingRule{DownloadBytesPerSec: 256 << 10})
+	start = time.Now()
+	obj, err := server.Client.GetObject(context.Background(), "test", "a.bin", minio.GetObjectOptions{})
+	server.NoError(err)
+	data, err := io.ReadAll(obj)
+	server.NoError(err)
+	require.Len(t, data, len(content))
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	d := UniformLatency(10*time.Millisecond, 20*time.Millisecond)()
+	require.True(t, d >= 10*time.Millisecond && d < 20*time.Millisecond)
+	require.GreaterOrEqual(t, NormalLatency(0, time.Millisecond)(), time.Duration(0))
+}
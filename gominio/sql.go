@@ -0,0 +1,1285 @@
+package gominio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// sqlError returns apiErr with a description of the failing query part
+func sqlError(apiErr APIError, format string, args ...any) error {
+	apiErr.Description = fmt.Sprintf(format, args...)
+	return apiErr
+}
+
+// Kinds of the tokens of a SQL expression
+const (
+	sqlEOF = iota
+	sqlIdent
+	sqlQuotedIdent
+	sqlString
+	sqlNumber
+	sqlSymbol
+)
+
+// sqlSymbols are the operators and punctuation of a SQL expression, longest first
+var sqlSymbols = []string{"<=", ">=", "<>", "!=", "=", "<", ">", "*", ",", "(", ")", ".", "[", "]", "+", "-", "/", "%"}
+
+// sqlKeywords can not be used as aliases without quoting
+var sqlKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "LIMIT": true, "AS": true, "AND": true, "OR": true,
+	"NOT": true, "LIKE": true, "ESCAPE": true, "IS": true, "NULL": true, "TRUE": true, "FALSE": true,
+	"BETWEEN": true, "IN": true, "CAST": true,
+}
+
+type sqlToken struct {
+	kind int
+	text string
+}
+
+func isSQLDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSQLIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// lexSQL splits a SQL expression into its tokens
+func lexSQL(s string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isSQLIdentStart(c):
+			j := i + 1
+			for j < len(s) && (isSQLIdentStart(s[j]) || isSQLDigit(s[j])) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlIdent, text: s[i:j]})
+			i = j
+		case isSQLDigit(c) || c == '.' && i+1 < len(s) && isSQLDigit(s[i+1]):
+			j := i
+			for j < len(s) && (isSQLDigit(s[j]) || s[j] == '.') {
+				j++
+			}
+			if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+				k := j + 1
+				if k < len(s) && (s[k] == '+' || s[k] == '-') {
+					k++
+				}
+				if k < len(s) && isSQLDigit(s[k]) {
+					for j = k; j < len(s) && isSQLDigit(s[j]); j++ {
+					}
+				}
+			}
+			tokens = append(tokens, sqlToken{kind: sqlNumber, text: s[i:j]})
+			i = j
+		case c == '\'' || c == '"':
+			text, n, ok := lexSQLQuoted(s[i:])
+			if !ok {
+				return nil, sqlError(ErrParseUnexpectedToken, "Unterminated quote at position %d.", i)
+			}
+			kind := sqlString
+			if c == '"' {
+				kind = sqlQuotedIdent
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: text})
+			i += n
+		default:
+			symbol := ""
+			for _, sym := range sqlSymbols {
+				if strings.HasPrefix(s[i:], sym) {
+					symbol = sym
+					break
+				}
+			}
+			if symbol == "" {
+				return nil, sqlError(ErrParseUnexpectedToken, "Unexpected character %q at position %d.", c, i)
+			}
+			tokens = append(tokens, sqlToken{kind: sqlSymbol, text: symbol})
+			i += len(symbol)
+		}
+	}
+	return append(tokens, sqlToken{kind: sqlEOF}), nil
+}
+
+// lexSQLQuoted reads the quoted text at the start of s, a doubled quote escapes the quote
+func lexSQLQuoted(s string) (string, int, bool) {
+	quote := s[0]
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != quote {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == quote {
+			sb.WriteByte(quote)
+			i++
+			continue
+		}
+		return sb.String(), i + 1, true
+	}
+	return "", 0, false
+}
+
+// sqlRecord is a record of the queried object
+type sqlRecord interface {
+	// lookup returns the value of a column path, nil when the record has none
+	lookup(path []string) any
+	// fields returns the column names and values of the record in order
+	fields() ([]string, []any)
+}
+
+// csvRecord is a CSV line, names are the header columns when the header is used
+type csvRecord struct {
+	names  []string
+	values []string
+}
+
+func (r *csvRecord) lookup(path []string) any {
+	if len(path) != 1 {
+		return nil
+	}
+	name := path[0]
+	if strings.HasPrefix(name, "_") {
+		if n, err := strconv.Atoi(name[1:]); err == nil {
+			if n >= 1 && n <= len(r.values) {
+				return r.values[n-1]
+			}
+			return nil
+		}
+	}
+	for _, match := range []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		strings.EqualFold,
+	} {
+		for i, column := range r.names {
+			if match(column, name) && i < len(r.values) {
+				return r.values[i]
+			}
+		}
+	}
+	return nil
+}
+
+func (r *csvRecord) fields() ([]string, []any) {
+	names := make([]string, len(r.values))
+	values := make([]any, len(r.values))
+	for i, v := range r.values {
+		if i < len(r.names) {
+			names[i] = r.names[i]
+		} else {
+			names[i] = "_" + strconv.Itoa(i+1)
+		}
+		values[i] = v
+	}
+	return names, values
+}
+
+// jsonObject is a JSON object keeping the order of its keys
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *jsonObject) set(key string, value any) {
+	if o.values == nil {
+		o.values = make(map[string]any)
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *jsonObject) get(key string) any {
+	if v, ok := o.values[key]; ok {
+		return v
+	}
+	for _, k := range o.keys {
+		if strings.EqualFold(k, key) {
+			return o.values[k]
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes the object with its keys in order
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeJSONValue decodes the next JSON value of dec, objects are decoded as *jsonObject
+func decodeJSONValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := &jsonObject{values: make(map[string]any)}
+			for dec.More() {
+				tok, err = dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, ok := tok.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid object key %v", tok)
+				}
+				value, err := decodeJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj.set(key, value)
+			}
+			_, err = dec.Token()
+			return obj, err
+		case '[':
+			array := []any{}
+			for dec.More() {
+				value, err := decodeJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			}
+			_, err = dec.Token()
+			return array, err
+		}
+		return nil, fmt.Errorf("unexpected delimiter %v", t)
+	default:
+		return t, nil
+	}
+}
+
+// jsonRecord is a JSON value, object keys are its columns
+type jsonRecord struct {
+	value any
+}
+
+func (r *jsonRecord) lookup(path []string) any {
+	v := r.value
+	for _, key := range path {
+		obj, ok := v.(*jsonObject)
+		if !ok {
+			return nil
+		}
+		v = obj.get(key)
+	}
+	return v
+}
+
+func (r *jsonRecord) fields() ([]string, []any) {
+	obj, ok := r.value.(*jsonObject)
+	if !ok {
+		return []string{"_1"}, []any{r.value}
+	}
+	values := make([]any, len(obj.keys))
+	for i, k := range obj.keys {
+		values[i] = obj.values[k]
+	}
+	return obj.keys, values
+}
+
+// formatSQLValue returns the text of a value in CSV output
+func formatSQLValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// sqlToNumber converts numbers and numeric strings to a number
+func sqlToNumber(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// sqlToBool returns the truth value of v, ok is false for NULL and non boolean values
+func sqlToBool(v any) (value bool, ok bool) {
+	switch t := v.(type) {
+	case bool:
+		return t, true
+	case string:
+		b, err := strconv.ParseBool(t)
+		return b, err == nil
+	}
+	return false, false
+}
+
+// compareSQLValues compares a and b, strings are compared as numbers against numbers
+func compareSQLValues(a, b any) (int, bool) {
+	compareFloat := func(x, y float64) int {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	switch x := a.(type) {
+	case float64:
+		if y, ok := sqlToNumber(b); ok {
+			return compareFloat(x, y), true
+		}
+	case string:
+		switch y := b.(type) {
+		case string:
+			return strings.Compare(x, y), true
+		case float64:
+			if xf, ok := sqlToNumber(x); ok {
+				return compareFloat(xf, y), true
+			}
+		case bool:
+			if xb, ok := sqlToBool(x); ok {
+				return compareSQLValues(xb, y)
+			}
+		}
+	case bool:
+		y, ok := sqlToBool(b)
+		if !ok {
+			break
+		}
+		switch {
+		case x == y:
+			return 0, true
+		case !x:
+			return -1, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+// sqlExpr is an expression evaluated on a record
+type sqlExpr interface {
+	eval(rec sqlRecord) (any, error)
+}
+
+type sqlLiteral struct {
+	value any
+}
+
+func (e *sqlLiteral) eval(sqlRecord) (any, error) {
+	return e.value, nil
+}
+
+// sqlColumn references a column of the record, the path may start with the FROM alias
+type sqlColumn struct {
+	path  []string
+	query *sqlQuery
+}
+
+func (e *sqlColumn) eval(rec sqlRecord) (any, error) {
+	if rec == nil {
+		return nil, sqlError(ErrUnsupportedSQLOperation,
+			"Column %s can not be selected with aggregate functions.", strings.Join(e.path, "."))
+	}
+	path := e.path
+	if len(path) > 1 && (strings.EqualFold(path[0], e.query.alias) || strings.EqualFold(path[0], "S3Object")) {
+		path = path[1:]
+	}
+	return rec.lookup(path), nil
+}
+
+// name returns the output column name of the column
+func (e *sqlColumn) name() string {
+	return e.path[len(e.path)-1]
+}
+
+type sqlLogical struct {
+	and         bool
+	left, right sqlExpr
+}
+
+func (e *sqlLogical) eval(rec sqlRecord) (any, error) {
+	l, err := e.left.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	lb, lok := sqlToBool(l)
+	if lok && lb != e.and {
+		return lb, nil
+	}
+	r, err := e.right.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	rb, rok := sqlToBool(r)
+	if rok && rb != e.and {
+		return rb, nil
+	}
+	if lok && rok {
+		return e.and, nil
+	}
+	return nil, nil
+}
+
+type sqlNot struct {
+	expr sqlExpr
+}
+
+func (e *sqlNot) eval(rec sqlRecord) (any, error) {
+	v, err := e.expr.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := sqlToBool(v); ok {
+		return !b, nil
+	}
+	return nil, nil
+}
+
+type sqlCompare struct {
+	op          string
+	left, right sqlExpr
+}
+
+func (e *sqlCompare) eval(rec sqlRecord) (any, error) {
+	l, err := e.left.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.eval(rec)
+	if err != nil || l == nil || r == nil {
+		return nil, err
+	}
+	c, ok := compareSQLValues(l, r)
+	if !ok {
+		switch e.op {
+		case "=":
+			return false, nil
+		case "!=", "<>":
+			return true, nil
+		}
+		return nil, nil
+	}
+	switch e.op {
+	case "=":
+		return c == 0, nil
+	case "!=", "<>":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+type sqlLike struct {
+	expr, pattern, escape sqlExpr
+	not                   bool
+}
+
+func (e *sqlLike) eval(rec sqlRecord) (any, error) {
+	v, err := e.expr.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	p, err := e.pattern.eval(rec)
+	if err != nil || v == nil || p == nil {
+		return nil, err
+	}
+	escape := rune(-1)
+	if e.escape != nil {
+		esc, err := e.escape.eval(rec)
+		if err != nil {
+			return nil, err
+		}
+		s := formatSQLValue(esc)
+		if utf8.RuneCountInString(s) != 1 {
+			return nil, sqlError(ErrInvalidDataType, "The LIKE escape %q must be a single character.", s)
+		}
+		escape, _ = utf8.DecodeRuneInString(s)
+	}
+	matched := matchLike([]rune(formatSQLValue(p)), []rune(formatSQLValue(v)), escape)
+	return matched != e.not, nil
+}
+
+// matchLike matches s against a LIKE pattern where % matches any text and _ any character
+func matchLike(pattern, s []rune, escape rune) bool {
+	var p wildcardPattern
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == escape && i+1 < len(pattern):
+			i++
+			p = p.add(wildcardLiteral, pattern[i])
+		case c == '%':
+			p = p.add(wildcardAny, c)
+		case c == '_':
+			p = p.add(wildcardOne, c)
+		default:
+			p = p.add(wildcardLiteral, c)
+		}
+	}
+	return p.match(s)
+}
+
+type sqlIsNull struct {
+	expr sqlExpr
+	not  bool
+}
+
+func (e *sqlIsNull) eval(rec sqlRecord) (any, error) {
+	v, err := e.expr.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	return (v == nil) != e.not, nil
+}
+
+type sqlBetween struct {
+	expr, low, high sqlExpr
+	not             bool
+}
+
+func (e *sqlBetween) eval(rec sqlRecord) (any, error) {
+	v, err := (&sqlLogical{
+		and:   true,
+		left:  &sqlCompare{op: ">=", left: e.expr, right: e.low},
+		right: &sqlCompare{op: "<=", left: e.expr, right: e.high},
+	}).eval(rec)
+	if b, ok := sqlToBool(v); ok && err == nil {
+		return b != e.not, nil
+	}
+	return v, err
+}
+
+type sqlIn struct {
+	expr sqlExpr
+	list []sqlExpr
+	not  bool
+}
+
+func (e *sqlIn) eval(rec sqlRecord) (any, error) {
+	for _, item := range e.list {
+		v, err := (&sqlCompare{op: "=", left: e.expr, right: item}).eval(rec)
+		if err != nil || v == nil {
+			return v, err
+		}
+		if v.(bool) {
+			return !e.not, nil
+		}
+	}
+	return e.not, nil
+}
+
+type sqlArith struct {
+	op          string
+	left, right sqlExpr
+}
+
+func (e *sqlArith) eval(rec sqlRecord) (any, error) {
+	l, err := e.left.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.eval(rec)
+	if err != nil || l == nil || r == nil {
+		return nil, err
+	}
+	x, xok := sqlToNumber(l)
+	y, yok := sqlToNumber(r)
+	if !xok || !yok {
+		return nil, sqlError(ErrInvalidDataType, "The operands of %s must be numbers.", e.op)
+	}
+	switch e.op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	}
+	if y == 0 {
+		return nil, sqlError(ErrInvalidDataType, "Division by zero.")
+	}
+	if e.op == "%" {
+		return math.Mod(x, y), nil
+	}
+	return x / y, nil
+}
+
+type sqlNegate struct {
+	expr sqlExpr
+}
+
+func (e *sqlNegate) eval(rec sqlRecord) (any, error) {
+	v, err := e.expr.eval(rec)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	n, ok := sqlToNumber(v)
+	if !ok {
+		return nil, sqlError(ErrInvalidDataType, "The operand of - must be a number.")
+	}
+	return -n, nil
+}
+
+type sqlCast struct {
+	expr sqlExpr
+	typ  string
+}
+
+func (e *sqlCast) eval(rec sqlRecord) (any, error) {
+	v, err := e.expr.eval(rec)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	switch e.typ {
+	case "STRING", "VARCHAR", "CHAR":
+		return formatSQLValue(v), nil
+	case "BOOL", "BOOLEAN":
+		if b, ok := sqlToBool(v); ok {
+			return b, nil
+		}
+	case "INT", "INTEGER":
+		if n, ok := sqlToNumber(v); ok {
+			return math.Trunc(n), nil
+		}
+	default:
+		if n, ok := sqlToNumber(v); ok {
+			return n, nil
+		}
+	}
+	return nil, sqlError(ErrCastFailed, "Can not cast %q to %s.", formatSQLValue(v), e.typ)
+}
+
+type sqlFunction struct {
+	name string
+	arg  sqlExpr
+}
+
+func (e *sqlFunction) eval(rec sqlRecord) (any, error) {
+	v, err := e.arg.eval(rec)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	s := formatSQLValue(v)
+	switch e.name {
+	case "LOWER":
+		return strings.ToLower(s), nil
+	case "UPPER":
+		return strings.ToUpper(s), nil
+	case "TRIM":
+		return strings.TrimSpace(s), nil
+	}
+	return float64(utf8.RuneCountInString(s)), nil
+}
+
+// sqlAggregate accumulates the values of the matching records
+type sqlAggregate struct {
+	name  string
+	arg   sqlExpr // nil for COUNT(*)
+	count float64
+	sum   float64
+	best  any
+}
+
+// accumulate adds the value of rec to the aggregate, NULL values are skipped
+func (e *sqlAggregate) accumulate(rec sqlRecord) error {
+	if e.arg == nil {
+		e.count++
+		return nil
+	}
+	v, err := e.arg.eval(rec)
+	if err != nil || v == nil {
+		return err
+	}
+	switch e.name {
+	case "SUM", "AVG":
+		n, ok := sqlToNumber(v)
+		if !ok {
+			return sqlError(ErrInvalidDataType, "%s requires numbers, found %q.", e.name, formatSQLValue(v))
+		}
+		e.sum += n
+	case "MIN", "MAX":
+		if n, ok := sqlToNumber(v); ok {
+			v = n
+		}
+		if e.best == nil {
+			e.best = v
+			break
+		}
+		c, ok := compareSQLValues(v, e.best)
+		if !ok {
+			return sqlError(ErrInvalidDataType, "%s can not compare %q.", e.name, formatSQLValue(v))
+		}
+		if e.name == "MIN" && c < 0 || e.name == "MAX" && c > 0 {
+			e.best = v
+		}
+	}
+	e.count++
+	return nil
+}
+
+func (e *sqlAggregate) eval(sqlRecord) (any, error) {
+	switch e.name {
+	case "COUNT":
+		return e.count, nil
+	case "SUM":
+		if e.count == 0 {
+			return nil, nil
+		}
+		return e.sum, nil
+	case "AVG":
+		if e.count == 0 {
+			return nil, nil
+		}
+		return e.sum / e.count, nil
+	}
+	return e.best, nil
+}
+
+type sqlSelectItem struct {
+	expr  sqlExpr
+	alias string
+}
+
+// sqlQuery is a parsed S3 Select expression
+type sqlQuery struct {
+	items      []sqlSelectItem // nil for SELECT *
+	alias      string
+	fromArray  bool
+	where      sqlExpr
+	limit      int64
+	aggregates []*sqlAggregate
+}
+
+// run evaluates the query on the records returned by next until io.EOF and passes the
+// selected columns to out
+func (q *sqlQuery) run(next func() (sqlRecord, error), out func(names []string, values []any) error) error {
+	for n := int64(0); q.limit < 0 || n < q.limit; {
+		rec, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if q.where != nil {
+			v, err := q.where.eval(rec)
+			if err != nil {
+				return err
+			}
+			if b, ok := sqlToBool(v); !ok || !b {
+				continue
+			}
+		}
+		n++
+
+		if len(q.aggregates) > 0 {
+			for _, agg := range q.aggregates {
+				if err = agg.accumulate(rec); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		names, values, err := q.project(rec)
+		if err != nil {
+			return err
+		}
+		if err = out(names, values); err != nil {
+			return err
+		}
+	}
+
+	if len(q.aggregates) == 0 {
+		return nil
+	}
+	names, values, err := q.project(nil)
+	if err != nil {
+		return err
+	}
+	return out(names, values)
+}
+
+// project returns the selected columns of rec, rec is nil for the result of aggregates
+func (q *sqlQuery) project(rec sqlRecord) ([]string, []any, error) {
+	if q.items == nil {
+		names, values := rec.fields()
+		return names, values, nil
+	}
+	names := make([]string, len(q.items))
+	values := make([]any, len(q.items))
+	for i, item := range q.items {
+		v, err := item.expr.eval(rec)
+		if err != nil {
+			return nil, nil, err
+		}
+		names[i], values[i] = item.alias, v
+		if names[i] != "" {
+			continue
+		}
+		if col, ok := item.expr.(*sqlColumn); ok {
+			names[i] = col.name()
+		} else {
+			names[i] = "_" + strconv.Itoa(i+1)
+		}
+	}
+	return names, values, nil
+}
+
+type sqlParser struct {
+	tokens []sqlToken
+	pos    int
+	query  *sqlQuery
+	// inAggregate is set while parsing the argument of an aggregate function
+	inAggregate bool
+	// bareColumns is set when a column is referenced outside of an aggregate function
+	bareColumns bool
+}
+
+// parseSQL parses an S3 Select expression:
+// SELECT */items FROM S3Object[[*]] [[AS] alias] [WHERE condition] [LIMIT n]
+func parseSQL(expression string) (*sqlQuery, error) {
+	tokens, err := lexSQL(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{tokens: tokens, query: &sqlQuery{limit: -1}}
+	if err = p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	if !p.symbol("*") {
+		for {
+			item, err := p.parseSelectItem()
+			if err != nil {
+				return nil, err
+			}
+			p.query.items = append(p.query.items, item)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if len(p.query.aggregates) > 0 && p.bareColumns {
+		return nil, sqlError(ErrUnsupportedSQLOperation, "Aggregate functions can not be mixed with columns.")
+	}
+
+	if err = p.parseFrom(); err != nil {
+		return nil, err
+	}
+	if p.keyword("WHERE") {
+		aggregates := len(p.query.aggregates)
+		if p.query.where, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		if len(p.query.aggregates) != aggregates {
+			return nil, sqlError(ErrUnsupportedSQLOperation, "Aggregate functions are not allowed in WHERE.")
+		}
+	}
+	if p.keyword("LIMIT") {
+		tok := p.next()
+		limit, err := strconv.ParseInt(tok.text, 10, 64)
+		if tok.kind != sqlNumber || err != nil || limit < 0 {
+			return nil, p.unexpected(tok)
+		}
+		p.query.limit = limit
+	}
+	if tok := p.peek(); tok.kind != sqlEOF {
+		return nil, p.unexpected(tok)
+	}
+	return p.query, nil
+}
+
+func (p *sqlParser) peek() sqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sqlParser) next() sqlToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != sqlEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword consumes the next token when it is the keyword kw
+func (p *sqlParser) keyword(kw string) bool {
+	if tok := p.peek(); tok.kind == sqlIdent && strings.EqualFold(tok.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// symbol consumes the next token when it is the symbol sym
+func (p *sqlParser) symbol(sym string) bool {
+	if tok := p.peek(); tok.kind == sqlSymbol && tok.text == sym {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) unexpected(tok sqlToken) error {
+	if tok.kind == sqlEOF {
+		return sqlError(ErrParseUnexpectedToken, "Unexpected end of the SQL expression.")
+	}
+	return sqlError(ErrParseUnexpectedToken, "Unexpected token %q in the SQL expression.", tok.text)
+}
+
+func (p *sqlParser) expectKeyword(kw string) error {
+	if !p.keyword(kw) {
+		return p.unexpected(p.peek())
+	}
+	return nil
+}
+
+func (p *sqlParser) expectSymbol(sym string) error {
+	if !p.symbol(sym) {
+		return p.unexpected(p.peek())
+	}
+	return nil
+}
+
+// parseAlias parses an optional [AS] alias
+func (p *sqlParser) parseAlias() (string, error) {
+	explicit := p.keyword("AS")
+	tok := p.peek()
+	switch {
+	case tok.kind == sqlQuotedIdent || tok.kind == sqlIdent && !sqlKeywords[strings.ToUpper(tok.text)]:
+		p.pos++
+		return tok.text, nil
+	case explicit:
+		return "", p.unexpected(tok)
+	}
+	return "", nil
+}
+
+func (p *sqlParser) parseSelectItem() (sqlSelectItem, error) {
+	expr, err := p.parseExpr()
+	if err != nil {
+		return sqlSelectItem{}, err
+	}
+	alias, err := p.parseAlias()
+	return sqlSelectItem{expr: expr, alias: alias}, err
+}
+
+func (p *sqlParser) parseFrom() error {
+	if err := p.expectKeyword("FROM"); err != nil {
+		return err
+	}
+	if tok := p.next(); tok.kind != sqlIdent || !strings.EqualFold(tok.text, "S3Object") {
+		return p.unexpected(tok)
+	}
+	if p.symbol("[") {
+		if err := p.expectSymbol("*"); err != nil {
+			return err
+		}
+		if err := p.expectSymbol("]"); err != nil {
+			return err
+		}
+		p.query.fromArray = true
+	}
+	alias, err := p.parseAlias()
+	p.query.alias = alias
+	return err
+}
+
+func (p *sqlParser) parseExpr() (sqlExpr, error) {
+	return p.parseLogical(false)
+}
+
+// parseLogical parses the OR operands of a condition, or the AND operands when and is set
+func (p *sqlParser) parseLogical(and bool) (sqlExpr, error) {
+	operand := func() (sqlExpr, error) {
+		if and {
+			return p.parseNot()
+		}
+		return p.parseLogical(true)
+	}
+	op := "OR"
+	if and {
+		op = "AND"
+	}
+
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword(op) {
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlLogical{and: and, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseNot() (sqlExpr, error) {
+	if p.keyword("NOT") {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlNot{expr: expr}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *sqlParser) parseComparison() (sqlExpr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"<=", ">=", "<>", "!=", "=", "<", ">"} {
+		if p.symbol(op) {
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return &sqlCompare{op: op, left: left, right: right}, nil
+		}
+	}
+	if p.keyword("IS") {
+		not := p.keyword("NOT")
+		if err = p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		return &sqlIsNull{expr: left, not: not}, nil
+	}
+
+	not := p.keyword("NOT")
+	switch {
+	case p.keyword("LIKE"):
+		like := &sqlLike{expr: left, not: not}
+		if like.pattern, err = p.parseAdditive(); err != nil {
+			return nil, err
+		}
+		if p.keyword("ESCAPE") {
+			if like.escape, err = p.parseAdditive(); err != nil {
+				return nil, err
+			}
+		}
+		return like, nil
+	case p.keyword("BETWEEN"):
+		between := &sqlBetween{expr: left, not: not}
+		if between.low, err = p.parseAdditive(); err != nil {
+			return nil, err
+		}
+		if err = p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		if between.high, err = p.parseAdditive(); err != nil {
+			return nil, err
+		}
+		return between, nil
+	case p.keyword("IN"):
+		in := &sqlIn{expr: left, not: not}
+		if err = p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		for {
+			item, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			in.list = append(in.list, item)
+			if !p.symbol(",") {
+				break
+			}
+		}
+		return in, p.expectSymbol(")")
+	case not:
+		return nil, p.unexpected(p.peek())
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseAdditive() (sqlExpr, error) {
+	return p.parseArith([]string{"+", "-"}, p.parseMultiplicative)
+}
+
+func (p *sqlParser) parseMultiplicative() (sqlExpr, error) {
+	return p.parseArith([]string{"*", "/", "%"}, p.parseUnary)
+}
+
+// parseArith parses the left associative ops between the operands parsed by operand
+func (p *sqlParser) parseArith(ops []string, operand func() (sqlExpr, error)) (sqlExpr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		matched := ""
+		for _, op := range ops {
+			if p.symbol(op) {
+				matched = op
+				break
+			}
+		}
+		if matched == "" {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlArith{op: matched, left: left, right: right}
+	}
+}
+
+func (p *sqlParser) parseUnary() (sqlExpr, error) {
+	if p.symbol("-") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlNegate{expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *sqlParser) parsePrimary() (sqlExpr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case sqlNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.unexpected(tok)
+		}
+		return &sqlLiteral{value: n}, nil
+	case sqlString:
+		return &sqlLiteral{value: tok.text}, nil
+	case sqlQuotedIdent:
+		return p.parseColumn(tok.text)
+	case sqlSymbol:
+		if tok.text != "(" {
+			break
+		}
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expectSymbol(")")
+	case sqlIdent:
+		name := strings.ToUpper(tok.text)
+		switch name {
+		case "TRUE", "FALSE":
+			return &sqlLiteral{value: name == "TRUE"}, nil
+		case "NULL":
+			return &sqlLiteral{}, nil
+		}
+		if p.symbol("(") {
+			return p.parseFunction(name)
+		}
+		if sqlKeywords[name] {
+			break
+		}
+		return p.parseColumn(tok.text)
+	}
+	return nil, p.unexpected(tok)
+}
+
+// parseColumn parses the rest of a dotted column path starting with first
+func (p *sqlParser) parseColumn(first string) (sqlExpr, error) {
+	if !p.inAggregate {
+		p.bareColumns = true
+	}
+	column := &sqlColumn{path: []string{first}, query: p.query}
+	for p.symbol(".") {
+		tok := p.next()
+		if tok.kind != sqlIdent && tok.kind != sqlQuotedIdent {
+			return nil, p.unexpected(tok)
+		}
+		column.path = append(column.path, tok.text)
+	}
+	return column, nil
+}
+
+// parseFunction parses the arguments of the function name after its opening parenthesis
+func (p *sqlParser) parseFunction(name string) (sqlExpr, error) {
+	switch name {
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+		if p.inAggregate {
+			return nil, sqlError(ErrUnsupportedSQLOperation, "Aggregate functions can not be nested.")
+		}
+		agg := &sqlAggregate{name: name}
+		if name != "COUNT" || !p.symbol("*") {
+			p.inAggregate = true
+			arg, err := p.parseExpr()
+			p.inAggregate = false
+			if err != nil {
+				return nil, err
+			}
+			agg.arg = arg
+		}
+		p.query.aggregates = append(p.query.aggregates, agg)
+		return agg, p.expectSymbol(")")
+	case "CAST":
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectKeyword("AS"); err != nil {
+			return nil, err
+		}
+		tok := p.next()
+		typ := strings.ToUpper(tok.text)
+		switch typ {
+		case "STRING", "VARCHAR", "CHAR", "BOOL", "BOOLEAN", "INT", "INTEGER", "FLOAT", "DECIMAL", "NUMERIC", "REAL", "DOUBLE":
+		default:
+			return nil, sqlError(ErrUnsupportedSQLOperation, "Unsupported CAST type %q.", tok.text)
+		}
+		return &sqlCast{expr: expr, typ: typ}, p.expectSymbol(")")
+	case "LOWER", "UPPER", "TRIM", "CHAR_LENGTH", "CHARACTER_LENGTH":
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlFunction{name: name, arg: arg}, p.expectSymbol(")")
+	}
+	return nil, sqlError(ErrUnsupportedSQLOperation, "Unsupported function %s.", name)
+}
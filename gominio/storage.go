@@ -0,0 +1,187 @@
+package gominio
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// Storage classes of the x-amz-storage-class header
+const (
+	StorageClassStandard           = "STANDARD"
+	StorageClassReducedRedundancy  = "REDUCED_REDUNDANCY"
+	StorageClassStandardIA         = "STANDARD_IA"
+	StorageClassOnezoneIA          = "ONEZONE_IA"
+	StorageClassIntelligentTiering = "INTELLIGENT_TIERING"
+	StorageClassGlacierIR          = "GLACIER_IR"
+	StorageClassGlacier            = "GLACIER"
+	StorageClassDeepArchive        = "DEEP_ARCHIVE"
+	StorageClassOutposts           = "OUTPOSTS"
+)
+
+const (
+	amzStorageClass = "x-amz-storage-class"
+	amzRestore      = "x-amz-restore"
+)
+
+// storageClasses are the supported storage classes, the data of the archived ones can't be read
+// until the object is restored
+var storageClasses = map[string]bool{
+	StorageClassStandard:           false,
+	StorageClassReducedRedundancy:  false,
+	StorageClassStandardIA:         false,
+	StorageClassOnezoneIA:          false,
+	StorageClassIntelligentTiering: false,
+	StorageClassGlacierIR:          false,
+	StorageClassGlacier:            true,
+	StorageClassDeepArchive:        true,
+	StorageClassOutposts:           false,
+}
+
+// restoreTiers are the retrieval tiers of restore requests
+var restoreTiers = map[string]bool{"": true, "Expedited": true, "Standard": true, "Bulk": true}
+
+// ParseStorageClass returns the storage class named by s, STANDARD when it is empty
+func ParseStorageClass(s string) (string, error) {
+	if s == "" {
+		return StorageClassStandard, nil
+	}
+	if _, ok := storageClasses[s]; !ok {
+		return "", ErrInvalidStorageClass
+	}
+	return s, nil
+}
+
+// ObjectRestore is the temporary copy of an archived object, its data can be read from Completed
+// until Expires
+type ObjectRestore struct {
+	Completed time.Time
+	Expires   time.Time
+}
+
+// GetStorageClass returns the storage class of the object
+func (oi *ObjectInfo) GetStorageClass() string {
+	if oi.StorageClass == "" {
+		return StorageClassStandard
+	}
+	return oi.StorageClass
+}
+
+// checkReadable returns InvalidObjectState when the object is archived and has no restored copy at now
+func (oi *ObjectInfo) checkReadable(now time.Time) error {
+	if !storageClasses[oi.GetStorageClass()] {
+		return nil
+	}
+	if restore := oi.Restore; restore != nil && !now.Before(restore.Completed) && now.Before(restore.Expires) {
+		return nil
+	}
+	apiErr := ErrInvalidObjectState
+	apiErr.Description = "The operation is not valid for the object's storage class"
+	return apiErr
+}
+
+// setStorageHeaders sets the storage class header of objects not stored in STANDARD and the
+// restore header of objects being restored or with a restored copy
+func setStorageHeaders(header http.Header, oi *ObjectInfo, now time.Time) {
+	if class := oi.GetStorageClass(); class != StorageClassStandard {
+		header.Set(amzStorageClass, class)
+	}
+	restore := oi.Restore
+	switch {
+	case restore == nil:
+	case now.Before(restore.Completed):
+		header.Set(amzRestore, `ongoing-request="true"`)
+	case now.Before(restore.Expires):
+		header.Set(amzRestore, fmt.Sprintf(`ongoing-request="false", expiry-date="%s"`,
+			restore.Expires.UTC().Format(http.TimeFormat)))
+	}
+}
+
+// RestoreObject restores a copy of an archived object for days, the copy can be read once the
+// restore delay elapsed. It returns true when a restore is started and false when the expiry of
+// the restored copy is extended.
+func (ms *MinioServer) RestoreObject(bucket, object string, days int) (bool, error) {
+	var started bool
+	info := &HookInfo{Operation: OpRestoreObject, Bucket: bucket, Object: object}
+	err := ms.hooks.run(info, func() error {
+		var err error
+		started, err = ms.restoreObject(bucket, object, days)
+		return err
+	})
+	return started, err
+}
+
+func (ms *MinioServer) restoreObject(bucket, object string, days int) (bool, error) {
+	ms.Lock()
+	defer ms.Unlock()
+
+	oi, err := ms.getObjectInfo(bucket, object)
+	if err != nil {
+		return false, err
+	}
+	if !storageClasses[oi.GetStorageClass()] {
+		apiErr := ErrInvalidObjectState
+		apiErr.Description = "Restore is not allowed for the object's current storage class"
+		return false, apiErr
+	}
+
+	now := ms.now()
+	lifetime := time.Duration(days) * 24 * time.Hour
+	if restore := oi.Restore; restore != nil && now.Before(restore.Expires) {
+		if now.Before(restore.Completed) {
+			return false, ErrRestoreAlreadyInProgress
+		}
+		oi = ms.replaceObject(bucket, oi)
+		oi.Restore = &ObjectRestore{Completed: restore.Completed, Expires: now.Add(lifetime)}
+		return false, nil
+	}
+	completed := now.Add(ms.RestoreDelay)
+	oi = ms.replaceObject(bucket, oi)
+	oi.Restore = &ObjectRestore{Completed: completed, Expires: completed.Add(lifetime)}
+	return true, nil
+}
+
+// RestoreRequest is the body of RestoreObject, SELECT restores are not supported
+type RestoreRequest struct {
+	Days                 *int
+	Type                 string
+	Tier                 string
+	GlacierJobParameters struct {
+		Tier string
+	}
+}
+
+// restoreObject starts restoring an archived object with 202 Accepted, or extends the expiry of
+// its restored copy with 200 OK
+func (api *ApiServer) restoreObject(ctx *gin.Context) {
+	bucket, object := ctx.Param("bucket"), ctx.Param("object")
+
+	var req RestoreRequest
+	if err := decodeAny(ctx.Request.Body, &req); err != nil ||
+		!restoreTiers[req.Tier] || !restoreTiers[req.GlacierJobParameters.Tier] {
+		ErrResponse(ctx, object, bucket, ErrMalformedXML)
+		return
+	}
+	if req.Type != "" {
+		ErrResponse(ctx, object, bucket, ErrNotImplemented)
+		return
+	}
+	if req.Days == nil || *req.Days < 1 {
+		apiErr := ErrInvalidArgument
+		apiErr.Description = "Days must be a positive integer."
+		ErrResponse(ctx, object, bucket, apiErr)
+		return
+	}
+
+	started, err := api.GetMS().RestoreObject(bucket, object, *req.Days)
+	if err != nil {
+		ErrResponse(ctx, object, bucket, ToAPIError(err))
+		return
+	}
+	if started {
+		SuccessResponse(ctx, http.StatusAccepted, nil)
+		return
+	}
+	SuccessResponse(ctx, http.StatusOK, nil)
+}
@@ -0,0 +1,191 @@
+package gominio
+
+import (
+	"context"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClock is a clock advanced by tests
+type testClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestStorageClasses(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"), WithServerConfig(func(cfg *ServerConfig) {
+		cfg.MinPartSize = 1
+	}))
+	ctx := context.Background()
+	put := func(object, class string) error {
+		_, err := server.Client.PutObject(ctx, "test", object, strings.NewReader(object), int64(len(object)),
+			minio.PutObjectOptions{StorageClass: class})
+		return err
+	}
+
+	require.NoError(t, put("standard.txt", ""))
+	require.NoError(t, put("dir/rrs.txt", StorageClassReducedRedundancy))
+	require.NoError(t, put("dir/sub/ia.txt", StorageClassStandardIA))
+	require.Equal(t, "InvalidStorageClass", minio.ToErrorResponse(put("bad.txt", "COLD")).Code)
+
+	oi, err := server.Client.StatObject(ctx, "test", "dir/rrs.txt", minio.StatObjectOptions{})
+	require.NoError(t, err)
+	require.Equal(t, StorageClassReducedRedundancy, oi.Metadata.Get(amzStorageClass))
+	oi, err = server.Client.StatObject(ctx, "test", "standard.txt", minio.StatObjectOptions{})
+	require.NoError(t, err)
+	require.Empty(t, oi.Metadata.Get(amzStorageClass))
+
+	// copies are stored in the requested class, STANDARD by default
+	_, err = server.Client.CopyObject(ctx, minio.CopyDestOptions{Bucket: "test", Object: "dir/copy.txt"},
+		minio.CopySrcOptions{Bucket: "test", Object: "dir/rrs.txt"})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPut, "/test/onezone.txt", nil)
+	require.NoError(t, err)
+	req.Header.Set(amzCopySource, "/test/standard.txt")
+	req.Header.Set(amzStorageClass, StorageClassOnezoneIA)
+	rsp, err := server.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, rsp.Body.Close())
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	// multipart uploads are stored in the class they were initiated with
+	core := minio.Core{Client: server.Client}
+	id, err := core.NewMultipartUpload(ctx, "test", "multi.txt", minio.PutObjectOptions{StorageClass: StorageClassIntelligentTiering})
+	require.NoError(t, err)
+	part, err := core.PutObjectPart(ctx, "test", "multi.txt", id, 1, strings.NewReader("multi"), 5, minio.PutObjectPartOptions{})
+	require.NoError(t, err)
+	_, err = core.CompleteMultipartUpload(ctx, "test", "multi.txt", id,
+		[]minio.CompletePart{{PartNumber: part.PartNumber, ETag: part.ETag}}, minio.PutObjectOptions{})
+	require.NoError(t, err)
+
+	for object, class := range map[string]string{
+		"standard.txt":   "",
+		"dir/rrs.txt":    StorageClassReducedRedundancy,
+		"dir/sub/ia.txt": StorageClassStandardIA,
+		"dir/copy.txt":   "",
+		"onezone.txt":    StorageClassOnezoneIA,
+		"multi.txt":      StorageClassIntelligentTiering,
+	} {
+		oi, err = server.Client.StatObject(ctx, "test", object, minio.StatObjectOptions{})
+		require.NoError(t, err)
+		require.Equal(t, class, oi.Metadata.Get(amzStorageClass), object)
+	}
+
+	attrs, err := http.NewRequest(http.MethodGet, "/test/multi.txt?attributes", nil)
+	require.NoError(t, err)
+	attrs.Header.Set(amzObjectAttributes, "StorageClass")
+	rsp, err = server.Do(attrs)
+	require.NoError(t, err)
+	data, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	require.NoError(t, rsp.Body.Close())
+	require.Contains(t, string(data), "<StorageClass>INTELLIGENT_TIERING</StorageClass>")
+
+}
+
+func TestRestoreObject(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	server := NewTestServer(t, WithBuckets("test"), WithServerConfig(func(cfg *ServerConfig) {
+		cfg.RestoreDelay = 4 * time.Hour
+		cfg.Clock = clock.Now
+	}))
+	ctx := context.Background()
+	_, err := server.Client.PutObject(ctx, "test", "archive.txt", strings.NewReader("archived"), 8,
+		minio.PutObjectOptions{StorageClass: StorageClassGlacier})
+	require.NoError(t, err)
+	_, err = server.Client.PutObject(ctx, "test", "hot.txt", strings.NewReader("hot"), 3, minio.PutObjectOptions{})
+	require.NoError(t, err)
+
+	get := func() (string, error) {
+		obj, err := server.Client.GetObject(ctx, "test", "archive.txt", minio.GetObjectOptions{})
+		require.NoError(t, err)
+		defer obj.Close()
+		data, err := io.ReadAll(obj)
+		return string(data), err
+	}
+	restore := func(object string, days int) error {
+		var req minio.RestoreRequest
+		req.SetDays(days)
+		req.SetGlacierJobParameters(minio.GlacierJobParameters{Tier: minio.TierStandard})
+		return server.Client.RestoreObject(ctx, "test", object, "", req)
+	}
+	stat := func() *minio.RestoreInfo {
+		oi, err := server.Client.StatObject(ctx, "test", "archive.txt", minio.StatObjectOptions{})
+		require.NoError(t, err)
+		require.Equal(t, StorageClassGlacier, oi.Metadata.Get(amzStorageClass))
+		return oi.Restore
+	}
+
+	// archived objects can be inspected but not read or copied
+	require.Nil(t, stat())
+	_, err = get()
+	require.Equal(t, "InvalidObjectState", minio.ToErrorResponse(err).Code)
+	_, err = server.Client.CopyObject(ctx, minio.CopyDestOptions{Bucket: "test", Object: "copy.txt"},
+		minio.CopySrcOptions{Bucket: "test", Object: "archive.txt"})
+	require.Equal(t, "InvalidObjectState", minio.ToErrorResponse(err).Code)
+	require.Equal(t, "InvalidObjectState", minio.ToErrorResponse(restore("hot.txt", 1)).Code)
+
+	require.NoError(t, restore("archive.txt", 2))
+	require.Equal(t, &minio.RestoreInfo{OngoingRestore: true}, stat())
+	require.Equal(t, "RestoreAlreadyInProgress", minio.ToErrorResponse(restore("archive.txt", 2)).Code)
+	_, err = get()
+	require.Equal(t, "InvalidObjectState", minio.ToErrorResponse(err).Code)
+
+	// the restored copy can be read once the restore delay elapsed until it expires
+	clock.Advance(4 * time.Hour)
+	expires := clock.Now().Add(48 * time.Hour)
+	require.Equal(t, &minio.RestoreInfo{ExpiryTime: expires}, stat())
+	data, err := get()
+	require.NoError(t, err)
+	require.Equal(t, "archived", data)
+	_, err = server.Client.CopyObject(ctx, minio.CopyDestOptions{Bucket: "test", Object: "copy.txt"},
+		minio.CopySrcOptions{Bucket: "test", Object: "archive.txt"})
+	require.NoError(t, err)
+
+	// restoring a restored object extends the expiry of its copy
+	clock.Advance(24 * time.Hour)
+	require.NoError(t, restore("archive.txt", 3))
+	require.Equal(t, &minio.RestoreInfo{ExpiryTime: clock.Now().Add(72 * time.Hour)}, stat())
+	clock.Advance(72 * time.Hour)
+	require.Nil(t, stat())
+	_, err = get()
+	require.Equal(t, "InvalidObjectState", minio.ToErrorResponse(err).Code)
+
+	for body, code := range map[string]string{
+		"<RestoreRequest/>": "InvalidArgument",
+		"<RestoreRequest><Days>0</Days></RestoreRequest>":                                                              "InvalidArgument",
+		"<RestoreRequest><Days>1</Days><Tier>Fast</Tier></RestoreRequest>":                                             "MalformedXML",
+		"<RestoreRequest><Type>SELECT</Type><Days>1</Days></RestoreRequest>":                                           "NotImplemented",
+		"<RestoreRequest><Days>1</Days><GlacierJobParameters><Tier>Now</Tier></GlacierJobParameters></RestoreRequest>": "MalformedXML",
+	} {
+		req, err := http.NewRequest(http.MethodPost, "/test/archive.txt?restore", strings.NewReader(body))
+		require.NoError(t, err)
+		rsp, err := server.Do(req)
+		require.NoError(t, err)
+		data, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		require.NoError(t, rsp.Body.Close())
+		require.Contains(t, string(data), fmt.Sprintf("<Code>%s</Code>", code), body)
+	}
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpRestoreObject, Status: http.StatusAccepted}, 1)
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpRestoreObject, Status: http.StatusOK}, 1)
+}
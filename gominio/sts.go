@@ -0,0 +1,298 @@
+package gominio
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	stsActionAssumeRole                = "AssumeRole"
+	stsActionAssumeRoleWithWebIdentity = "AssumeRoleWithWebIdentity"
+
+	minSessionDuration     = 15 * time.Minute
+	maxSessionDuration     = 12 * time.Hour
+	defaultSessionDuration = time.Hour
+
+	// expiredSessionRetention is how long expired credentials are kept to be told apart from
+	// unknown ones, they are removed when new credentials are issued afterwards
+	expiredSessionRetention = 15 * time.Minute
+
+	// maxSTSBodySize bounds the url encoded body of STS requests
+	maxSTSBodySize = 1 << 20
+	stsFormKey     = "gominio.stsForm"
+)
+
+// Session is a set of temporary credentials issued by the STS API
+type Session struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Expiration   time.Time
+
+	// Parent is the access key the credentials were issued to by AssumeRole, it is empty for
+	// web identities
+	Parent string
+	// Subject and Policies are the subject and the policies of a web identity
+	Subject  string
+	Policies []string
+	// Policy is the inline session policy, it restricts the permissions of the credentials
+	Policy *Policy
+}
+
+// SetOpenIDConfig configures the verification of the web identity tokens of AssumeRoleWithWebIdentity
+func (iam *IAM) SetOpenIDConfig(cfg OpenIDConfig) error {
+	verifier, err := newOpenIDVerifier(cfg)
+	if err != nil {
+		return err
+	}
+
+	iam.Lock()
+	defer iam.Unlock()
+
+	iam.openID = verifier
+	return nil
+}
+
+// SetMaxSessionDuration caps the lifetime of temporary credentials, zero removes the cap. Tests
+// may lower it below the 15 minutes minimum of the STS API to exercise credential refreshes.
+func (iam *IAM) SetMaxSessionDuration(d time.Duration) {
+	iam.Lock()
+	defer iam.Unlock()
+
+	iam.maxSessionDuration = d
+}
+
+// AssumeRole issues temporary credentials to the root credentials or a user, they are allowed
+// what parent is allowed and policy, when not nil, allows
+func (iam *IAM) AssumeRole(parent string, duration time.Duration, policy *Policy) (Session, error) {
+	iam.Lock()
+	defer iam.Unlock()
+
+	if !iam.isRoot(parent) {
+		user, ok := iam.users[parent]
+		if !ok || !user.Enabled {
+			apiErr := ErrAccessDenied
+			apiErr.Description = "only the root credentials and users may assume a role"
+			return Session{}, apiErr
+		}
+	}
+	return iam.newSession(&Session{Parent: parent, Policy: policy}, duration)
+}
+
+// AssumeRoleWithWebIdentity issues temporary credentials to the identity of a web identity token,
+// they are allowed what the policies named by the policy claim and policy, when not nil, allow
+func (iam *IAM) AssumeRoleWithWebIdentity(token string, duration time.Duration, policy *Policy) (Session, error) {
+	iam.Lock()
+	defer iam.Unlock()
+
+	if iam.openID == nil {
+		apiErr := ErrInvalidParameterValue
+		apiErr.Description = "web identities are not configured"
+		return Session{}, apiErr
+	}
+	claims, err := iam.openID.verify(token)
+	if err != nil {
+		apiErr := ErrInvalidIdentityToken
+		apiErr.Description = err.Error()
+		return Session{}, apiErr
+	}
+
+	policies := claimStrings(claims[iam.openID.cfg.ClaimName], true)
+	if len(policies) == 0 {
+		apiErr := ErrAccessDenied
+		apiErr.Description = fmt.Sprintf("the token has no %q claim", iam.openID.cfg.ClaimName)
+		return Session{}, apiErr
+	}
+	if err := iam.checkPolicies(policies); err != nil {
+		apiErr := ErrInvalidParameterValue
+		apiErr.Description = err.Error()
+		return Session{}, apiErr
+	}
+	subject, _ := claims["sub"].(string)
+	return iam.newSession(&Session{Subject: subject, Policies: policies, Policy: policy}, duration)
+}
+
+// GetSession returns a copy of the temporary credentials of access
+func (iam *IAM) GetSession(access string) (Session, bool) {
+	iam.RLock()
+	defer iam.RUnlock()
+
+	session, ok := iam.sessions[access]
+	if !ok {
+		return Session{}, false
+	}
+	return *session, true
+}
+
+// newSession generates the keys of session and stores it, the lock must be held
+func (iam *IAM) newSession(session *Session, duration time.Duration) (Session, error) {
+	if duration == 0 {
+		duration = defaultSessionDuration
+	}
+	if iam.maxSessionDuration > 0 && duration > iam.maxSessionDuration {
+		duration = iam.maxSessionDuration
+	}
+
+	access, err := randomKey(15)
+	if err != nil {
+		return Session{}, err
+	}
+	secret, err := randomKey(30)
+	if err != nil {
+		return Session{}, err
+	}
+	token, err := randomKey(96)
+	if err != nil {
+		return Session{}, err
+	}
+	session.AccessKey = access
+	session.SecretKey = secret
+	session.SessionToken = token
+	session.Expiration = time.Now().Add(duration).UTC()
+	iam.pruneSessions()
+	iam.sessions[access] = session
+	return *session, nil
+}
+
+// pruneSessions removes the credentials that expired more than expiredSessionRetention ago, the
+// lock must be held
+func (iam *IAM) pruneSessions() {
+	cutoff := time.Now().Add(-expiredSessionRetention)
+	for access, session := range iam.sessions {
+		if session.Expiration.Before(cutoff) {
+			delete(iam.sessions, access)
+		}
+	}
+}
+
+// randomKey returns n random bytes encoded as a base64 string without padding or slashes
+func randomKey(n int) (string, error) {
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// stsForm returns the parameters of a STS request, given by its url encoded body or its query.
+// The body is kept for the signature verification and the handlers.
+func stsForm(ctx *gin.Context) url.Values {
+	if form, ok := ctx.Get(stsFormKey); ok {
+		return form.(url.Values)
+	}
+
+	form := ctx.Request.URL.Query()
+	if ctx.Request.Body != nil {
+		body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxSTSBodySize))
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if values, parseErr := url.ParseQuery(string(body)); err == nil && parseErr == nil {
+			for key, value := range values {
+				form[key] = value
+			}
+		}
+	}
+	ctx.Set(stsFormKey, form)
+	return form
+}
+
+// stsParams returns the session duration and the inline session policy of a STS request
+func stsParams(form url.Values) (time.Duration, *Policy, error) {
+	var duration time.Duration
+	if value := form.Get("DurationSeconds"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		duration = time.Duration(seconds) * time.Second
+		if err != nil || duration < minSessionDuration || duration > maxSessionDuration {
+			apiErr := ErrInvalidParameterValue
+			apiErr.Description = fmt.Sprintf("DurationSeconds must be between %d and %d",
+				int(minSessionDuration.Seconds()), int(maxSessionDuration.Seconds()))
+			return 0, nil, apiErr
+		}
+	}
+
+	var policy *Policy
+	if document := form.Get("Policy"); document != "" {
+		var err error
+		if policy, err = ParsePolicy([]byte(document)); err != nil {
+			apiErr := ErrMalformedPolicyDocument
+			apiErr.Description = ToAPIError(err).Description
+			return 0, nil, apiErr
+		}
+	}
+	return duration, policy, nil
+}
+
+// STS serves the AssumeRole and AssumeRoleWithWebIdentity actions of the STS API
+func (api *ApiServer) STS(ctx *gin.Context) {
+	form := stsForm(ctx)
+	switch action := form.Get("Action"); action {
+	case stsActionAssumeRole:
+		api.assumeRole(ctx, form)
+	case stsActionAssumeRoleWithWebIdentity:
+		api.assumeRoleWithWebIdentity(ctx, form)
+	default:
+		apiErr := ErrNotImplemented
+		apiErr.Description = fmt.Sprintf("STS action %q is not implemented", action)
+		ErrResponse(ctx, "", "", apiErr)
+	}
+}
+
+func (api *ApiServer) assumeRole(ctx *gin.Context, form url.Values) {
+	iam := api.GetMS().GetIAM()
+	scope, err := iam.verifySignature(ctx.Request)
+	if err == nil && scope.Service != "sts" {
+		err = ErrSignatureDoesNotMatch
+	}
+	if err != nil {
+		ErrResponse(ctx, "", "", ToAPIError(err))
+		return
+	}
+	duration, policy, err := stsParams(form)
+	if err != nil {
+		ErrResponse(ctx, "", "", ToAPIError(err))
+		return
+	}
+
+	session, err := iam.AssumeRole(scope.AccessKey, duration, policy)
+	if err != nil {
+		ErrResponse(ctx, "", "", ToAPIError(err))
+		return
+	}
+	rsp := credentials.AssumeRoleResponse{}
+	rsp.Result.Credentials.AccessKey = session.AccessKey
+	rsp.Result.Credentials.SecretKey = session.SecretKey
+	rsp.Result.Credentials.SessionToken = session.SessionToken
+	rsp.Result.Credentials.Expiration = session.Expiration
+	rsp.ResponseMetadata.RequestID = ctx.Writer.Header().Get(amzRequestID)
+	SuccessResponse(ctx, http.StatusOK, encodeAny(rsp))
+}
+
+func (api *ApiServer) assumeRoleWithWebIdentity(ctx *gin.Context, form url.Values) {
+	duration, policy, err := stsParams(form)
+	if err != nil {
+		ErrResponse(ctx, "", "", ToAPIError(err))
+		return
+	}
+
+	session, err := api.GetMS().GetIAM().AssumeRoleWithWebIdentity(form.Get("WebIdentityToken"), duration, policy)
+	if err != nil {
+		ErrResponse(ctx, "", "", ToAPIError(err))
+		return
+	}
+	rsp := credentials.AssumeRoleWithWebIdentityResponse{}
+	rsp.Result.Credentials.AccessKey = session.AccessKey
+	rsp.Result.Credentials.SecretKey = session.SecretKey
+	rsp.Result.Credentials.SessionToken = session.SessionToken
+	rsp.Result.Credentials.Expiration = session.Expiration
+	rsp.Result.SubjectFromWebIdentityToken = session.Subject
+	rsp.ResponseMetadata.RequestID = ctx.Writer.Header().Get(amzRequestID)
+	SuccessResponse(ctx, http.StatusOK, encodeAny(rsp))
+}
@@ -0,0 +1,276 @@
+package gominio
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/signer"
+	"github.com/stretchr/testify/require"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signJWT returns a JWT of claims signed with an HMAC key or an RSA private key
+func signJWT(t *testing.T, key any, kid string, claims map[string]any) string {
+	alg := "HS256"
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		alg = "RS256"
+	}
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// stsErrorCode returns the error code of a failed credentials retrieval
+func stsErrorCode(err error) string {
+	var errRsp credentials.ErrorResponse
+	if errors.As(err, &errRsp) {
+		return errRsp.STSError.Code
+	}
+	return ""
+}
+
+func TestAssumeRole(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	ctx := context.Background()
+	iam := server.GetMS().GetIAM()
+	endpoint := "http://" + server.Endpoint
+	require.NoError(t, iam.SetPolicy("readwrite", []byte(`{"Statement": [
+		{"Effect": "Allow", "Action": ["s3:GetObject", "s3:PutObject"], "Resource": "arn:aws:s3:::test/*"}
+	]}`)))
+	require.NoError(t, iam.AddUser("service", "service-secret", "readwrite"))
+	content := `hello world`
+	_, err := server.Client.PutObject(ctx, "test", "hello.txt", strings.NewReader(content),
+		int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+
+	// the session policy restricts the permissions of the user
+	creds, err := credentials.NewSTSAssumeRole(endpoint, credentials.STSAssumeRoleOptions{
+		AccessKey: "service",
+		SecretKey: "service-secret",
+		Policy: `{"Statement": [
+			{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::test/*"}
+		]}`,
+	})
+	require.NoError(t, err)
+	value, err := creds.Get()
+	require.NoError(t, err)
+	require.NotEmpty(t, value.SessionToken)
+	session, ok := iam.GetSession(value.AccessKeyID)
+	require.True(t, ok)
+	require.Equal(t, "service", session.Parent)
+	require.WithinDuration(t, time.Now().Add(time.Hour), session.Expiration, time.Minute)
+
+	client, err := minio.New(server.Endpoint, &minio.Options{Creds: creds})
+	require.NoError(t, err)
+	_, err = client.StatObject(ctx, "test", "hello.txt", minio.StatObjectOptions{})
+	server.NoError(err)
+	_, err = client.PutObject(ctx, "test", "other.txt", strings.NewReader(content),
+		int64(len(content)), minio.PutObjectOptions{})
+	require.Equal(t, "AccessDenied", minio.ToErrorResponse(err).Code)
+
+	// the session token must come with the temporary credentials
+	forged, err := minio.New(server.Endpoint, &minio.Options{
+		Creds: credentials.NewStaticV4(value.AccessKeyID, value.SecretAccessKey, "forged"),
+	})
+	require.NoError(t, err)
+	_, err = forged.StatObject(ctx, "test", "hello.txt", minio.StatObjectOptions{})
+	require.Equal(t, "InvalidToken", minio.ToErrorResponse(err).Code)
+
+	// bad credentials and parameters are rejected
+	for code, opts := range map[string]credentials.STSAssumeRoleOptions{
+		"SignatureDoesNotMatch":   {AccessKey: "service", SecretKey: "wrong-secret"},
+		"InvalidAccessKeyId":      {AccessKey: "unknown", SecretKey: "service-secret"},
+		"MalformedPolicyDocument": {AccessKey: "service", SecretKey: "service-secret", Policy: "not a policy"},
+		"InvalidParameterValue":   {AccessKey: "service", SecretKey: "service-secret", DurationSeconds: 100000},
+	} {
+		bad, err := credentials.NewSTSAssumeRole(endpoint, opts)
+		require.NoError(t, err)
+		_, err = bad.Get()
+		require.Equal(t, code, stsErrorCode(err))
+	}
+
+	// credentials are refreshed once they expire
+	iam.SetMaxSessionDuration(time.Second)
+	creds, err = credentials.NewSTSAssumeRole(endpoint, credentials.STSAssumeRoleOptions{
+		AccessKey: server.Access,
+		SecretKey: server.Secret,
+	})
+	require.NoError(t, err)
+	client, err = minio.New(server.Endpoint, &minio.Options{Creds: creds})
+	require.NoError(t, err)
+	_, err = client.StatObject(ctx, "test", "hello.txt", minio.StatObjectOptions{})
+	server.NoError(err)
+	expired, err := creds.Get()
+	require.NoError(t, err)
+
+	time.Sleep(1100 * time.Millisecond)
+	_, err = client.StatObject(ctx, "test", "hello.txt", minio.StatObjectOptions{})
+	server.NoError(err)
+	refreshed, err := creds.Get()
+	require.NoError(t, err)
+	require.NotEqual(t, expired.AccessKeyID, refreshed.AccessKeyID)
+
+	req, err := http.NewRequest(http.MethodHead, endpoint+"/test/hello.txt", nil)
+	require.NoError(t, err)
+	req = signer.SignV4(*req, expired.AccessKeyID, expired.SecretAccessKey, expired.SessionToken, "us-east-1")
+	rsp, err := server.HTTPClient.Do(req)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpHeadObject, Status: http.StatusBadRequest}, 1)
+
+	// credentials expired for a while are removed when new ones are issued
+	iam.Lock()
+	iam.sessions[refreshed.AccessKeyID].Expiration = time.Now().Add(-expiredSessionRetention - time.Minute)
+	iam.Unlock()
+	creds, err = credentials.NewSTSAssumeRole(endpoint, credentials.STSAssumeRoleOptions{
+		AccessKey: server.Access,
+		SecretKey: server.Secret,
+	})
+	require.NoError(t, err)
+	issued, err := creds.Get()
+	require.NoError(t, err)
+	_, ok = iam.GetSession(refreshed.AccessKeyID)
+	require.False(t, ok)
+	_, ok = iam.GetSession(issued.AccessKeyID)
+	require.True(t, ok)
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpAssumeRole, Status: http.StatusOK}, 4)
+}
+
+func TestExpiredSession(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	iam := server.GetMS().GetIAM()
+
+	creds, err := credentials.NewSTSAssumeRole("http://"+server.Endpoint, credentials.STSAssumeRoleOptions{
+		AccessKey: server.Access,
+		SecretKey: server.Secret,
+	})
+	require.NoError(t, err)
+	value, err := creds.Get()
+	require.NoError(t, err)
+	iam.Lock()
+	iam.sessions[value.AccessKeyID].Expiration = time.Now().Add(-time.Minute)
+	iam.Unlock()
+
+	// expired credentials are kept until they are pruned, even when no user exists
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodGet, "http://"+server.Endpoint+"/test/", nil)
+		require.NoError(t, err)
+		req = signer.SignV4(*req, value.AccessKeyID, value.SecretAccessKey, value.SessionToken, "us-east-1")
+		rsp, err := server.HTTPClient.Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(rsp.Body)
+		_ = rsp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+		require.Contains(t, string(body), "<Code>ExpiredToken</Code>")
+		require.True(t, iam.Enabled())
+	}
+}
+
+func TestAssumeRoleWithWebIdentity(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+	ctx := context.Background()
+	iam := server.GetMS().GetIAM()
+	endpoint := "http://" + server.Endpoint
+	require.NoError(t, iam.SetPolicy("readonly", []byte(`{"Statement": [
+		{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::*"}
+	]}`)))
+	content := `hello world`
+	_, err := server.Client.PutObject(ctx, "test", "hello.txt", strings.NewReader(content),
+		int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwks, err := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "rsa",
+		"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}}})
+	require.NoError(t, err)
+	hmacKey := []byte("web identity secret")
+	require.NoError(t, iam.SetOpenIDConfig(OpenIDConfig{HMACKey: hmacKey, JWKS: jwks, ClientID: "app"}))
+
+	assume := func(token string) (*minio.Client, error) {
+		creds, err := credentials.NewSTSWebIdentity(endpoint, func() (*credentials.WebIdentityToken, error) {
+			return &credentials.WebIdentityToken{Token: token}, nil
+		})
+		require.NoError(t, err)
+		if _, err = creds.Get(); err != nil {
+			return nil, err
+		}
+		return minio.New(server.Endpoint, &minio.Options{Creds: creds})
+	}
+	claims := func(aud string, exp time.Duration, policy any) map[string]any {
+		return map[string]any{
+			"sub":    "alice",
+			"aud":    aud,
+			"exp":    time.Now().Add(exp).Unix(),
+			"policy": policy,
+		}
+	}
+
+	for _, token := range []string{
+		signJWT(t, hmacKey, "", claims("app", time.Hour, "readonly")),
+		signJWT(t, rsaKey, "rsa", claims("app", time.Hour, []string{"readonly"})),
+	} {
+		client, err := assume(token)
+		require.NoError(t, err)
+		_, err = client.StatObject(ctx, "test", "hello.txt", minio.StatObjectOptions{})
+		server.NoError(err)
+		_, err = client.PutObject(ctx, "test", "other.txt", strings.NewReader(content),
+			int64(len(content)), minio.PutObjectOptions{})
+		require.Equal(t, "AccessDenied", minio.ToErrorResponse(err).Code)
+	}
+
+	for token, code := range map[string]string{
+		signJWT(t, hmacKey, "", claims("other", time.Hour, "readonly")):                  "InvalidIdentityToken",
+		signJWT(t, hmacKey, "", claims("app", -time.Minute, "readonly")):                 "InvalidIdentityToken",
+		signJWT(t, []byte("wrong key"), "", claims("app", time.Hour, "readonly")):        "InvalidIdentityToken",
+		signJWT(t, rsaKey, "unknown", claims("app", time.Hour, "readonly")):              "InvalidIdentityToken",
+		signJWT(t, hmacKey, "", claims("app", time.Hour, "missing")):                     "InvalidParameterValue",
+		signJWT(t, hmacKey, "", map[string]any{"exp": time.Now().Add(time.Hour).Unix()}): "InvalidIdentityToken",
+	} {
+		_, err := assume(token)
+		require.Equal(t, code, stsErrorCode(err), token)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/?Action=GetCallerIdentity", nil)
+	require.NoError(t, err)
+	rsp, err := server.Do(req)
+	require.NoError(t, err)
+	data, err := io.ReadAll(rsp.Body)
+	_ = rsp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotImplemented, rsp.StatusCode, string(data))
+}
@@ -0,0 +1,161 @@
+package gominio
+
+import (
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/signer"
+	"net/http"
+	"testing"
+)
+
+const (
+	// DefaultTestAccess is the access key used by NewTestServer when none is given
+	DefaultTestAccess = "minioadmin"
+	// DefaultTestSecret is the secret key used by NewTestServer when none is given
+	DefaultTestSecret = "minioadmin"
+)
+
+// TestServer is a started Server wired to a minio client, it is torn down by the test cleanup
+type TestServer struct {
+	*Server
+
+	t        testing.TB
+	Client   *minio.Client
+	Endpoint string
+	Access   string
+	Secret   string
+
+	// HTTPClient sends plain requests to the server, it shares the transport of Client
+	HTTPClient *http.Client
+}
+
+type testServerOptions struct {
+	config  ServerConfig
+	buckets []string
+}
+
+// TestServerOption customize the server started by NewTestServer
+type TestServerOption func(opts *testServerOptions)
+
+// WithCredentials sets the access and secret key of the test server and its client
+func WithCredentials(access, secret string) TestServerOption {
+	return func(opts *testServerOptions) {
+		opts.config.Access = access
+		opts.config.Secret = secret
+	}
+}
+
+// WithBuckets creates the given buckets before the test server is returned
+func WithBuckets(buckets ...string) TestServerOption {
+	return func(opts *testServerOptions) {
+		opts.buckets = append(opts.buckets, buckets...)
+	}
+}
+
+// WithServerConfig allows tweaking the server configuration, the port is always chosen randomly
+func WithServerConfig(fn func(cfg *ServerConfig)) TestServerOption {
+	return func(opts *testServerOptions) {
+		fn(&opts.config)
+	}
+}
+
+// NewTestServer starts a server on a random port, registers its shutdown with t.Cleanup
+// and returns it together with a client configured to talk to it.
+func NewTestServer(t testing.TB, opts ...TestServerOption) *TestServer {
+	t.Helper()
+
+	o := &testServerOptions{
+		config: ServerConfig{
+			Access: DefaultTestAccess,
+			Secret: DefaultTestSecret,
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	cfg := o.config
+	cfg.Port = 0
+	srv, err := NewServer(&cfg)
+	if err != nil {
+		t.Fatalf("gominio: create test server: %v", err)
+	}
+
+	port, err := srv.Start()
+	if err != nil {
+		t.Fatalf("gominio: start test server: %v", err)
+	}
+	transport, err := srv.GetTransport()
+	if err != nil {
+		_ = srv.Close()
+		t.Fatalf("gominio: create client transport: %v", err)
+	}
+	t.Cleanup(func() {
+		// connections dialed by the transport but never used keep the server from shutting
+		// down until they time out
+		transport.CloseIdleConnections()
+		if err := srv.Close(); err != nil {
+			t.Errorf("gominio: close test server: %v", err)
+		}
+	})
+
+	ts := &TestServer{
+		Server:   srv,
+		t:        t,
+		Endpoint: fmt.Sprintf("127.0.0.1:%d", port),
+		Access:   cfg.Access,
+		Secret:   cfg.Secret,
+	}
+
+	ts.HTTPClient = &http.Client{Transport: transport}
+	ts.Client, err = minio.New(ts.Endpoint, &minio.Options{
+		Creds:     credentials.NewStaticV4(ts.Access, ts.Secret, ""),
+		Secure:    cfg.TLS,
+		Transport: transport,
+	})
+	ts.NoError(err, "create minio client")
+
+	for _, bucket := range o.buckets {
+		ts.NoError(srv.GetMS().CreateBucket(bucket, ""), "create bucket %q", bucket)
+	}
+
+	return ts
+}
+
+// Do sends req to the test server signed with the test server credentials, the host and
+// scheme of the request URL are replaced by the ones of the server.
+func (ts *TestServer) Do(req *http.Request) (*http.Response, error) {
+	req.URL.Host = ts.Endpoint
+	req.URL.Scheme = "http"
+	if ts.config.TLS {
+		req.URL.Scheme = "https"
+	}
+	if req.Host == "" {
+		req.Host = ts.Endpoint
+	}
+	req = signer.SignV4(*req, ts.Access, ts.Secret, "", ts.GetMS().GetRegion())
+	return ts.HTTPClient.Do(req)
+}
+
+// NoError fails the test with a dump of the server state if err is not nil
+func (ts *TestServer) NoError(err error, msgAndArgs ...any) {
+	ts.t.Helper()
+	if err == nil {
+		return
+	}
+
+	msg := "unexpected error"
+	if len(msgAndArgs) > 0 {
+		if format, ok := msgAndArgs[0].(string); ok {
+			msg = fmt.Sprintf(format, msgAndArgs[1:]...)
+		}
+	}
+	ts.Fatalf("%s: %v", msg, err)
+}
+
+// Fatalf fails the test immediately, appending a dump of the server state to the message
+func (ts *TestServer) Fatalf(format string, args ...any) {
+	ts.t.Helper()
+	ts.t.Fatalf("gominio: "+format+"\n%s", append(args, ts.GetMS().Dump())...)
+}
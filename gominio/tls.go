@@ -0,0 +1,197 @@
+package gominio
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// certAuthority is an in-memory CA issuing the server and client certificates
+type certAuthority struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newCertAuthority() (*certAuthority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := certTemplate("gominio CA")
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &certAuthority{cert: cert, key: key}, nil
+}
+
+// issue issues a leaf certificate, hosts are added as DNS or IP subject alternative names
+func (ca *certAuthority) issue(commonName string, usage x509.ExtKeyUsage, hosts ...string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template, err := certTemplate(commonName)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der, ca.cert.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+func certTemplate(commonName string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"gominio"}, CommonName: commonName},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+	}, nil
+}
+
+// setupTLS builds the TLS configuration of the server from its config
+func (s *Server) setupTLS() error {
+	cfg := s.config
+	s.tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	s.certPool = x509.NewCertPool()
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return err
+		}
+		for _, der := range cert.Certificate {
+			c, err := x509.ParseCertificate(der)
+			if err != nil {
+				return err
+			}
+			s.certPool.AddCert(c)
+		}
+		s.tlsConfig.Certificates = []tls.Certificate{cert}
+	} else {
+		ca, err := newCertAuthority()
+		if err != nil {
+			return err
+		}
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if cfg.Domain != "" {
+			hosts = append(hosts, cfg.Domain, "*."+cfg.Domain)
+		}
+		if cfg.WebsiteDomain != "" {
+			hosts = append(hosts, cfg.WebsiteDomain, "*."+cfg.WebsiteDomain)
+		}
+		cert, err := ca.issue("gominio server", x509.ExtKeyUsageServerAuth, hosts...)
+		if err != nil {
+			return err
+		}
+		s.ca = ca
+		s.certPool.AddCert(ca.cert)
+		s.tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if !cfg.RequireClientCert {
+		return nil
+	}
+	switch {
+	case cfg.ClientCAFile != "":
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate found in %s", cfg.ClientCAFile)
+		}
+		s.tlsConfig.ClientCAs = pool
+	case s.ca != nil:
+		s.tlsConfig.ClientCAs = s.certPool
+	default:
+		return errors.New("client certificates verification requires ClientCAFile with a provided certificate")
+	}
+	s.tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	return nil
+}
+
+// GetCertPool returns the pool of certificates a client needs to trust the server, it is nil without TLS.
+func (s *Server) GetCertPool() *x509.CertPool {
+	return s.certPool
+}
+
+// NewClientCertificate issues a client certificate signed by the auto-generated CA.
+func (s *Server) NewClientCertificate() (tls.Certificate, error) {
+	if s.ca == nil {
+		return tls.Certificate{}, errors.New("server has no auto-generated certificate authority")
+	}
+	return s.ca.issue("gominio client", x509.ExtKeyUsageClientAuth)
+}
+
+// GetTransport returns a transport trusting the server certificate, it presents a client
+// certificate issued by NewClientCertificate when the server requires one.
+func (s *Server) GetTransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if s.tlsConfig == nil {
+		return transport, nil
+	}
+
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    s.certPool,
+	}
+	if s.config.RequireClientCert && s.ca != nil {
+		cert, err := s.NewClientCertificate()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+	return transport, nil
+}
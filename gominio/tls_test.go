@@ -0,0 +1,101 @@
+package gominio
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTLS(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"), WithServerConfig(func(cfg *ServerConfig) {
+		cfg.TLS = true
+	}))
+	require.NotNil(t, server.GetCertPool())
+
+	content := strings.Repeat("hello world\n", 1000)
+	_, err := server.Client.PutObject(context.Background(), "test", "hello.txt",
+		strings.NewReader(content), int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+
+	oi, err := server.Client.GetObject(context.Background(), "test", "hello.txt", minio.GetObjectOptions{})
+	server.NoError(err)
+	data, err := io.ReadAll(oi)
+	server.NoError(err)
+	require.Equal(t, content, string(data))
+
+	// plain HTTP clients and clients not trusting the CA are refused
+	rsp, err := http.Get("http://" + server.Endpoint + "/")
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	_, err = http.Get("https://" + server.Endpoint + "/")
+	require.Error(t, err)
+}
+
+func TestMutualTLS(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"), WithServerConfig(func(cfg *ServerConfig) {
+		cfg.TLS = true
+		cfg.RequireClientCert = true
+	}))
+
+	ok, err := server.Client.BucketExists(context.Background(), "test")
+	server.NoError(err)
+	require.True(t, ok)
+
+	// a client without certificate fails the handshake
+	client, err := minio.New(server.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(server.Access, server.Secret, ""),
+		Secure: true,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: server.GetCertPool(), MinVersion: tls.VersionTLS12},
+		},
+	})
+	require.NoError(t, err)
+	_, err = client.BucketExists(context.Background(), "test")
+	require.Error(t, err)
+
+}
+
+func TestTLSKeyPair(t *testing.T) {
+	ca, err := newCertAuthority()
+	require.NoError(t, err)
+	cert, err := ca.issue("test", x509.ExtKeyUsageServerAuth, "127.0.0.1")
+	require.NoError(t, err)
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "public.crt"), filepath.Join(dir, "private.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0600))
+
+	server := NewTestServer(t, WithBuckets("test"), WithServerConfig(func(cfg *ServerConfig) {
+		cfg.TLS = true
+		cfg.CertFile = certFile
+		cfg.KeyFile = keyFile
+	}))
+	ok, err := server.Client.BucketExists(context.Background(), "test")
+	server.NoError(err)
+	require.True(t, ok)
+
+	// mutual TLS with a provided key pair needs a client CA
+	for _, cfg := range []*ServerConfig{
+		{TLS: true, CertFile: certFile, KeyFile: keyFile, RequireClientCert: true},
+		{TLS: true, CertFile: "missing.crt", KeyFile: "missing.key"},
+	} {
+		srv, err := NewServer(cfg)
+		require.NoError(t, err)
+		_, err = srv.Start()
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,57 @@
+package gominio
+
+import (
+	"net"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxObjectNameLength = 1024
+
+var (
+	strictBucketName  = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+	lenientBucketName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{1,61}[A-Za-z0-9]$`)
+)
+
+// CheckBucketName checks bucket is a valid bucket name. Strict names follow the S3 DNS-compliant
+// naming rules, lenient names additionally allow uppercase letters, underscores and colons like MinIO.
+func CheckBucketName(bucket string, strict bool) error {
+	apiErr := ErrInvalidBucketName
+	switch {
+	case len(bucket) < 3 || len(bucket) > 63:
+		apiErr.Description = "Bucket name must be between 3 and 63 characters long."
+	case strict && !strictBucketName.MatchString(bucket):
+		apiErr.Description = "Bucket name can only contain lowercase letters, numbers, dots and hyphens, " +
+			"and must begin and end with a letter or a number."
+	case !strict && !lenientBucketName.MatchString(bucket):
+		apiErr.Description = "Bucket name contains invalid characters."
+	case strings.Contains(bucket, "..") || strings.Contains(bucket, ".-") || strings.Contains(bucket, "-."):
+		apiErr.Description = "Bucket name cannot contain adjacent periods, or periods adjacent to hyphens."
+	case net.ParseIP(bucket) != nil:
+		apiErr.Description = "Bucket name cannot be formatted as an IP address."
+	case strict && (strings.HasPrefix(bucket, "xn--") || strings.HasSuffix(bucket, "-s3alias") ||
+		strings.HasSuffix(bucket, "--ol-s3")):
+		apiErr.Description = "Bucket name uses a reserved prefix or suffix."
+	default:
+		return nil
+	}
+	return apiErr
+}
+
+// CheckObjectName checks object is a valid object key
+func CheckObjectName(object string) error {
+	switch {
+	case len(object) > maxObjectNameLength:
+		return ErrKeyTooLongError
+	case object == "":
+		apiErr := ErrInvalidArgument
+		apiErr.Description = "Object name cannot be empty."
+		return apiErr
+	case !utf8.ValidString(object):
+		apiErr := ErrInvalidArgument
+		apiErr.Description = "Object name contains invalid UTF-8 characters."
+		return apiErr
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package gominio
+
+import (
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckBucketName(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		strict  bool
+		lenient bool
+	}{
+		{"test", true, true},
+		{"my.bucket-1", true, true},
+		{"ab", false, false},
+		{strings.Repeat("a", 64), false, false},
+		{"Test", false, true},
+		{"my_bucket", false, true},
+		{"192.168.1.1", false, false},
+		{"my..bucket", false, false},
+		{"my-.bucket", false, false},
+		{"-bucket", false, false},
+		{"xn--bucket", false, true},
+		{"bucket-s3alias", false, true},
+	} {
+		require.Equal(t, tc.strict, CheckBucketName(tc.name, true) == nil, tc.name)
+		require.Equal(t, tc.lenient, CheckBucketName(tc.name, false) == nil, tc.name)
+	}
+
+	require.NoError(t, CheckObjectName("dir/hello.txt"))
+	require.ErrorIs(t, CheckObjectName(strings.Repeat("a", 1025)), ErrKeyTooLongError)
+	require.Equal(t, ErrInvalidArgument.Code, ToAPIError(CheckObjectName("\xff")).Code)
+}
+
+func TestNameValidation(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("test"))
+
+	err := server.GetMS().CreateBucket("Test", "")
+	require.Equal(t, ErrInvalidBucketName.Code, ToAPIError(err).Code)
+	require.ErrorIs(t, server.GetMS().CreateBucket("test", ""), ErrBucketAlreadyOwnedByYou)
+	require.False(t, server.GetMS().MakeBucket("test"))
+
+	// the client checks bucket names as well, send the request directly
+	req, err := http.NewRequest(http.MethodPut, "/my_bucket/", nil)
+	require.NoError(t, err)
+	rsp, err := server.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	require.Contains(t, string(body), ErrInvalidBucketName.Code)
+
+	req, err = http.NewRequest(http.MethodPut, "/test/"+strings.Repeat("a", 1025), strings.NewReader("hello"))
+	require.NoError(t, err)
+	rsp, err = server.Do(req)
+	require.NoError(t, err)
+	body, err = io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	require.Contains(t, string(body), ErrKeyTooLongError.Code)
+
+	content := `hello world`
+
+	_, err = server.Client.PutObject(context.Background(), "test", strings.Repeat("a", 1024),
+		strings.NewReader(content), int64(len(content)), minio.PutObjectOptions{})
+	server.NoError(err)
+
+	lenient := NewTestServer(t, WithServerConfig(func(cfg *ServerConfig) {
+		cfg.LenientNames = true
+	}))
+	require.True(t, lenient.GetMS().MakeBucket("My_Bucket"))
+	require.False(t, lenient.GetMS().MakeBucket("ab"))
+}
@@ -0,0 +1,387 @@
+package gominio
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxRoutingRules is the maximum number of routing rules of a website configuration
+const maxRoutingRules = 50
+
+// WebsiteIndexDocument is the suffix appended to the requests of directory-style paths,
+// like index.html
+type WebsiteIndexDocument struct {
+	Suffix string `xml:"Suffix"`
+}
+
+// WebsiteErrorDocument is the object served when a request fails
+type WebsiteErrorDocument struct {
+	Key string `xml:"Key"`
+}
+
+// WebsiteRedirectAll redirects every request to another host
+type WebsiteRedirectAll struct {
+	HostName string `xml:"HostName"`
+	Protocol string `xml:"Protocol,omitempty"`
+}
+
+// WebsiteCondition is the condition of a routing rule, the key of the request must start with
+// KeyPrefixEquals and, when it is set, the request must fail with HttpErrorCodeReturnedEquals
+type WebsiteCondition struct {
+	KeyPrefixEquals             string `xml:"KeyPrefixEquals,omitempty"`
+	HttpErrorCodeReturnedEquals string `xml:"HttpErrorCodeReturnedEquals,omitempty"`
+}
+
+// WebsiteRedirect is the redirect of a routing rule, the location keeps the host, the protocol
+// and the key of the request unless they are replaced
+type WebsiteRedirect struct {
+	HostName string `xml:"HostName,omitempty"`
+	// HttpRedirectCode is the status of the redirect, 301 when empty
+	HttpRedirectCode string `xml:"HttpRedirectCode,omitempty"`
+	Protocol         string `xml:"Protocol,omitempty"`
+	// ReplaceKeyPrefixWith replaces the KeyPrefixEquals of the condition, it may be empty
+	ReplaceKeyPrefixWith *string `xml:"ReplaceKeyPrefixWith,omitempty"`
+	ReplaceKeyWith       string  `xml:"ReplaceKeyWith,omitempty"`
+}
+
+// WebsiteRoutingRule redirects the requests matching its condition, rules without condition
+// redirect every request
+type WebsiteRoutingRule struct {
+	Condition *WebsiteCondition `xml:"Condition,omitempty"`
+	Redirect  WebsiteRedirect   `xml:"Redirect"`
+}
+
+// WebsiteConfiguration is the static website configuration of a bucket, it either redirects
+// every request or serves the objects of the bucket with an index document
+type WebsiteConfiguration struct {
+	XMLName               xml.Name              `xml:"WebsiteConfiguration"`
+	IndexDocument         *WebsiteIndexDocument `xml:"IndexDocument,omitempty"`
+	ErrorDocument         *WebsiteErrorDocument `xml:"ErrorDocument,omitempty"`
+	RedirectAllRequestsTo *WebsiteRedirectAll   `xml:"RedirectAllRequestsTo,omitempty"`
+	RoutingRules          []WebsiteRoutingRule  `xml:"RoutingRules>RoutingRule,omitempty"`
+}
+
+// Validate checks the configuration follows the S3 website configuration rules
+func (cfg *WebsiteConfiguration) Validate() error {
+	invalid := func(format string, args ...any) error {
+		apiErr := ErrInvalidArgument
+		apiErr.Description = fmt.Sprintf(format, args...)
+		return apiErr
+	}
+	validProtocol := func(protocol string) bool {
+		return protocol == "" || protocol == "http" || protocol == "https"
+	}
+
+	if redirect := cfg.RedirectAllRequestsTo; redirect != nil {
+		if cfg.IndexDocument != nil || cfg.ErrorDocument != nil || len(cfg.RoutingRules) > 0 {
+			return invalid("RedirectAllRequestsTo cannot be provided in conjunction with other Routing/Redirect configuration.")
+		}
+		if redirect.HostName == "" {
+			return invalid("A host name must be provided to redirect all requests.")
+		}
+		if !validProtocol(redirect.Protocol) {
+			return invalid("Invalid protocol, protocol can be http or https. If not defined the protocol will be selected automatically.")
+		}
+		return nil
+	}
+
+	if cfg.IndexDocument == nil || cfg.IndexDocument.Suffix == "" {
+		return invalid("A value for IndexDocument Suffix must be provided if RedirectAllRequestsTo is empty")
+	}
+	if strings.Contains(cfg.IndexDocument.Suffix, "/") {
+		return invalid("The IndexDocument Suffix is not well formed")
+	}
+	if cfg.ErrorDocument != nil && cfg.ErrorDocument.Key == "" {
+		return invalid("The ErrorDocument Key is not well formed")
+	}
+	if len(cfg.RoutingRules) > maxRoutingRules {
+		return invalid("The number of routing rules must not exceed %d", maxRoutingRules)
+	}
+	for _, rule := range cfg.RoutingRules {
+		if cond := rule.Condition; cond != nil {
+			if cond.KeyPrefixEquals == "" && cond.HttpErrorCodeReturnedEquals == "" {
+				return invalid("Condition cannot be empty. To redirect all requests without a condition, the condition element shouldn't be present.")
+			}
+			if code := cond.HttpErrorCodeReturnedEquals; code != "" {
+				if n, err := strconv.Atoi(code); err != nil || n < 400 || n > 599 {
+					return invalid("The provided HTTP error code (%s) is not valid. Valid codes are 4XX or 5XX.", code)
+				}
+			}
+		}
+
+		redirect := rule.Redirect
+		if redirect == (WebsiteRedirect{}) {
+			return invalid("Redirect must contain at least one of the following elements: " +
+				"HostName, HttpRedirectCode, Protocol, ReplaceKeyPrefixWith, ReplaceKeyWith.")
+		}
+		if redirect.ReplaceKeyPrefixWith != nil && redirect.ReplaceKeyWith != "" {
+			return invalid("You can only define ReplaceKeyPrefix or ReplaceKey but not both.")
+		}
+		if !validProtocol(redirect.Protocol) {
+			return invalid("Invalid protocol, protocol can be http or https. If not defined the protocol will be selected automatically.")
+		}
+		if code := redirect.HttpRedirectCode; code != "" {
+			if n, err := strconv.Atoi(code); err != nil || n <= 300 || n > 399 {
+				return invalid("The provided HTTP redirect code (%s) is not valid. Valid codes are 3XX except 300.", code)
+			}
+		}
+	}
+	return nil
+}
+
+// matchRoutingRule returns the first rule matching a request for key, status is zero before the
+// object is read and the status of the failed request otherwise
+func (cfg *WebsiteConfiguration) matchRoutingRule(key string, status int) *WebsiteRoutingRule {
+	for i := range cfg.RoutingRules {
+		rule := &cfg.RoutingRules[i]
+		var prefix, code string
+		if rule.Condition != nil {
+			prefix, code = rule.Condition.KeyPrefixEquals, rule.Condition.HttpErrorCodeReturnedEquals
+		}
+		if (code == "") != (status == 0) || (code != "" && code != strconv.Itoa(status)) {
+			continue
+		}
+		if strings.HasPrefix(key, prefix) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// location returns the location and the status of the redirect of a request for key
+func (rule *WebsiteRoutingRule) location(r *http.Request, key string) (string, int) {
+	redirect := rule.Redirect
+	switch {
+	case redirect.ReplaceKeyWith != "":
+		key = redirect.ReplaceKeyWith
+	case redirect.ReplaceKeyPrefixWith != nil:
+		var prefix string
+		if rule.Condition != nil {
+			prefix = rule.Condition.KeyPrefixEquals
+		}
+		key = *redirect.ReplaceKeyPrefixWith + strings.TrimPrefix(key, prefix)
+	}
+	status := http.StatusMovedPermanently
+	if redirect.HttpRedirectCode != "" {
+		status, _ = strconv.Atoi(redirect.HttpRedirectCode)
+	}
+	return websiteURL(r, redirect.Protocol, redirect.HostName, key), status
+}
+
+// websiteURL returns the URL of key on host, the protocol and the host of the request are used
+// when they are empty
+func websiteURL(r *http.Request, protocol, host, key string) string {
+	if protocol == "" {
+		protocol = "http"
+		if r.TLS != nil {
+			protocol = "https"
+		}
+	}
+	if host == "" {
+		host = r.Host
+	}
+	u := url.URL{Scheme: protocol, Host: host, Path: "/" + key}
+	return u.String()
+}
+
+// SetBucketWebsite sets the website configuration of bucket
+func (ms *MinioServer) SetBucketWebsite(bucket string, cfg *WebsiteConfiguration) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	ms.Lock()
+	defer ms.Unlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return ErrBucketNotExists
+	}
+	bd.Info.Website = cfg
+	return nil
+}
+
+// GetBucketWebsite returns the website configuration of bucket, ErrNoSuchWebsiteConfiguration is
+// returned when the bucket has none
+func (ms *MinioServer) GetBucketWebsite(bucket string) (*WebsiteConfiguration, error) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return nil, ErrBucketNotExists
+	}
+	if bd.Info.Website == nil {
+		return nil, ErrNoSuchWebsiteConfiguration
+	}
+	return bd.Info.Website, nil
+}
+
+// RemoveBucketWebsite removes the website configuration of bucket
+func (ms *MinioServer) RemoveBucketWebsite(bucket string) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	bd, ok := ms.Buckets[bucket]
+	if !ok {
+		return ErrBucketNotExists
+	}
+	bd.Info.Website = nil
+	return nil
+}
+
+func (api *ApiServer) getBucketWebsite(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	cfg, err := api.GetMS().GetBucketWebsite(bucket)
+	if err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+	SuccessResponse(ctx, http.StatusOK, encodeAny(cfg))
+}
+
+func (api *ApiServer) putBucketWebsite(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	var cfg = new(WebsiteConfiguration)
+	if err := decodeAny(ctx.Request.Body, cfg); err != nil {
+		ErrResponse(ctx, "", bucket, ErrMalformedXML)
+		return
+	}
+
+	if err := api.GetMS().SetBucketWebsite(bucket, cfg); err != nil {
+		ErrResponse(ctx, "", bucket, ToAPIError(err))
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, nil)
+}
+
+// websiteObject returns the object the website of bucket serves for key, objects are served when
+// the bucket policy allows anyone to read them
+func (ms *MinioServer) websiteObject(bucket, key string) (*ObjectInfo, error) {
+	content, _ := ms.GetBucketPolicy(bucket)
+	policy, err := ParseBucketPolicy([]byte(content))
+	if err != nil || !policy.allowsAnyone("s3:GetObject", s3ResourcePrefix+bucket+"/"+key) {
+		return nil, ErrAccessDenied
+	}
+	oi, err := ms.GetObject(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if err = oi.checkReadable(ms.now()); err != nil {
+		return nil, err
+	}
+	return oi, nil
+}
+
+// serveWebsite serves a GET or HEAD request addressed to the website endpoint of bucket. The
+// index document is served for directory-style keys, the routing rules redirect matching requests
+// and the error document is served with the status of failed requests. Website requests bypass
+// the S3 middlewares, they are neither journaled nor signed.
+func (api *ApiServer) serveWebsite(w http.ResponseWriter, r *http.Request, bucket string) {
+	api.setRequestID(w.Header())
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		api.websiteError(w, r, "", ErrMethodNotAllowed)
+		return
+	}
+
+	ms := api.GetMS()
+	cfg, err := ms.GetBucketWebsite(bucket)
+	if err != nil {
+		api.websiteError(w, r, "", ToAPIError(err))
+		return
+	}
+	if redirect := cfg.RedirectAllRequestsTo; redirect != nil {
+		u := websiteURL(r, redirect.Protocol, redirect.HostName, strings.TrimPrefix(r.URL.Path, "/"))
+		http.Redirect(w, r, u, http.StatusMovedPermanently)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if rule := cfg.matchRoutingRule(key, 0); rule != nil {
+		u, status := rule.location(r, key)
+		http.Redirect(w, r, u, status)
+		return
+	}
+
+	object := key
+	if object == "" || strings.HasSuffix(object, "/") {
+		object += cfg.IndexDocument.Suffix
+	}
+	oi, err := ms.websiteObject(bucket, object)
+	if err == nil {
+		api.writeWebsiteObject(w, r, http.StatusOK, oi)
+		return
+	}
+	// keys without trailing slash are redirected to their directory when it has an index document
+	if object == key && key != "" {
+		if _, dirErr := ms.websiteObject(bucket, key+"/"+cfg.IndexDocument.Suffix); dirErr == nil {
+			dir := url.URL{Path: "/" + key + "/"}
+			http.Redirect(w, r, dir.EscapedPath(), http.StatusFound)
+			return
+		}
+	}
+
+	apiErr := ToAPIError(err)
+	if rule := cfg.matchRoutingRule(key, apiErr.HTTPStatusCode); rule != nil {
+		u, status := rule.location(r, key)
+		http.Redirect(w, r, u, status)
+		return
+	}
+	if cfg.ErrorDocument != nil {
+		if doc, docErr := ms.websiteObject(bucket, cfg.ErrorDocument.Key); docErr == nil {
+			api.writeWebsiteObject(w, r, apiErr.HTTPStatusCode, doc)
+			return
+		}
+	}
+	api.websiteError(w, r, object, apiErr)
+}
+
+// writeWebsiteObject writes the data of oi with status, its content type is guessed from its key
+func (api *ApiServer) writeWebsiteObject(w http.ResponseWriter, r *http.Request, status int, oi *ObjectInfo) {
+	contentType := mime.TypeByExtension(path.Ext(oi.Name))
+	if contentType == "" {
+		contentType = "binary/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatUint(oi.Size, 10))
+	w.Header().Set("Last-Modified", oi.LastModified.UTC().Format(http.TimeFormat))
+	w.Header().Set("ETag", "\""+oi.Etag+"\"")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(oi.Data)
+	}
+}
+
+// websiteError writes the HTML error page of apiErr, the error is reported by the
+// x-amz-error-code and x-amz-error-message headers as well
+func (api *ApiServer) websiteError(w http.ResponseWriter, r *http.Request, key string, apiErr APIError) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("x-amz-error-code", apiErr.Code)
+	w.Header().Set("x-amz-error-message", apiErr.Description)
+	w.WriteHeader(apiErr.HTTPStatusCode)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	title := fmt.Sprintf("%d %s", apiErr.HTTPStatusCode, http.StatusText(apiErr.HTTPStatusCode))
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html>\n<head><title>%s</title></head>\n<body>\n<h1>%s</h1>\n<ul>\n", title, title)
+	fmt.Fprintf(&b, "<li>Code: %s</li>\n", html.EscapeString(apiErr.Code))
+	fmt.Fprintf(&b, "<li>Message: %s</li>\n", html.EscapeString(apiErr.Description))
+	if key != "" {
+		fmt.Fprintf(&b, "<li>Key: %s</li>\n", html.EscapeString(key))
+	}
+	fmt.Fprintf(&b, "<li>RequestId: %s</li>\n", w.Header().Get(amzRequestID))
+	fmt.Fprintf(&b, "<li>HostId: %s</li>\n", w.Header().Get(amzID2))
+	b.WriteString("</ul>\n<hr/>\n</body>\n</html>\n")
+	_, _ = w.Write([]byte(b.String()))
+}
@@ -0,0 +1,174 @@
+package gominio
+
+import (
+	"context"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBucketWebsite(t *testing.T) {
+	server := NewTestServer(t, WithBuckets("site", "redirect"), WithServerConfig(func(cfg *ServerConfig) {
+		cfg.WebsiteDomain = "s3-website.local"
+	}))
+	ctx := context.Background()
+
+	do := func(method, path, body string) (int, string) {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		require.NoError(t, err)
+		rsp, err := server.Do(req)
+		require.NoError(t, err)
+		data, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		require.NoError(t, rsp.Body.Close())
+		return rsp.StatusCode, string(data)
+	}
+	noRedirect := &http.Client{
+		Transport: server.HTTPClient.Transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	website := func(method, host, path string) (*http.Response, string) {
+		req, err := http.NewRequest(method, "http://"+server.Endpoint+path, nil)
+		require.NoError(t, err)
+		req.Host = host + ".s3-website.local"
+		rsp, err := noRedirect.Do(req)
+		require.NoError(t, err)
+		data, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		require.NoError(t, rsp.Body.Close())
+		return rsp, string(data)
+	}
+	put := func(object, content string) {
+		_, err := server.Client.PutObject(ctx, "site", object, strings.NewReader(content), int64(len(content)),
+			minio.PutObjectOptions{})
+		require.NoError(t, err)
+	}
+
+	status, body := do(http.MethodGet, "/site?website", "")
+	require.Equal(t, http.StatusNotFound, status)
+	require.Contains(t, body, "<Code>NoSuchWebsiteConfiguration</Code>")
+	for cfg, code := range map[string]string{
+		"<WebsiteConfiguration>":                        "MalformedXML",
+		"<WebsiteConfiguration></WebsiteConfiguration>": "InvalidArgument",
+		"<WebsiteConfiguration><IndexDocument><Suffix>a/b</Suffix></IndexDocument></WebsiteConfiguration>": "InvalidArgument",
+		`<WebsiteConfiguration><RedirectAllRequestsTo><HostName>example.com</HostName></RedirectAllRequestsTo>
+			<IndexDocument><Suffix>index.html</Suffix></IndexDocument></WebsiteConfiguration>`: "InvalidArgument",
+		`<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument><RoutingRules><RoutingRule>
+			<Condition><HttpErrorCodeReturnedEquals>200</HttpErrorCodeReturnedEquals></Condition>
+			<Redirect><HostName>example.com</HostName></Redirect></RoutingRule></RoutingRules></WebsiteConfiguration>`: "InvalidArgument",
+		`<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument><RoutingRules><RoutingRule>
+			<Redirect><ReplaceKeyWith>a</ReplaceKeyWith><ReplaceKeyPrefixWith>b</ReplaceKeyPrefixWith></Redirect>
+			</RoutingRule></RoutingRules></WebsiteConfiguration>`: "InvalidArgument",
+	} {
+		_, body = do(http.MethodPut, "/site?website", cfg)
+		require.Contains(t, body, fmt.Sprintf("<Code>%s</Code>", code), cfg)
+	}
+
+	status, _ = do(http.MethodPut, "/site?website", `<WebsiteConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+		<IndexDocument><Suffix>index.html</Suffix></IndexDocument>
+		<ErrorDocument><Key>error.html</Key></ErrorDocument>
+		<RoutingRules>
+			<RoutingRule>
+				<Condition><KeyPrefixEquals>docs/</KeyPrefixEquals></Condition>
+				<Redirect><ReplaceKeyPrefixWith>documents/</ReplaceKeyPrefixWith></Redirect>
+			</RoutingRule>
+			<RoutingRule>
+				<Condition><KeyPrefixEquals>old/</KeyPrefixEquals><HttpErrorCodeReturnedEquals>404</HttpErrorCodeReturnedEquals></Condition>
+				<Redirect><HostName>archive.example.com</HostName><HttpRedirectCode>302</HttpRedirectCode></Redirect>
+			</RoutingRule>
+		</RoutingRules>
+	</WebsiteConfiguration>`)
+	require.Equal(t, http.StatusOK, status)
+	status, body = do(http.MethodGet, "/site?website", "")
+	require.Equal(t, http.StatusOK, status)
+	require.Contains(t, body, "<IndexDocument><Suffix>index.html</Suffix></IndexDocument>")
+	require.Contains(t, body, "<ReplaceKeyPrefixWith>documents/</ReplaceKeyPrefixWith>")
+
+	put("index.html", "<h1>home</h1>")
+	put("about/index.html", "<h1>about</h1>")
+	put("style.css", "h1 {}")
+	put("error.html", "<h1>oops</h1>")
+	put("private/secret.html", "secret")
+
+	// objects are only served when the bucket policy allows anyone to read them
+	rsp, _ := website(http.MethodGet, "site", "/")
+	require.Equal(t, http.StatusForbidden, rsp.StatusCode)
+	require.Equal(t, "AccessDenied", rsp.Header.Get("x-amz-error-code"))
+	require.NoError(t, server.Client.SetBucketPolicy(ctx, "site", `{"Version": "2012-10-17", "Statement": [
+		{"Effect": "Allow", "Principal": {"AWS": ["*"]}, "Action": ["s3:GetObject"], "Resource": ["arn:aws:s3:::site/*"]},
+		{"Effect": "Deny", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::site/private/*"}
+	]}`))
+
+	rsp, body = website(http.MethodGet, "site", "/")
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, "<h1>home</h1>", body)
+	require.Equal(t, "text/html; charset=utf-8", rsp.Header.Get("Content-Type"))
+	rsp, body = website(http.MethodGet, "site", "/about/")
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, "<h1>about</h1>", body)
+	rsp, _ = website(http.MethodGet, "site", "/about")
+	require.Equal(t, http.StatusFound, rsp.StatusCode)
+	require.Equal(t, "/about/", rsp.Header.Get("Location"))
+	rsp, body = website(http.MethodHead, "site", "/style.css")
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Empty(t, body)
+	require.Equal(t, "text/css; charset=utf-8", rsp.Header.Get("Content-Type"))
+	require.Equal(t, "5", rsp.Header.Get("Content-Length"))
+
+	// failed requests are served the error document with their status
+	rsp, body = website(http.MethodGet, "site", "/missing.html")
+	require.Equal(t, http.StatusNotFound, rsp.StatusCode)
+	require.Equal(t, "<h1>oops</h1>", body)
+	rsp, body = website(http.MethodGet, "site", "/private/secret.html")
+	require.Equal(t, http.StatusForbidden, rsp.StatusCode)
+	require.Equal(t, "<h1>oops</h1>", body)
+
+	// routing rules redirect requests by prefix, or by prefix and error code
+	rsp, _ = website(http.MethodGet, "site", "/docs/guide.html")
+	require.Equal(t, http.StatusMovedPermanently, rsp.StatusCode)
+	require.Equal(t, "http://site.s3-website.local/documents/guide.html", rsp.Header.Get("Location"))
+	rsp, _ = website(http.MethodGet, "site", "/old/page.html")
+	require.Equal(t, http.StatusFound, rsp.StatusCode)
+	require.Equal(t, "http://archive.example.com/old/page.html", rsp.Header.Get("Location"))
+	put("old/page.html", "still here")
+	rsp, body = website(http.MethodGet, "site", "/old/page.html")
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, "still here", body)
+
+	// without error document errors are served as HTML pages
+	require.NoError(t, server.Client.RemoveObject(ctx, "site", "error.html", minio.RemoveObjectOptions{}))
+	rsp, body = website(http.MethodGet, "site", "/missing.html")
+	require.Equal(t, http.StatusNotFound, rsp.StatusCode)
+	require.Equal(t, "NoSuchKey", rsp.Header.Get("x-amz-error-code"))
+	require.Contains(t, body, "<li>Key: missing.html</li>")
+	rsp, _ = website(http.MethodPost, "site", "/")
+	require.Equal(t, http.StatusMethodNotAllowed, rsp.StatusCode)
+	rsp, _ = website(http.MethodGet, "missing", "/")
+	require.Equal(t, "NoSuchBucket", rsp.Header.Get("x-amz-error-code"))
+	rsp, _ = website(http.MethodGet, "redirect", "/")
+	require.Equal(t, "NoSuchWebsiteConfiguration", rsp.Header.Get("x-amz-error-code"))
+
+	// buckets may redirect every request to another host
+	status, _ = do(http.MethodPut, "/redirect?website", `<WebsiteConfiguration><RedirectAllRequestsTo>
+		<HostName>www.example.com</HostName><Protocol>https</Protocol></RedirectAllRequestsTo></WebsiteConfiguration>`)
+	require.Equal(t, http.StatusOK, status)
+	rsp, _ = website(http.MethodGet, "redirect", "/blog/post.html")
+	require.Equal(t, http.StatusMovedPermanently, rsp.StatusCode)
+	require.Equal(t, "https://www.example.com/blog/post.html", rsp.Header.Get("Location"))
+
+	status, _ = do(http.MethodDelete, "/site?website", "")
+	require.Equal(t, http.StatusNoContent, status)
+	rsp, _ = website(http.MethodGet, "site", "/")
+	require.Equal(t, http.StatusNotFound, rsp.StatusCode)
+	require.Equal(t, "NoSuchWebsiteConfiguration", rsp.Header.Get("x-amz-error-code"))
+
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpPutBucketWebsite, Status: http.StatusOK}, 2)
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpGetBucketWebsite}, 2)
+	server.GetJournal().AssertCount(t, JournalFilter{Operation: OpDeleteBucketWebsite, Status: http.StatusNoContent}, 1)
+}
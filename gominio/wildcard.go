@@ -0,0 +1,55 @@
+package gominio
+
+// wildcardKind is the kind of a wildcard pattern token
+type wildcardKind int
+
+const (
+	// wildcardLiteral matches its rune
+	wildcardLiteral wildcardKind = iota
+	// wildcardOne matches any single rune
+	wildcardOne
+	// wildcardAny matches any sequence of runes, runs of them are collapsed into one
+	wildcardAny
+)
+
+type wildcardToken struct {
+	kind wildcardKind
+	r    rune
+}
+
+// wildcardPattern is a compiled wildcard pattern
+type wildcardPattern []wildcardToken
+
+func (p wildcardPattern) add(kind wildcardKind, r rune) wildcardPattern {
+	if kind == wildcardAny && len(p) > 0 && p[len(p)-1].kind == wildcardAny {
+		return p
+	}
+	return append(p, wildcardToken{kind: kind, r: r})
+}
+
+// match reports whether the pattern matches all of s. The last wildcardAny is backtracked one
+// rune at a time instead of recursing, so matching takes O(len(p)*len(s)) at worst.
+func (p wildcardPattern) match(s []rune) bool {
+	pi, si := 0, 0
+	star, next := -1, 0
+	for si < len(s) {
+		switch {
+		case pi < len(p) && p[pi].kind == wildcardAny:
+			// first try matching the empty sequence, resume after the rune at next otherwise
+			star, next = pi, si
+			pi++
+		case pi < len(p) && (p[pi].kind == wildcardOne || p[pi].r == s[si]):
+			pi++
+			si++
+		case star >= 0:
+			next++
+			pi, si = star+1, next
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi].kind == wildcardAny {
+		pi++
+	}
+	return pi == len(p)
+}